@@ -0,0 +1,15 @@
+package securecookie
+
+// EncodeWithAAD encodes a cookie value like Encode, authenticating aad, e.g.
+// a tenant ID, API version or route, along with it without storing it: the
+// value only decodes with DecodeWithAAD and the same aad. An empty aad is the
+// same as none.
+func (s *SecureCookie) EncodeWithAAD(name string, value interface{}, aad []byte) (string, error) {
+	return s.bind("aad", aad).Encode(name, value)
+}
+
+// DecodeWithAAD decodes a cookie value encoded by EncodeWithAAD with the same
+// aad, or fails with ErrMacInvalid.
+func (s *SecureCookie) DecodeWithAAD(name, value string, aad []byte, dst interface{}) error {
+	return s.bind("aad", aad).Decode(name, value, dst)
+}
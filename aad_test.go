@@ -0,0 +1,25 @@
+package securecookie
+
+import "testing"
+
+func TestAAD(t *testing.T) {
+	s := New([]byte("12345"), []byte("1234567890123456"))
+	encoded, err := s.EncodeWithAAD("sid", "alice", []byte("tenant=acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := s.DecodeWithAAD("sid", encoded, []byte("tenant=acme"), &dst); err != nil || dst != "alice" {
+		t.Fatalf("Expected %q, got %q, %v", "alice", dst, err)
+	}
+	for _, aad := range []string{"tenant=other", ""} {
+		if err := s.DecodeWithAAD("sid", encoded, []byte(aad), &dst); err != ErrMacInvalid {
+			t.Fatalf("Expected ErrMacInvalid for %q, got %v", aad, err)
+		}
+	}
+
+	// AAD is not interchangeable with other bindings.
+	if err := s.WithFingerprint([]byte("tenant=acme")).Decode("sid", encoded, &dst); err != ErrMacInvalid {
+		t.Fatalf("Expected ErrMacInvalid, got %v", err)
+	}
+}
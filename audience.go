@@ -0,0 +1,12 @@
+package securecookie
+
+// Audience binds values to aud, typically the host or service name, so that
+// a value encoded for "api.example.com" fails to decode, with ErrMacInvalid,
+// on a codec for "admin.example.com" even if both share keys. Values encoded
+// with an audience only decode with the same audience.
+//
+// Default is "", for no audience.
+func (s *SecureCookie) Audience(aud string) *SecureCookie {
+	s.audience = aud
+	return s
+}
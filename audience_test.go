@@ -0,0 +1,28 @@
+package securecookie
+
+import "testing"
+
+func TestAudience(t *testing.T) {
+	api := New([]byte("12345"), nil).Audience("api.example.com")
+	admin := New([]byte("12345"), nil).Audience("admin.example.com")
+	encoded, err := api.Encode("sid", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := api.Decode("sid", encoded, &dst); err != nil || dst != "alice" {
+		t.Fatalf("Expected %q, got %q, %v", "alice", dst, err)
+	}
+	if err := admin.Decode("sid", encoded, &dst); err != ErrMacInvalid {
+		t.Fatalf("Expected ErrMacInvalid, got %v", err)
+	}
+	if err := New([]byte("12345"), nil).Decode("sid", encoded, &dst); err != ErrMacInvalid {
+		t.Fatalf("Expected ErrMacInvalid without an audience, got %v", err)
+	}
+
+	// The audience and fingerprints are distinct bindings.
+	fp := New([]byte("12345"), nil).WithFingerprint([]byte("api.example.com"))
+	if err := fp.Decode("sid", encoded, &dst); err != ErrMacInvalid {
+		t.Fatalf("Expected ErrMacInvalid, got %v", err)
+	}
+}
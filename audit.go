@@ -0,0 +1,106 @@
+package securecookie
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// longMaxAge is the max age above which AuditConfig reports a finding.
+const longMaxAge = 86400 * 90
+
+// Severity ranks audit findings.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityHigh
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityHigh:
+		return "high"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Finding is a problem reported by AuditConfig. Code is a stable identifier,
+// e.g. to allow-list a finding in a deploy gate.
+type Finding struct {
+	Severity Severity
+	Code     string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.Code, f.Message)
+}
+
+// AuditConfig reports insecure or risky settings of s, most severe first:
+//
+//	config-error        an error is recorded, so Encode and Decode fail (high)
+//	weak-key            a key fails the checks of ValidateKeys (high)
+//	block-key-missing   values are authenticated but not encrypted (warning)
+//	max-age-unlimited   MaxAge is 0, so values never expire (warning)
+//	max-age-long        MaxAge is longer than 90 days (warning)
+//	gob-serializer      values are decoded with encoding/gob, whose decoder
+//	                    runs on input chosen by clients (warning)
+//	custom-serializer   the serializer is not one of this package, so it may
+//	                    not be safe to run on untrusted input (info)
+//	min-age-missing     MinAge is 0 (info)
+func AuditConfig(s *SecureCookie) []Finding {
+	var findings []Finding
+	add := func(sev Severity, code, format string, args ...interface{}) {
+		findings = append(findings, Finding{Severity: sev, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.err != nil {
+		add(SeverityHigh, "config-error", "%v", s.err)
+	}
+	if err := checkKeys(s.hashKey, s.blockKey); err != nil {
+		for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+			add(SeverityHigh, "weak-key", "%v", e)
+		}
+	}
+	if s.block == nil {
+		add(SeverityWarning, "block-key-missing", "values are readable by clients; set a block key to encrypt them")
+	}
+	if s.maxAge == 0 {
+		add(SeverityWarning, "max-age-unlimited", "values never expire")
+	} else if s.maxAge > longMaxAge {
+		add(SeverityWarning, "max-age-long", "values are valid for %d days", s.maxAge/86400)
+	}
+	if _, ok := s.sz.(gobSerializer); ok {
+		add(SeverityWarning, "gob-serializer", "encoding/gob decodes client input; allow only types without interface fields, or prefer JSON")
+	} else if !builtinSerializer(s.sz) {
+		add(SeverityInfo, "custom-serializer", "serializer %T must be safe to run on untrusted input", s.sz)
+	}
+	if s.minAge == 0 {
+		add(SeverityInfo, "min-age-missing", "values are accepted immediately after being issued")
+	}
+	return findings
+}
+
+// gobSerializer is implemented by GobEncoder, which is only built with the
+// gob tag.
+type gobSerializer interface {
+	decodesGob()
+}
+
+// builtinSerializer reports whether sz is a serializer of this package,
+// including those built with tags.
+func builtinSerializer(sz Serializer) bool {
+	if m, ok := sz.(MarshalerEncoder); ok {
+		return m.Fallback == nil || builtinSerializer(m.Fallback)
+	}
+	for _, b := range serializers {
+		if reflect.TypeOf(b) == reflect.TypeOf(sz) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package securecookie
+
+import "testing"
+
+type gobLike struct{ JSONEncoder }
+
+func TestAuditConfig(t *testing.T) {
+	codes := func(findings []Finding) map[string]bool {
+		m := make(map[string]bool)
+		for _, f := range findings {
+			m[f.Code] = true
+		}
+		return m
+	}
+
+	got := codes(AuditConfig(New([]byte("12345"), nil).MaxAge(0).SetSerializer(gobLike{})))
+	for _, code := range []string{"weak-key", "block-key-missing", "max-age-unlimited", "custom-serializer", "min-age-missing"} {
+		if !got[code] {
+			t.Errorf("Expected finding %s, got %v", code, got)
+		}
+	}
+	if got := codes(AuditConfig(New([]byte("12345"), nil).MaxAge(86400 * 365))); !got["max-age-long"] {
+		t.Errorf("Expected finding max-age-long, got %v", got)
+	}
+
+	s := New(GenerateRandomKey(32), GenerateRandomKey(32)).MinAge(1)
+	if findings := AuditConfig(s); len(findings) != 0 {
+		t.Fatalf("Expected no findings, got %v", findings)
+	}
+	for _, sz := range []Serializer{NopEncoder{}, MarshalerEncoder{}, MarshalerEncoder{Fallback: NopEncoder{}}} {
+		if findings := AuditConfig(s.SetSerializer(sz)); len(findings) != 0 {
+			t.Errorf("Expected no findings for %T, got %v", sz, findings)
+		}
+	}
+	if got := codes(AuditConfig(s.SetSerializer(MarshalerEncoder{Fallback: gobLike{}}))); !got["custom-serializer"] {
+		t.Errorf("Expected finding custom-serializer for a custom fallback, got %v", got)
+	}
+}
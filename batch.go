@@ -0,0 +1,86 @@
+package securecookie
+
+import (
+	"crypto/hmac"
+	"hash"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DecodeAllError is returned by DecodeAll when some cookies fail to decode.
+// It maps their names to a *MissingCookieError or an *InvalidCookieError.
+type DecodeAllError map[string]error
+
+func (e DecodeAllError) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = e[name].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// DecodeAll decodes several cookies of r, e.g. the session, CSRF and
+// preference cookies, into the values of dsts, keyed by cookie name. The
+// Cookie header is parsed once and the values share a single MAC state, which
+// saves time and allocations over decoding the cookies one by one.
+// Cookies that fail are reported in a DecodeAllError, as with DecodeCookie;
+// the others are decoded anyway.
+func (s *SecureCookie) DecodeAll(r *http.Request, dsts map[string]interface{}) error {
+	codec, err := s.forRequest(r)
+	if err != nil {
+		return err
+	}
+	c := *codec
+	c.mac = hmac.New(c.hashFunc, c.hashKey)
+	jar := make(map[string]string, len(dsts))
+	for _, cookie := range r.Cookies() {
+		if _, ok := jar[cookie.Name]; !ok {
+			jar[cookie.Name] = cookie.Value
+		}
+	}
+	errs := DecodeAllError{}
+	for name, dst := range dsts {
+		value, ok := prefixedValue(jar, name)
+		if !ok {
+			errs[name] = &MissingCookieError{Name: name}
+			continue
+		}
+		if err := c.Decode(name, value, dst); err != nil {
+			errs[name] = &InvalidCookieError{Name: name, Err: err}
+		}
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// prefixedValue returns the value of the named cookie in jar, preferring its
+// prefixed variants as prefixedCookie does.
+func prefixedValue(jar map[string]string, name string) (string, bool) {
+	if cookiePrefix(name) == "" {
+		for _, prefix := range []string{hostPrefix, securePrefix} {
+			if value, ok := jar[prefix+name]; ok {
+				return value, true
+			}
+		}
+	}
+	value, ok := jar[name]
+	return value, ok
+}
+
+// newMac returns a MAC for the hash key: the shared one of a DecodeAll call,
+// reset, or a new one.
+func (s *SecureCookie) newMac() hash.Hash {
+	if s.mac != nil {
+		s.mac.Reset()
+		return s.mac
+	}
+	return hmac.New(s.hashFunc, s.hashKey)
+}
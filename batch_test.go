@@ -0,0 +1,71 @@
+package securecookie
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecodeAll(t *testing.T) {
+	s := New([]byte("12345"), []byte("1234567890123456"))
+	r := httptest.NewRequest("GET", "/", nil)
+	for name, value := range map[string]interface{}{"session": "alice", "csrf": "token"} {
+		encoded, err := s.Encode(name, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.AddCookie(&http.Cookie{Name: name, Value: encoded})
+	}
+	r.AddCookie(&http.Cookie{Name: "prefs", Value: "tampered"})
+
+	var session, csrf, prefs, theme string
+	err := s.DecodeAll(r, map[string]interface{}{"session": &session, "csrf": &csrf, "prefs": &prefs, "theme": &theme})
+	if session != "alice" || csrf != "token" {
+		t.Fatalf("Expected the valid cookies to be decoded, got %q, %q", session, csrf)
+	}
+	var errs DecodeAllError
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected two errors, got %v", err)
+	}
+	var invalid *InvalidCookieError
+	var missing *MissingCookieError
+	if !errors.As(errs["prefs"], &invalid) || !errors.As(errs["theme"], &missing) {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	if err := s.DecodeAll(r, map[string]interface{}{"session": &session}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func BenchmarkDecodeAll(b *testing.B) {
+	s := New(GenerateRandomKey(32), GenerateRandomKey(32))
+	r := httptest.NewRequest("GET", "/", nil)
+	names := []string{"session", "csrf", "prefs"}
+	for _, name := range names {
+		encoded, _ := s.Encode(name, map[string]string{"v": name})
+		r.AddCookie(&http.Cookie{Name: name, Value: encoded})
+	}
+	dsts := map[string]interface{}{}
+	for _, name := range names {
+		dsts[name] = &map[string]string{}
+	}
+	b.Run("DecodeAll", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := s.DecodeAll(r, dsts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Decode", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, name := range names {
+				c, _ := r.Cookie(name)
+				if err := s.Decode(name, c.Value, dsts[name]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
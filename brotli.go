@@ -0,0 +1,43 @@
+//go:build brotli
+
+package securecookie
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// This file requires github.com/andybalholm/brotli, which is not a
+// dependency of the default build. Add it with
+// "go get github.com/andybalholm/brotli" and build with "-tags brotli".
+
+func init() {
+	RegisterCompressor(BrotliCompressor{})
+}
+
+// BrotliCompressor compresses with brotli.
+type BrotliCompressor struct {
+	// Level is the brotli compression level, from 0 to 11. Default is
+	// brotli.DefaultCompression.
+	Level int
+}
+
+// ID returns the ID of brotli.
+func (c BrotliCompressor) ID() byte {
+	return compressBrotli
+}
+
+// NewWriter returns a brotli writer.
+func (c BrotliCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = brotli.DefaultCompression
+	}
+	return brotli.NewWriterLevel(w, level), nil
+}
+
+// NewReader returns a brotli reader.
+func (c BrotliCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(brotli.NewReader(r)), nil
+}
@@ -0,0 +1,91 @@
+package securecookie
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// defaultCookieBudget is the default CookieBudget limit. Many servers and
+// proxies reject request headers over 8 KiB, and browsers send all the
+// cookies of a domain in a single Cookie header.
+const defaultCookieBudget = 8192
+
+// BudgetError is returned or reported by CookieBudget when writing a cookie
+// exceeds the budget of the response.
+type BudgetError struct {
+	// Name is the name of the cookie that exceeded the budget.
+	Name string
+	// Used is the number of Set-Cookie bytes including that cookie.
+	Used  int
+	Limit int
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("securecookie: cookie %s brings the response to %d Set-Cookie bytes, over the budget of %d", e.Name, e.Used, e.Limit)
+}
+
+// CookieBudget tracks the total size of the cookies written to one response,
+// which individual MaxLength checks do not capture. Create one per response.
+//
+// A CookieBudget is safe for concurrent use.
+type CookieBudget struct {
+	mu    sync.Mutex
+	limit int
+	warn  func(*BudgetError)
+	used  int
+}
+
+// NewCookieBudget returns a CookieBudget allowing limit bytes of Set-Cookie
+// header values, or 8192 if limit is 0. Set the limit with a margin below
+// the actual limits to be warned before reaching them.
+//
+// If warn is nil the budget is strict: cookies exceeding it are not written
+// and WriteCookie returns a *BudgetError. Otherwise they are written and
+// warn is called.
+func NewCookieBudget(limit int, warn func(*BudgetError)) *CookieBudget {
+	if limit <= 0 {
+		limit = defaultCookieBudget
+	}
+	return &CookieBudget{limit: limit, warn: warn}
+}
+
+// WriteCookie writes c like the package-level WriteCookie, counting its size
+// against the budget.
+func (b *CookieBudget) WriteCookie(w http.ResponseWriter, r *http.Request, c *http.Cookie) error {
+	n := len(c.String())
+	b.mu.Lock()
+	used := b.used + n
+	var over *BudgetError
+	if used > b.limit {
+		over = &BudgetError{Name: c.Name, Used: used, Limit: b.limit}
+	}
+	if over == nil || b.warn != nil {
+		b.used = used
+	}
+	b.mu.Unlock()
+	if over != nil {
+		if b.warn == nil {
+			return over
+		}
+		b.warn(over)
+	}
+	return WriteCookie(w, r, c)
+}
+
+// Used returns the number of Set-Cookie bytes written so far.
+func (b *CookieBudget) Used() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// Remaining returns the number of Set-Cookie bytes left in the budget.
+func (b *CookieBudget) Remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used > b.limit {
+		return 0
+	}
+	return b.limit - b.used
+}
@@ -0,0 +1,40 @@
+package securecookie
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCookieBudget(t *testing.T) {
+	c := &http.Cookie{Name: "a", Value: strings.Repeat("x", 60)}
+	size := len(c.String())
+
+	w := httptest.NewRecorder()
+	b := NewCookieBudget(size*2, nil)
+	for i := 0; i < 2; i++ {
+		if err := b.WriteCookie(w, nil, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var budgetErr *BudgetError
+	if err := b.WriteCookie(w, nil, c); !errors.As(err, &budgetErr) || budgetErr.Used != size*3 {
+		t.Fatalf("Expected a *BudgetError, got %v", err)
+	}
+	if n := len(w.Result().Cookies()); n != 2 || b.Remaining() != 0 {
+		t.Fatalf("Expected 2 cookies and no budget left, got %d, %d", n, b.Remaining())
+	}
+
+	var warned *BudgetError
+	b = NewCookieBudget(size, func(err *BudgetError) { warned = err })
+	w = httptest.NewRecorder()
+	_ = b.WriteCookie(w, nil, c)
+	if err := b.WriteCookie(w, nil, c); err != nil || warned == nil {
+		t.Fatalf("Expected a warning only, got %v, %v", err, warned)
+	}
+	if n := len(w.Result().Cookies()); n != 2 || b.Used() != size*2 {
+		t.Fatalf("Expected 2 cookies, got %d", n)
+	}
+}
@@ -0,0 +1,42 @@
+package securecookie
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ChannelBindingLabel is the exporter label used by ChannelBindingRequest.
+const ChannelBindingLabel = "EXPORTER-securecookie-channel-binding"
+
+var (
+	errNoTLS             = Error{msg: "request was not received over TLS"}
+	errChannelBindingEKM = Error{msg: "TLS keying material could not be exported"}
+)
+
+// WithChannelBinding returns a copy of s that binds values to ekm, keying
+// material exported from a TLS connection (RFC 5705, RFC 8446 section 7.5):
+// values encoded by the copy only decode with the same material, so a cookie
+// exfiltrated e.g. through XSS cannot be used from another connection. A nil
+// or empty ekm leaves values unbound.
+//
+// Exported keying material differs for every TLS 1.3 connection, resumed or
+// not, so bound values suit long-lived connections or short exchanges
+// within one connection; the session itself should live in another cookie.
+func (s *SecureCookie) WithChannelBinding(ekm []byte) *SecureCookie {
+	return s.bind("channel", ekm)
+}
+
+// ChannelBindingRequest returns a copy of s bound to the TLS connection r was
+// received over, using 32 bytes of keying material exported with
+// ChannelBindingLabel. It fails if r was not received over TLS, e.g. behind
+// a TLS-terminating proxy.
+func (s *SecureCookie) ChannelBindingRequest(r *http.Request) (*SecureCookie, error) {
+	if r.TLS == nil {
+		return nil, errNoTLS
+	}
+	ekm, err := r.TLS.ExportKeyingMaterial(ChannelBindingLabel, nil, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errChannelBindingEKM, err)
+	}
+	return s.WithChannelBinding(ekm), nil
+}
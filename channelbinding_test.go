@@ -0,0 +1,56 @@
+package securecookie
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChannelBinding(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	var encoded string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := s.ChannelBindingRequest(r)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if encoded == "" {
+			if encoded, err = c.Encode("sid", "alice"); err != nil {
+				t.Error(err)
+			}
+			return
+		}
+		var dst string
+		if err := c.Decode("sid", encoded, &dst); err != nil {
+			io.WriteString(w, err.Error())
+			return
+		}
+		io.WriteString(w, dst)
+	}))
+	defer srv.Close()
+	client := srv.Client()
+	get := func() string {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	get()
+	if got := get(); got != "alice" {
+		t.Fatalf("Expected the value to decode on the same connection, got %q", got)
+	}
+	client.CloseIdleConnections()
+	if got := get(); got != ErrMacInvalid.Error() {
+		t.Fatalf("Expected ErrMacInvalid on another connection, got %q", got)
+	}
+
+	if _, err := s.ChannelBindingRequest(httptest.NewRequest("GET", "/", nil)); err != errNoTLS {
+		t.Fatalf("Expected errNoTLS, got %v", err)
+	}
+}
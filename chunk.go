@@ -0,0 +1,243 @@
+package securecookie
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultChunkSize leaves room for the name and attributes of a chunk
+	// within the 4096 bytes browsers accept per cookie.
+	defaultChunkSize = 3800
+	// maxChunks bounds the number of chunks read for a value.
+	maxChunks = 64
+	// chunkTagSize is the size of the tag of each chunk, in bytes.
+	chunkTagSize = 16
+	// chunkTagSep separates a chunk from its tag. It is not used by
+	// base64url.
+	chunkTagSep = "~"
+)
+
+var (
+	errChunkCount   = Error{msg: "chunked cookie has an invalid chunk count"}
+	errChunkMissing = Error{msg: "chunked cookie is missing a chunk"}
+	errChunkInvalid = Error{msg: "chunk is invalid, reordered or from another value"}
+)
+
+// chunkCodec is implemented by codecs that authenticate each chunk.
+type chunkCodec interface {
+	EncodeChunks(name string, value interface{}, size int) ([]string, error)
+	DecodeChunks(name string, chunks []string, dst interface{}) error
+}
+
+// WriteChunked encodes value with codec under the name of c and writes it as
+// the cookies "name.0", "name.1", ..., each holding at most size bytes of the
+// encoded value, or 3800 if size is 0. The first chunk records the number of
+// chunks. The other fields of c are used as attributes of every chunk, and
+// chunks left over from a previous, longer value sent with r are expired.
+//
+// Values are authenticated as a whole, so chunks that are reordered, mixed
+// with chunks of another value or missing fail to decode. If codec is a
+// *SecureCookie, every chunk is also authenticated on its own, see
+// SecureCookie.EncodeChunks. As the encoded value exceeds 4096 bytes, the
+// MaxLength of the codec must be raised, or its overflow policy set to
+// OverflowChunk.
+func WriteChunked(w http.ResponseWriter, r *http.Request, codec Codec, c *http.Cookie, value interface{}, size int) error {
+	codec, err := RequestCodec(codec, r)
+	if err != nil {
+		return err
+	}
+	if cc, ok := codec.(chunkCodec); ok {
+		chunks, err := cc.EncodeChunks(c.Name, value, size)
+		if err != nil {
+			return err
+		}
+		return writeChunks(w, r, c, chunks)
+	}
+	encoded, err := codec.Encode(c.Name, value)
+	if err != nil {
+		return err
+	}
+	return writeChunks(w, r, c, splitChunks(encoded, size))
+}
+
+// ReadChunked reads the chunks written by WriteChunked for the named cookie
+// and decodes the reassembled value with codec into dst.
+func ReadChunked(r *http.Request, codec Codec, name string, dst interface{}) error {
+	codec, err := RequestCodec(codec, r)
+	if err != nil {
+		return err
+	}
+	chunks, err := readChunks(r, name)
+	if err != nil {
+		return err
+	}
+	if cc, ok := codec.(chunkCodec); ok {
+		return cc.DecodeChunks(name, chunks, dst)
+	}
+	return codec.Decode(name, strings.Join(chunks, ""), dst)
+}
+
+// EncodeChunks encodes value and splits the result into chunks of at most
+// size bytes of encoded value, or 3800 if size is 0, for callers storing a
+// value across several cookies or fields. Each chunk carries a tag
+// authenticating the name, its index, the number of chunks and the whole
+// value, so deleting, reordering or swapping chunks is detected as
+// tampering, and DecodeChunks reports the index of the first chunk that
+// does not verify.
+func (s *SecureCookie) EncodeChunks(name string, value interface{}, size int) ([]string, error) {
+	var encoded string
+	if s.overflow == OverflowChunk {
+		out, err := s.encodeValue(name, value, nil)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(out)
+	} else {
+		var err error
+		if encoded, err = s.Encode(name, value); err != nil {
+			return nil, err
+		}
+	}
+	chunks := splitChunks(encoded, size)
+	for i, tag := range s.chunkTags(name, encoded, len(chunks)) {
+		chunks[i] += chunkTagSep + base64.RawURLEncoding.EncodeToString(tag)
+	}
+	return chunks, nil
+}
+
+// DecodeChunks verifies the chunks returned by EncodeChunks and decodes the
+// reassembled value into dst.
+func (s *SecureCookie) DecodeChunks(name string, chunks []string, dst interface{}) error {
+	if len(chunks) == 0 || len(chunks) > maxChunks {
+		return errChunkCount
+	}
+	parts := make([]string, len(chunks))
+	tags := make([][]byte, len(chunks))
+	for i, chunk := range chunks {
+		j := strings.LastIndex(chunk, chunkTagSep)
+		if j < 0 {
+			return fmt.Errorf("%w: %d", errChunkInvalid, i)
+		}
+		tag, err := base64.RawURLEncoding.DecodeString(chunk[j+len(chunkTagSep):])
+		if err != nil {
+			return fmt.Errorf("%w: %d", errChunkInvalid, i)
+		}
+		parts[i], tags[i] = chunk[:j], tag
+	}
+	encoded := strings.Join(parts, "")
+	for i, want := range s.chunkTags(name, encoded, len(chunks)) {
+		if !hmac.Equal(tags[i], want) {
+			return fmt.Errorf("%w: %d", errChunkInvalid, i)
+		}
+	}
+	if s.overflow == OverflowChunk {
+		err := s.decode(name, encoded, dst, 0, nil)
+		if err != nil {
+			s.sampleFailure(name, encoded, err)
+		}
+		return err
+	}
+	return s.Decode(name, encoded, dst)
+}
+
+// chunkTags returns the tags of the n chunks of an encoded value.
+func (s *SecureCookie) chunkTags(name, encoded string, n int) [][]byte {
+	key := hkdf(sha256.New, s.hashKey, nil, []byte("securecookie chunk"), 32)
+	digest := sha256.Sum256([]byte(encoded))
+	tags := make([][]byte, n)
+	for i := range tags {
+		h := hmac.New(sha256.New, key)
+		var buf [4]byte
+		binary.BigEndian.PutUint16(buf[:2], uint16(i))
+		binary.BigEndian.PutUint16(buf[2:], uint16(n))
+		h.Write(buf[:])
+		h.Write(digest[:])
+		h.Write([]byte(name))
+		tags[i] = h.Sum(nil)[:chunkTagSize]
+	}
+	return tags
+}
+
+// splitChunks splits s into chunks of at most size bytes.
+func splitChunks(s string, size int) []string {
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+	var chunks []string
+	for len(s) > size {
+		chunks = append(chunks, s[:size])
+		s = s[size:]
+	}
+	return append(chunks, s)
+}
+
+// writeChunks writes chunks as cookies named after c, and expires stale
+// chunks sent with r.
+func writeChunks(w http.ResponseWriter, r *http.Request, c *http.Cookie, chunks []string) error {
+	for _, cc := range chunkCookies(c, chunks) {
+		if err := WriteCookie(w, r, cc); err != nil {
+			return err
+		}
+	}
+	if r == nil {
+		return nil
+	}
+	for i := len(chunks); i < maxChunks; i++ {
+		if _, err := r.Cookie(chunkName(c.Name, i)); err != nil {
+			break
+		}
+		cc := *c
+		cc.Name, cc.Value, cc.MaxAge = chunkName(c.Name, i), "", -1
+		http.SetCookie(w, &cc)
+	}
+	return nil
+}
+
+// chunkCookies returns the cookies holding chunks, named after c and with
+// its attributes.
+func chunkCookies(c *http.Cookie, chunks []string) []*http.Cookie {
+	cookies := make([]*http.Cookie, len(chunks))
+	for i, chunk := range chunks {
+		cc := *c
+		cc.Name = chunkName(c.Name, i)
+		cc.Value = chunk
+		if i == 0 {
+			cc.Value = strconv.Itoa(len(chunks)) + "." + chunk
+		}
+		cookies[i] = &cc
+	}
+	return cookies
+}
+
+// readChunks returns the chunks of the named cookie sent with r.
+func readChunks(r *http.Request, name string) ([]string, error) {
+	first, err := r.Cookie(chunkName(name, 0))
+	if err != nil {
+		return nil, err
+	}
+	count, chunk, ok := strings.Cut(first.Value, ".")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n < 1 || n > maxChunks {
+		return nil, errChunkCount
+	}
+	chunks := []string{chunk}
+	for i := 1; i < n; i++ {
+		c, err := r.Cookie(chunkName(name, i))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %d of %d", errChunkMissing, i, n)
+		}
+		chunks = append(chunks, c.Value)
+	}
+	return chunks, nil
+}
+
+func chunkName(name string, i int) string {
+	return name + "." + strconv.Itoa(i)
+}
@@ -0,0 +1,81 @@
+package securecookie
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChunked(t *testing.T) {
+	s := New([]byte("12345"), []byte("1234567890123456")).MaxLength(0)
+	value := strings.Repeat("x", 10000)
+
+	w := httptest.NewRecorder()
+	if err := WriteChunked(w, nil, s, &http.Cookie{Name: "big", Path: "/"}, value, 0); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 4 || cookies[3].Name != "big.3" {
+		t.Fatalf("Expected 4 chunks, got %v", cookies)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	var dst string
+	if err := ReadChunked(r, s, "big", &dst); err != nil || dst != value {
+		t.Fatalf("Expected the value back, got %v", err)
+	}
+
+	// Swapping two chunks breaks the MAC.
+	r = httptest.NewRequest("GET", "/", nil)
+	cookies[1].Value, cookies[2].Value = cookies[2].Value, cookies[1].Value
+	for _, c := range cookies[:3] {
+		r.AddCookie(c)
+	}
+	if err := ReadChunked(r, s, "big", &dst); !errors.Is(err, errChunkMissing) {
+		t.Fatalf("Expected errChunkMissing, got %v", err)
+	}
+	r.AddCookie(cookies[3])
+	if err := ReadChunked(r, s, "big", &dst); !errors.Is(err, errChunkInvalid) {
+		t.Fatalf("Expected errChunkInvalid, got %v", err)
+	}
+
+	// A shorter value expires the stale chunks.
+	w = httptest.NewRecorder()
+	if err := WriteChunked(w, r, s, &http.Cookie{Name: "big"}, "small", 0); err != nil {
+		t.Fatal(err)
+	}
+	cookies = w.Result().Cookies()
+	if len(cookies) != 4 || cookies[1].MaxAge != -1 || cookies[3].MaxAge != -1 {
+		t.Fatalf("Expected 1 chunk and 3 expired ones, got %v", cookies)
+	}
+}
+
+func TestEncodeChunks(t *testing.T) {
+	s := New([]byte("12345"), nil).MaxLength(0)
+	value := strings.Repeat("x", 1000)
+	chunks, err := s.EncodeChunks("big", value, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := s.DecodeChunks("big", chunks, &dst); err != nil || dst != value {
+		t.Fatalf("Expected the value back, got %v", err)
+	}
+
+	other, _ := s.EncodeChunks("big", strings.Repeat("y", 1000), 300)
+	mixed := append([]string{chunks[0], other[1]}, chunks[2:]...)
+	if err := s.DecodeChunks("big", mixed, &dst); !errors.Is(err, errChunkInvalid) {
+		t.Fatalf("Expected errChunkInvalid, got %v", err)
+	}
+	if err := s.DecodeChunks("big", chunks[:len(chunks)-1], &dst); !errors.Is(err, errChunkInvalid) {
+		t.Fatalf("Expected errChunkInvalid, got %v", err)
+	}
+	if err := s.DecodeChunks("other", chunks, &dst); !errors.Is(err, errChunkInvalid) {
+		t.Fatalf("Expected errChunkInvalid, got %v", err)
+	}
+}
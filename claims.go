@@ -0,0 +1,69 @@
+package securecookie
+
+import (
+	"fmt"
+	"time"
+)
+
+var (
+	errClaimsExpired = Error{msg: "claims are expired"}
+	errClaimsPurpose = Error{msg: "claims are for another purpose"}
+)
+
+// Claims is a payload with standard fields, like those of JWTs, so that
+// applications do not reinvent expiry and purpose fields in their own
+// payloads. Times are Unix timestamps in seconds, 0 if unset. Custom holds
+// application fields, and must be supported by the serializer.
+type Claims struct {
+	Expiry    int64                  `json:"exp,omitempty"`
+	NotBefore int64                  `json:"nbf,omitempty"`
+	IssuedAt  int64                  `json:"iat,omitempty"`
+	Subject   string                 `json:"sub,omitempty"`
+	Purpose   string                 `json:"purpose,omitempty"`
+	Custom    map[string]interface{} `json:"custom,omitempty"`
+}
+
+// Validate checks that the claims are valid at now, and for purpose if it is
+// not empty. Claims not valid yet fail with a *NotBeforeError.
+func (c *Claims) Validate(now time.Time, purpose string) error {
+	return c.validate(now, 0, purpose)
+}
+
+// validate is Validate tolerating a clock skew of leeway, see Leeway.
+func (c *Claims) validate(now time.Time, leeway time.Duration, purpose string) error {
+	t, skew := now.Unix(), int64(leeway/time.Second)
+	if c.Expiry != 0 && t-skew >= c.Expiry {
+		return fmt.Errorf("%w: at %d", errClaimsExpired, c.Expiry)
+	}
+	if c.NotBefore != 0 && t+skew < c.NotBefore {
+		return &NotBeforeError{NotBefore: time.Unix(c.NotBefore, 0)}
+	}
+	if purpose != "" && c.Purpose != purpose {
+		return fmt.Errorf("%w: %q", errClaimsPurpose, c.Purpose)
+	}
+	return nil
+}
+
+// EncodeClaims encodes claims like Encode, setting IssuedAt to the current
+// time if it is 0.
+func (s *SecureCookie) EncodeClaims(name string, c *Claims) (string, error) {
+	if c.IssuedAt == 0 {
+		claims := *c
+		claims.IssuedAt = s.timestamp()
+		c = &claims
+	}
+	return s.Encode(name, c)
+}
+
+// DecodeClaims decodes claims encoded by EncodeClaims and validates them for
+// purpose, see Claims.Validate, tolerating the clock skew set by Leeway.
+func (s *SecureCookie) DecodeClaims(name, value, purpose string) (*Claims, error) {
+	c := &Claims{}
+	if err := s.Decode(name, value, c); err != nil {
+		return nil, err
+	}
+	if err := c.validate(time.Unix(s.timestamp(), 0), s.leeway, purpose); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,61 @@
+package securecookie
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClaims(t *testing.T) {
+	now := int64(1700000000)
+	s := New([]byte("12345"), []byte("1234567890123456"))
+	s.timeFunc = func() int64 { return now }
+	encoded, err := s.EncodeClaims("token", &Claims{
+		Expiry:    now + 60,
+		NotBefore: now + 10,
+		Subject:   "alice",
+		Purpose:   "reset-password",
+		Custom:    map[string]interface{}{"tenant": "acme"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nbf *NotBeforeError
+	if _, err := s.DecodeClaims("token", encoded, "reset-password"); !errors.As(err, &nbf) || nbf.NotBefore.Unix() != now+10 {
+		t.Fatalf("Expected a *NotBeforeError, got %v", err)
+	}
+	now += 10
+	c, err := s.DecodeClaims("token", encoded, "reset-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.IssuedAt != now-10 || c.Subject != "alice" || c.Custom["tenant"] != "acme" {
+		t.Fatalf("Unexpected claims: %+v", c)
+	}
+	if _, err := s.DecodeClaims("token", encoded, "login"); !errors.Is(err, errClaimsPurpose) {
+		t.Fatalf("Expected errClaimsPurpose, got %v", err)
+	}
+	now += 50
+	if _, err := s.DecodeClaims("token", encoded, ""); !errors.Is(err, errClaimsExpired) {
+		t.Fatalf("Expected errClaimsExpired, got %v", err)
+	}
+}
+
+func TestClaimsLeeway(t *testing.T) {
+	now := int64(1700000000)
+	s := New([]byte("12345"), nil).MaxAge(0).Leeway(5 * time.Second)
+	s.timeFunc = func() int64 { return now }
+	encoded, _ := s.EncodeClaims("token", &Claims{Expiry: now + 60, NotBefore: now + 10})
+
+	for _, tc := range []struct {
+		at  int64
+		err bool
+	}{
+		{now + 4, true}, {now + 5, false}, {now + 64, false}, {now + 65, true},
+	} {
+		now = tc.at
+		if _, err := s.DecodeClaims("token", encoded, ""); (err != nil) != tc.err {
+			t.Errorf("At %d, expected error %v, got %v", tc.at, tc.err, err)
+		}
+	}
+}
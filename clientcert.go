@@ -0,0 +1,60 @@
+package securecookie
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"net/http"
+)
+
+var errNoClientCertificate = Error{msg: "request has no client certificate"}
+
+// BindClientCertificate makes the HTTP helpers of this package bind values
+// to the client certificate of the request, for services using mutual TLS;
+// see ClientCertificateRequest. Requests without a client certificate fail.
+// Encode and Decode, which do not see the request, are not affected.
+//
+// Default is false.
+func (s *SecureCookie) BindClientCertificate(enabled bool) *SecureCookie {
+	s.clientCert = enabled
+	return s
+}
+
+// WithClientCertificate returns a copy of s that binds values to the SHA-256
+// fingerprint of cert: values encoded by the copy only decode for the same
+// certificate.
+func (s *SecureCookie) WithClientCertificate(cert *x509.Certificate) *SecureCookie {
+	sum := sha256.Sum256(cert.Raw)
+	return s.bind("client-certificate", sum[:])
+}
+
+// ClientCertificateRequest returns a copy of s bound to the certificate the
+// client presented for r, see WithClientCertificate. It fails if r carries
+// none, e.g. behind a TLS-terminating proxy.
+func (s *SecureCookie) ClientCertificateRequest(r *http.Request) (*SecureCookie, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, errNoClientCertificate
+	}
+	return s.WithClientCertificate(r.TLS.PeerCertificates[0]), nil
+}
+
+// forRequest returns the codec the HTTP helpers use for r.
+func (s *SecureCookie) forRequest(r *http.Request) (*SecureCookie, error) {
+	if s.requireTLS && !s.proxyTrust.IsTLS(r) {
+		return nil, errInsecureRequest
+	}
+	if !s.clientCert {
+		return s, nil
+	}
+	return s.ClientCertificateRequest(r)
+}
+
+// RequestCodec returns codec as the HTTP helpers of this package use it for
+// r, for adapters encoding or decoding outside of them: a *SecureCookie
+// enforces RequireTLS and BindClientCertificate, other codecs are returned as
+// is.
+func RequestCodec(codec Codec, r *http.Request) (Codec, error) {
+	if s, ok := codec.(*SecureCookie); ok {
+		return s.forRequest(r)
+	}
+	return codec, nil
+}
@@ -0,0 +1,67 @@
+package securecookie
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientCertificate(t *testing.T) {
+	s := New([]byte("12345"), nil).BindClientCertificate(true)
+	request := func(raw string, value string) *http.Request {
+		r := httptest.NewRequest("GET", "https://svc.internal/", nil)
+		if raw != "" {
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Raw: []byte(raw)}}}
+		}
+		if value != "" {
+			r.AddCookie(&http.Cookie{Name: "sid", Value: value})
+		}
+		return r
+	}
+	c, err := s.ClientCertificateRequest(request("cert-a", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := c.Encode("sid", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dst string
+	if _, err := DecodeFirstValid(request("cert-a", encoded), s, "sid", &dst); err != nil || dst != "alice" {
+		t.Fatalf("Expected %q, got %q, %v", "alice", dst, err)
+	}
+	rejected, err := DecodeFirstValid(request("cert-b", encoded), s, "sid", &dst)
+	if err == nil || len(rejected) != 1 || rejected[0].Err != ErrMacInvalid {
+		t.Fatalf("Expected ErrMacInvalid for another certificate, got %v, %v", rejected, err)
+	}
+	if _, err := DecodeFirstValid(request("", encoded), s, "sid", &dst); err != errNoClientCertificate {
+		t.Fatalf("Expected errNoClientCertificate, got %v", err)
+	}
+	if err := s.Decode("sid", encoded, &dst); err != ErrMacInvalid {
+		t.Fatalf("Expected Decode to ignore the request, got %v", err)
+	}
+}
+
+func TestClientCertificateSetCookie(t *testing.T) {
+	s := New([]byte("12345"), nil).BindClientCertificate(true)
+	r := httptest.NewRequest("GET", "https://svc.internal/", nil)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Raw: []byte("cert-a")}}}
+	w := httptest.NewRecorder()
+	if err := s.SetCookie(w, r, "sid", "alice", nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	var dst string
+	if err := s.GetCookie(r, "sid", &dst); err != nil || dst != "alice" {
+		t.Fatalf("Expected %q, got %q, %v", "alice", dst, err)
+	}
+	r.TLS = nil
+	if err := s.SetCookie(httptest.NewRecorder(), r, "sid", "alice", nil); err != errNoClientCertificate {
+		t.Fatalf("Expected errNoClientCertificate, got %v", err)
+	}
+}
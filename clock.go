@@ -0,0 +1,34 @@
+package securecookie
+
+import "time"
+
+// Clock tells the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to a Clock.
+type ClockFunc func() time.Time
+
+// Now returns f().
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SetClock sets the clock used for timestamps, their age checks and
+// not-before times, e.g. so that tests and systems replaying traffic control
+// time deterministically.
+//
+// Default is the system clock.
+func (s *SecureCookie) SetClock(c Clock) *SecureCookie {
+	s.clock = c
+	return s
+}
+
+// now returns the time of the clock.
+func (s *SecureCookie) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
@@ -0,0 +1,62 @@
+package securecookie
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetClock(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := New([]byte("12345"), nil).MinAge(10).MaxAge(60).
+		SetClock(ClockFunc(func() time.Time { return now }))
+	encoded, err := s.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	for _, tt := range []struct {
+		after time.Duration
+		err   error
+	}{
+		{0, errTimestampTooNew},
+		{9 * time.Second, errTimestampTooNew},
+		{10 * time.Second, nil},
+		{60 * time.Second, nil},
+		{61 * time.Second, errTimestampExpired},
+	} {
+		now = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Add(tt.after)
+		if err := s.Decode("sid", encoded, &dst); err != tt.err {
+			t.Fatalf("After %v: expected %v, got %v", tt.after, tt.err, err)
+		}
+	}
+}
+
+func TestLeeway(t *testing.T) {
+	issued := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := issued
+	clock := ClockFunc(func() time.Time { return now })
+	for _, precision := range []time.Duration{time.Second, time.Millisecond} {
+		s := New([]byte("12345"), nil).MinAge(10).MaxAge(60).Leeway(30 * time.Second).
+			TimestampPrecision(precision).SetClock(clock)
+		now = issued
+		encoded, err := s.EncodeWith("sid", "value", NotBefore(issued.Add(40*time.Second)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dst string
+		for _, tt := range []struct {
+			after time.Duration
+			ok    bool
+		}{
+			{9 * time.Second, false},
+			{10 * time.Second, true},
+			{90 * time.Second, true},
+			{91 * time.Second, false},
+		} {
+			now = issued.Add(tt.after)
+			if err := s.Decode("sid", encoded, &dst); (err == nil) != tt.ok {
+				t.Fatalf("%v after %v: expected ok=%v, got %v", precision, tt.after, tt.ok, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package securecookie
+
+import (
+	"crypto/aes"
+	"sync/atomic"
+)
+
+var errCodecClosed = Error{msg: "codec is closed"}
+
+// Close zeroes the keys held by s and makes every later call to Encode and
+// Decode fail, on s and on the copies derived from it, e.g. by WithFingerprint
+// or WithPurpose. The keys passed to New are copies, so Close does not touch
+// the caller's slices. The cipher.Block built from the block key is released
+// but, as its key schedule is not exposed, it cannot be wiped.
+//
+// Close must not be called concurrently with Encode or Decode.
+func (s *SecureCookie) Close() error {
+	if s.closed == nil {
+		s.closed = new(atomic.Bool)
+	}
+	// Copies check the flag before using the keys they share with s.
+	s.closed.Store(true)
+	wipe(s.hashKey)
+	wipe(s.blockKey)
+	s.hashKey, s.blockKey, s.block = nil, nil, nil
+	s.err = errCodecClosed
+	return nil
+}
+
+// isClosed reports whether Close was called on s or on the codec s was
+// copied from.
+func (s *SecureCookie) isClosed() bool {
+	return s.closed != nil && s.closed.Load()
+}
+
+// SetKeys replaces the keys of s. The previous keys are not zeroed, as copies
+// derived from s before, e.g. by WithFingerprint, keep using them; Close
+// zeroes the current ones. The block cipher is rebuilt with the function last
+// given to BlockFunc, AES by default, and keys are stretched again if s was
+// created by NewStretched. Errors caused by the previous keys are cleared,
+// and the new keys are checked again, e.g. by a strict ValidateKeys or FIPS;
+// other configuration errors remain. It has no effect on a closed codec.
+//
+// SetKeys must not be called concurrently with Encode or Decode.
+func (s *SecureCookie) SetKeys(hashKey, blockKey []byte) *SecureCookie {
+	if s.isClosed() {
+		return s
+	}
+	configErr := s.err
+	if configErr == s.keyErr {
+		configErr = nil
+	}
+	s.err, s.keyErr = nil, nil
+	if len(hashKey) == 0 {
+		s.setKeyErr(errHashKeyNotSet)
+	} else {
+		if s.flags&flagKeysStretched != 0 {
+			hashKey, blockKey = stretchKeys(hashKey, blockKey)
+		}
+		s.hashKey, s.blockKey, s.block = cloneKey(hashKey), cloneKey(blockKey), nil
+		if blockKey != nil {
+			f := s.blockFunc
+			if f == nil {
+				f = aes.NewCipher
+			}
+			s.BlockFunc(f)
+		}
+		if s.strictKeys && s.err == nil {
+			s.ValidateKeys(nil)
+		}
+	}
+	if configErr != nil {
+		s.err = configErr
+	}
+	return s
+}
+
+// setKeyErr records err, caused by the keys, as the error of s.
+func (s *SecureCookie) setKeyErr(err error) {
+	s.err, s.keyErr = err, err
+}
+
+// cloneKey returns a copy of key, preserving nil.
+func cloneKey(key []byte) []byte {
+	if key == nil {
+		return nil
+	}
+	return append(make([]byte, 0, len(key)), key...)
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,115 @@
+package securecookie
+
+import (
+	"crypto/md5"
+	"errors"
+	"testing"
+)
+
+func TestClose(t *testing.T) {
+	hashKey := []byte("12345")
+	s := New(hashKey, []byte("1234567890123456"))
+	encoded, err := s.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	held := s.hashKey
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !allZero(held) {
+		t.Fatal("Expected the hash key to be zeroed")
+	}
+	if string(hashKey) != "12345" {
+		t.Fatal("Expected the caller's key to be untouched")
+	}
+	var dst string
+	if err := s.Decode("sid", encoded, &dst); !errors.Is(err, errCodecClosed) {
+		t.Fatalf("Expected errCodecClosed, got %v", err)
+	}
+	if s.SetKeys([]byte("12345"), nil); !errors.Is(s.err, errCodecClosed) {
+		t.Fatal("Expected SetKeys to keep the codec closed")
+	}
+}
+
+func TestSetKeys(t *testing.T) {
+	for _, newCodec := range []func([]byte, []byte) *SecureCookie{New, NewStretched} {
+		s := newCodec([]byte("12345"), []byte("1234567890123456"))
+		old := s.hashKey
+		s.SetKeys([]byte("67890"), []byte("6543210987654321"))
+		if allZero(old) {
+			t.Fatal("Expected the previous hash key to be kept for copies")
+		}
+		encoded, err := s.Encode("sid", "value")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dst string
+		if err := newCodec([]byte("67890"), []byte("6543210987654321")).Decode("sid", encoded, &dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSetKeysCopies(t *testing.T) {
+	fp := []byte("fingerprint")
+	s := New(GenerateRandomKey(32), nil)
+	bound := s.WithFingerprint(fp)
+	before, err := bound.Encode("sid", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetKeys(GenerateRandomKey(32), nil)
+
+	forged, err := New(make([]byte, 32), nil).WithFingerprint(fp).Encode("sid", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := bound.Decode("sid", forged, &dst); err == nil {
+		t.Fatalf("Expected a value forged with a zero key to fail, got %q", dst)
+	}
+	if err := bound.Decode("sid", before, &dst); err != nil || dst != "alice" {
+		t.Fatalf("Expected the copy to keep its keys, got %q, %v", dst, err)
+	}
+
+	s.Close()
+	if err := bound.Decode("sid", forged, &dst); !errors.Is(err, errCodecClosed) {
+		t.Fatalf("Expected errCodecClosed for a copy of a closed codec, got %v", err)
+	}
+	if _, err := bound.Encode("sid", "alice"); !errors.Is(err, errCodecClosed) {
+		t.Fatalf("Expected errCodecClosed for a copy of a closed codec, got %v", err)
+	}
+}
+
+func TestSetKeysErrors(t *testing.T) {
+	strong := GenerateRandomKey(32)
+
+	// Errors caused by the keys are cleared.
+	s := New([]byte("12345"), []byte("short"))
+	if _, err := s.Encode("sid", "value"); err == nil {
+		t.Fatal("Expected an invalid block key to fail")
+	}
+	if _, err := s.SetKeys(strong, nil).Encode("sid", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Strict validation runs again on the new keys.
+	s = New(strong, nil).ValidateKeys(nil)
+	if _, err := s.SetKeys([]byte("12345"), nil).Encode("sid", "value"); !errors.Is(err, errKeyTooShort) {
+		t.Fatalf("Expected errKeyTooShort, got %v", err)
+	}
+	if _, err := s.SetKeys(GenerateRandomKey(32), nil).Encode("sid", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Other configuration errors remain.
+	s = New(strong, nil).FormatVersion(9)
+	if _, err := s.SetKeys(GenerateRandomKey(32), nil).Encode("sid", "value"); err != errFormatVersion {
+		t.Fatalf("Expected errFormatVersion, got %v", err)
+	}
+	s = New(strong, nil).HashFunc(md5.New).FIPS(true)
+	if _, err := s.SetKeys(GenerateRandomKey(32), nil).Encode("sid", "value"); err != errFIPSHash {
+		t.Fatalf("Expected errFIPSHash, got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// genAnnotation marks the structs gen emits methods for.
+const genAnnotation = "securecookie:binary"
+
+// genField is a field of an annotated struct.
+type genField struct {
+	name string
+	kind string
+}
+
+// genStruct is an annotated struct.
+type genStruct struct {
+	name   string
+	fields []genField
+}
+
+// generate returns the source of MarshalBinary and UnmarshalBinary methods for
+// the structs of src annotated with genAnnotation, or nil if there are none.
+func generate(filename string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var structs []genStruct
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok || !annotated(gd.Doc, ts.Doc) {
+				continue
+			}
+			s := genStruct{name: ts.Name.Name}
+			for _, field := range st.Fields.List {
+				kind, ok := fieldKind(field.Type)
+				if !ok {
+					return nil, fmt.Errorf("%s: %s: unsupported field type", fset.Position(field.Pos()), s.name)
+				}
+				for _, name := range field.Names {
+					if name.Name != "_" {
+						s.fields = append(s.fields, genField{name: name.Name, kind: kind})
+					}
+				}
+				if len(field.Names) == 0 {
+					return nil, fmt.Errorf("%s: %s: embedded fields are not supported", fset.Position(field.Pos()), s.name)
+				}
+			}
+			structs = append(structs, s)
+		}
+	}
+	if len(structs) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by securecookie gen; DO NOT EDIT.\n\npackage %s\n\n", f.Name.Name)
+	imports := map[string]bool{"errors": true}
+	for _, s := range structs {
+		for _, f := range s.fields {
+			switch f.kind {
+			case "bool":
+			case "float32", "float64":
+				imports["encoding/binary"], imports["math"] = true, true
+			default:
+				imports["encoding/binary"] = true
+			}
+		}
+	}
+	buf.WriteString("import (\n")
+	for _, path := range []string{"encoding/binary", "errors", "math"} {
+		if imports[path] {
+			fmt.Fprintf(&buf, "\t%q\n", path)
+		}
+	}
+	buf.WriteString(")\n")
+	for _, s := range structs {
+		writeMarshal(&buf, s)
+		writeUnmarshal(&buf, s)
+	}
+	return format.Source(buf.Bytes())
+}
+
+func annotated(groups ...*ast.CommentGroup) bool {
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		for _, c := range g.List {
+			if strings.TrimSpace(strings.TrimPrefix(c.Text, "//")) == genAnnotation {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fieldKind returns the kind of a supported field type: a basic type name
+// or "[]byte".
+func fieldKind(expr ast.Expr) (string, bool) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string", "bool", "float32", "float64",
+			"int", "int8", "int16", "int32", "int64",
+			"uint", "uint8", "uint16", "uint32", "uint64", "byte", "rune":
+			return t.Name, true
+		}
+	case *ast.ArrayType:
+		if id, ok := t.Elt.(*ast.Ident); ok && t.Len == nil && (id.Name == "byte" || id.Name == "uint8") {
+			return "[]byte", true
+		}
+	}
+	return "", false
+}
+
+func writeMarshal(buf *bytes.Buffer, s genStruct) {
+	fmt.Fprintf(buf, "\n// MarshalBinary encodes x without reflection.\n")
+	fmt.Fprintf(buf, "func (x %s) MarshalBinary() ([]byte, error) {\n\tvar b []byte\n", s.name)
+	for _, f := range s.fields {
+		v := "x." + f.name
+		switch f.kind {
+		case "string", "[]byte":
+			fmt.Fprintf(buf, "\tb = binary.AppendUvarint(b, uint64(len(%s)))\n\tb = append(b, %s...)\n", v, v)
+		case "bool":
+			fmt.Fprintf(buf, "\tif %s {\n\t\tb = append(b, 1)\n\t} else {\n\t\tb = append(b, 0)\n\t}\n", v)
+		case "float32":
+			fmt.Fprintf(buf, "\tb = binary.LittleEndian.AppendUint32(b, math.Float32bits(%s))\n", v)
+		case "float64":
+			fmt.Fprintf(buf, "\tb = binary.LittleEndian.AppendUint64(b, math.Float64bits(%s))\n", v)
+		case "int", "int8", "int16", "int32", "int64", "rune":
+			fmt.Fprintf(buf, "\tb = binary.AppendVarint(b, int64(%s))\n", v)
+		default:
+			fmt.Fprintf(buf, "\tb = binary.AppendUvarint(b, uint64(%s))\n", v)
+		}
+	}
+	buf.WriteString("\treturn b, nil\n}\n")
+}
+
+func writeUnmarshal(buf *bytes.Buffer, s genStruct) {
+	errName := "err" + strings.ToUpper(s.name[:1]) + s.name[1:] + "Malformed"
+	fmt.Fprintf(buf, "\nvar %s = errors.New(%q)\n", errName, s.name+": malformed binary encoding")
+	fmt.Fprintf(buf, "\n// UnmarshalBinary decodes x without reflection.\n")
+	fmt.Fprintf(buf, "func (x *%s) UnmarshalBinary(b []byte) error {\n", s.name)
+	for _, f := range s.fields {
+		v := "x." + f.name
+		buf.WriteString("\t{\n")
+		switch f.kind {
+		case "string", "[]byte":
+			buf.WriteString("\t\tu, n := binary.Uvarint(b)\n\t\tif n <= 0 || u > uint64(len(b)-n) {\n\t\t\treturn " + errName + "\n\t\t}\n")
+			if f.kind == "string" {
+				fmt.Fprintf(buf, "\t\t%s = string(b[n : n+int(u)])\n", v)
+			} else {
+				fmt.Fprintf(buf, "\t\t%s = append([]byte(nil), b[n:n+int(u)]...)\n", v)
+			}
+			buf.WriteString("\t\tb = b[n+int(u):]\n")
+		case "bool":
+			buf.WriteString("\t\tif len(b) < 1 || b[0] > 1 {\n\t\t\treturn " + errName + "\n\t\t}\n")
+			fmt.Fprintf(buf, "\t\t%s = b[0] == 1\n\t\tb = b[1:]\n", v)
+		case "float32":
+			buf.WriteString("\t\tif len(b) < 4 {\n\t\t\treturn " + errName + "\n\t\t}\n")
+			fmt.Fprintf(buf, "\t\t%s = math.Float32frombits(binary.LittleEndian.Uint32(b))\n\t\tb = b[4:]\n", v)
+		case "float64":
+			buf.WriteString("\t\tif len(b) < 8 {\n\t\t\treturn " + errName + "\n\t\t}\n")
+			fmt.Fprintf(buf, "\t\t%s = math.Float64frombits(binary.LittleEndian.Uint64(b))\n\t\tb = b[8:]\n", v)
+		case "int", "int8", "int16", "int32", "int64", "rune":
+			// Values out of the range of narrow types are rejected rather
+			// than truncated.
+			check := ""
+			if f.kind != "int64" {
+				check = fmt.Sprintf(" || int64(%s(i)) != i", f.kind)
+			}
+			buf.WriteString("\t\ti, n := binary.Varint(b)\n\t\tif n <= 0" + check + " {\n\t\t\treturn " + errName + "\n\t\t}\n")
+			fmt.Fprintf(buf, "\t\t%s = %s(i)\n\t\tb = b[n:]\n", v, f.kind)
+		default:
+			check := ""
+			if f.kind != "uint64" {
+				check = fmt.Sprintf(" || uint64(%s(u)) != u", f.kind)
+			}
+			buf.WriteString("\t\tu, n := binary.Uvarint(b)\n\t\tif n <= 0" + check + " {\n\t\t\treturn " + errName + "\n\t\t}\n")
+			fmt.Fprintf(buf, "\t\t%s = %s(u)\n\t\tb = b[n:]\n", v, f.kind)
+		}
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString("\tif len(b) != 0 {\n\t\treturn " + errName + "\n\t}\n\treturn nil\n}\n")
+}
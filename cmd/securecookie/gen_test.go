@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	src := `package app
+
+//securecookie:binary
+type Session struct {
+	User  string
+	Admin bool
+	Score float64
+}
+
+type Other struct {
+	Names []string
+}
+`
+	out, err := generate("app.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func (x Session) MarshalBinary() ([]byte, error)",
+		"func (x *Session) UnmarshalBinary(b []byte) error",
+		`"math"`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(string(out), "Other") {
+		t.Error("Expected unannotated structs to be skipped")
+	}
+
+	src = strings.Replace(src, "type Other", "//securecookie:binary\ntype Other", 1)
+	if _, err := generate("app.go", []byte(src)); err == nil || !strings.Contains(err.Error(), "unsupported field type") {
+		t.Fatalf("Expected an unsupported field type error, got %v", err)
+	}
+}
+
+// genSource has a struct with a field of every supported kind.
+const genSource = `package app
+
+//securecookie:binary
+type Kinds struct {
+	S   string
+	B   []byte
+	T   bool
+	F32 float32
+	F64 float64
+	I   int
+	I8  int8
+	I16 int16
+	I32 int32
+	I64 int64
+	R   rune
+	U   uint
+	U8  uint8
+	U16 uint16
+	U32 uint32
+	U64 uint64
+	By  byte
+}
+`
+
+// genRoundTrip tests and benchmarks the code generated for genSource.
+const genRoundTrip = `package app
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+var kinds = Kinds{
+	S: "alice", B: []byte{0, 1, 2}, T: true, F32: -1.5, F64: math.Pi,
+	I: -1 << 40, I8: math.MinInt8, I16: math.MaxInt16, I32: math.MinInt32, I64: math.MinInt64, R: 'é',
+	U: 1 << 40, U8: math.MaxUint8, U16: math.MaxUint16, U32: math.MaxUint32, U64: math.MaxUint64, By: 7,
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, want := range []Kinds{{}, kinds} {
+		b, err := want.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Kinds
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Expected %+v, got %+v", want, got)
+		}
+		for i := 0; i < len(b); i++ {
+			if err := got.UnmarshalBinary(b[:i]); err == nil {
+				t.Fatalf("Expected an error for %d of %d bytes", i, len(b))
+			}
+		}
+	}
+}
+
+func TestNarrowInts(t *testing.T) {
+	// I8 is the sixth field: S and B are empty, T, F32 and F64 are zero.
+	b, _ := Kinds{}.MarshalBinary()
+	prefix := b[:1+1+1+4+8+1]
+	rest := b[len(prefix)+1:]
+	for _, v := range []int64{math.MaxInt8 + 1, math.MinInt8 - 1} {
+		value := append(append(append([]byte(nil), prefix...), binary.AppendVarint(nil, v)...), rest...)
+		var got Kinds
+		if err := got.UnmarshalBinary(value); err == nil {
+			t.Fatalf("Expected an error for %d in an int8, got %d", v, got.I8)
+		}
+	}
+}
+
+func BenchmarkMarshalBinary(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		v, _ := kinds.MarshalBinary()
+		var k Kinds
+		if err := k.UnmarshalBinary(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSON(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		v, _ := json.Marshal(kinds)
+		var k Kinds
+		if err := json.Unmarshal(v, &k); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+`
+
+func TestGenerateCompiles(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	out, err := generate("app.go", []byte(genSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":            "module app\n\ngo 1.20\n",
+		"app.go":            genSource,
+		"app_gen.go":        string(out),
+		"roundtrip_test.go": genRoundTrip,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(gobin, "test", "-bench", ".", "-benchtime", "100x", "-benchmem")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off")
+	result, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s\n%s", err, result, out)
+	}
+	t.Logf("%s", result)
+}
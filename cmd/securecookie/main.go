@@ -0,0 +1,91 @@
+// Command securecookie provides tooling for applications using the
+// securecookie package.
+//
+// Usage:
+//
+//	securecookie diff old.json new.json
+//	securecookie gen file.go
+//
+// diff prints the cookies added (+), removed (-) or changed (~) between two
+// cookie manifests, and exits with status 1 if there are any.
+//
+// gen emits MarshalBinary and UnmarshalBinary methods for the structs of a
+// Go file whose doc comment contains the line "//securecookie:binary", into
+// file_binary.go. Serialized with securecookie.MarshalerEncoder, such values
+// are encoded without reflection. Fields must be of a basic type or []byte.
+// It is meant to be run by go generate:
+//
+//	//go:generate securecookie gen $GOFILE
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	securecookie "github.com/monime-lab/gorilla-securecookie"
+)
+
+const usage = `usage:
+	securecookie diff old.json new.json
+	securecookie gen file.go`
+
+func main() {
+	switch {
+	case len(os.Args) == 4 && os.Args[1] == "diff":
+		diff(os.Args[2], os.Args[3])
+	case len(os.Args) == 3 && os.Args[1] == "gen":
+		gen(os.Args[2])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func diff(oldPath, newPath string) {
+	old, err := readManifest(oldPath)
+	if err != nil {
+		fatal(err)
+	}
+	new, err := readManifest(newPath)
+	if err != nil {
+		fatal(err)
+	}
+	changes := securecookie.DiffManifests(old, new)
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+func gen(path string) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fatal(err)
+	}
+	out, err := generate(path, src)
+	if err != nil {
+		fatal(err)
+	}
+	if out == nil {
+		fatal(fmt.Errorf("%s: no struct annotated with //%s", path, genAnnotation))
+	}
+	if err := os.WriteFile(strings.TrimSuffix(path, ".go")+"_binary.go", out, 0o644); err != nil {
+		fatal(err)
+	}
+}
+
+func readManifest(path string) (*securecookie.Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return securecookie.ParseManifest(data)
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "securecookie:", err)
+	os.Exit(2)
+}
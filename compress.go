@@ -0,0 +1,186 @@
+package securecookie
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// flagCompressed marks values whose serialized data is compressed. The data
+// then starts with the ID of the Compressor used.
+const flagCompressed uint16 = 1 << 14
+
+// Compressor IDs of the compressors of this package.
+const (
+	compressGzip   byte = 1
+	compressZstd   byte = 2
+	compressBrotli byte = 3
+)
+
+// Default decompression limits.
+const (
+	defaultMaxDecompressedSize = 64 << 10
+	defaultMaxExpansionRatio   = 100
+)
+
+var (
+	errCompressionUnknown   = Error{msg: "value is compressed with an unknown method"}
+	errDecompressionFailed  = Error{msg: "the value could not be decompressed"}
+	errDecompressedTooLarge = Error{msg: "the value is too large once decompressed"}
+)
+
+// Compressor is a compression method for serialized values.
+type Compressor interface {
+	// ID identifies the method in encoded values. IDs below 16 are reserved
+	// for this package.
+	ID() byte
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// compressors are the compressors used to decode values compressed by other
+// codecs, by ID.
+var compressors = map[byte]Compressor{
+	compressGzip: GzipCompressor{},
+}
+
+// RegisterCompressor makes c available to decode values compressed with its
+// ID by any codec, replacing the compressor registered with the same ID. It
+// must be called at init time.
+func RegisterCompressor(c Compressor) {
+	compressors[c.ID()] = c
+}
+
+// GzipCompressor compresses with gzip.
+type GzipCompressor struct {
+	// Level is the compression level, see compress/gzip. Default is
+	// gzip.DefaultCompression.
+	Level int
+}
+
+// ID returns the ID of gzip.
+func (c GzipCompressor) ID() byte {
+	return compressGzip
+}
+
+// NewWriter returns a gzip writer.
+func (c GzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+// NewReader returns a gzip reader.
+func (c GzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// Compress enables gzip compression of serialized values, before encryption.
+// It is a shortcut for SetCompressor(GzipCompressor{}).
+func (s *SecureCookie) Compress(enabled bool) *SecureCookie {
+	if !enabled {
+		return s.SetCompressor(nil)
+	}
+	return s.SetCompressor(GzipCompressor{})
+}
+
+// SetCompressor sets the compressor of serialized values, applied before
+// encryption. Values are only stored compressed if that makes them smaller,
+// so tiny values do not pay for the compression header. Compressed values
+// are flagged with the compressor ID, so they are decoded whether or not the
+// decoding codec compresses its own values: the compressor of the codec is
+// used if its ID matches, or else the one registered with
+// RegisterCompressor.
+//
+// Encryption does not hide the length of compressed values, which depends on
+// their content. If a value holds both a secret, such as a CSRF token, and
+// data an attacker can influence, observing its length over several requests
+// may reveal the secret, as in the CRIME and BREACH attacks. Do not compress
+// such values.
+//
+// Default is nil (no compression).
+func (s *SecureCookie) SetCompressor(c Compressor) *SecureCookie {
+	s.compressor = c
+	return s
+}
+
+// MaxDecompressedSize restricts the size, in bytes, of decompressed values,
+// so a crafted value cannot allocate large amounts of memory on Decode.
+//
+// Default is 64 KiB. Set it to 0 for no restriction.
+func (s *SecureCookie) MaxDecompressedSize(value int) *SecureCookie {
+	s.maxDecompressedSize = value
+	return s
+}
+
+// MaxExpansionRatio restricts the size of decompressed values to the given
+// multiple of their compressed size.
+//
+// Default is 100. Set it to 0 for no restriction.
+func (s *SecureCookie) MaxExpansionRatio(value int) *SecureCookie {
+	s.maxExpansionRatio = value
+	return s
+}
+
+// decompressLimit returns the maximum decompressed size of a value of n
+// compressed bytes, or -1 if there is none.
+func (s *SecureCookie) decompressLimit(n int) int {
+	limit := -1
+	if s.maxDecompressedSize > 0 {
+		limit = s.maxDecompressedSize
+	}
+	if s.maxExpansionRatio > 0 && (limit < 0 || s.maxExpansionRatio*n < limit) {
+		limit = s.maxExpansionRatio * n
+	}
+	return limit
+}
+
+// compress compresses data with c, prefixed with its ID.
+func compress(c Compressor, data []byte) ([]byte, error) {
+	buf := bytes.NewBuffer([]byte{c.ID()})
+	w, err := c.NewWriter(buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress reverses compress.
+func (s *SecureCookie) decompress(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errCompressionUnknown
+	}
+	c := s.compressor
+	if c == nil || c.ID() != data[0] {
+		if c = compressors[data[0]]; c == nil {
+			return nil, errCompressionUnknown
+		}
+	}
+	r, err := c.NewReader(bytes.NewReader(data[1:]))
+	if err != nil {
+		return nil, errDecompressionFailed
+	}
+	defer r.Close()
+	var src io.Reader = r
+	limit := s.decompressLimit(len(data) - 1)
+	if limit >= 0 {
+		src = io.LimitReader(r, int64(limit)+1)
+	}
+	out, err := io.ReadAll(src)
+	if err != nil {
+		return nil, errDecompressionFailed
+	}
+	if limit >= 0 && len(out) > limit {
+		return nil, fmt.Errorf("%w: limit is %d", errDecompressedTooLarge, limit)
+	}
+	return out, nil
+}
@@ -0,0 +1,109 @@
+package securecookie
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	value := map[string]string{"prefs": strings.Repeat("dark-mode;", 200)}
+	plain := New([]byte("12345"), []byte("1234567890123456")).MaxLength(0)
+	compressed := New([]byte("12345"), []byte("1234567890123456")).Compress(true)
+
+	long, _ := plain.Encode("prefs", value)
+	short, err := compressed.Encode("prefs", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(short) >= len(long)/4 {
+		t.Fatalf("Expected compression, got %d bytes from %d", len(short), len(long))
+	}
+	// Compressed values are flagged, so any codec decodes them.
+	if header := payloadHeader(t, short); header&flagCompressed == 0 {
+		t.Fatal("Expected the value to be flagged as compressed")
+	}
+	for _, s := range []*SecureCookie{compressed, plain} {
+		var dst map[string]string
+		if err := s.Decode("prefs", short, &dst); err != nil || dst["prefs"] != value["prefs"] {
+			t.Fatalf("Expected the value back, got %v", err)
+		}
+	}
+}
+
+// customCompressor is gzip under another ID, to test registration.
+type customCompressor struct{ GzipCompressor }
+
+func (customCompressor) ID() byte { return 200 }
+
+func TestSetCompressor(t *testing.T) {
+	s := New([]byte("12345"), nil).SetCompressor(customCompressor{})
+	value := strings.Repeat("value", 100)
+	encoded, err := s.Encode("sid", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := s.Decode("sid", encoded, &dst); err != nil || dst != value {
+		t.Fatalf("Expected the value back, got %v", err)
+	}
+	other := New([]byte("12345"), nil)
+	if err := other.Decode("sid", encoded, &dst); err != errCompressionUnknown {
+		t.Fatalf("Expected errCompressionUnknown, got %v", err)
+	}
+	RegisterCompressor(customCompressor{})
+	defer delete(compressors, customCompressor{}.ID())
+	if err := other.Decode("sid", encoded, &dst); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompressAdaptive(t *testing.T) {
+	s := New([]byte("12345"), nil).Compress(true)
+	encoded, err := s.Encode("sid", "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header := payloadHeader(t, encoded); header&flagCompressed != 0 {
+		t.Fatal("Expected a tiny value to be stored uncompressed")
+	}
+	var dst string
+	if err := s.Decode("sid", encoded, &dst); err != nil || dst != "v" {
+		t.Fatalf("Expected v, got %q, %v", dst, err)
+	}
+}
+
+// payloadHeader returns the name length field of an encoded value.
+func payloadHeader(t *testing.T, encoded string) uint16 {
+	b, err := decode(base64.URLEncoding, []byte(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return binary.LittleEndian.Uint16(b[sha256.Size:])
+}
+
+func TestDecompressionLimits(t *testing.T) {
+	bomb := strings.Repeat("a", 1<<20)
+	s := New([]byte("12345"), nil).Compress(true).MaxDecompressedSize(0).MaxExpansionRatio(0)
+	encoded, err := s.Encode("sid", bomb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := s.Decode("sid", encoded, &dst); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*SecureCookie{
+		New([]byte("12345"), nil),
+		New([]byte("12345"), nil).MaxDecompressedSize(0),
+	} {
+		err := c.Decode("sid", encoded, &dst)
+		if !errors.Is(err, errDecompressedTooLarge) {
+			t.Fatalf("Expected errDecompressedTooLarge, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package securecookie
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	errHostPrefix   = Error{msg: "__Host- cookies must be Secure, for the path / and without a domain"}
+	errSecurePrefix = Error{msg: "__Secure- cookies must be Secure"}
+	errPartitioned  = Error{msg: "partitioned cookies must be Secure"}
+)
+
+// CookieOptions are the attributes of cookies built by NewCookie.
+type CookieOptions struct {
+	// Path defaults to "/".
+	Path   string
+	Domain string
+	// MaxAge is the lifetime of the cookie in seconds: 0 for a session
+	// cookie, and a negative number to delete it.
+	MaxAge   int
+	Secure   bool
+	HttpOnly bool
+	// SameSite=None requires Secure. The zero value leaves the attribute
+	// out, which browsers treat as Lax.
+	SameSite http.SameSite
+	// Partitioned sets the Partitioned attribute (CHIPS), which keys the
+	// cookie by the top-level site when embedded in a third-party context.
+	// It requires Secure. Before Go 1.23 http.Cookie has no such field, so
+	// only SetCookie writes it.
+	Partitioned bool
+	// AutoPrefix prefixes the name with __Host- or __Secure-, the strongest
+	// prefix the other attributes allow, if it has none. GetCookie finds
+	// such cookies by their unprefixed name.
+	AutoPrefix bool
+}
+
+// cookieOptions returns opts, or if it is nil the defaults of NewCookie for
+// the given max age.
+func cookieOptions(opts *CookieOptions, maxAge int) *CookieOptions {
+	if opts != nil {
+		return opts
+	}
+	return &CookieOptions{
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// NewCookie encodes value and returns it as the named cookie, with the
+// attributes of opts. If opts is nil, the cookie is Secure, HttpOnly,
+// SameSite=Lax, for the path "/" and lives as long as the MaxAge of s.
+// Expires is set along with MaxAge for clients that ignore the latter.
+//
+// The value is encoded for r, the request being answered, like GetCookie
+// decodes it: RequireTLS and BindClientCertificate apply. r may be nil, in
+// which case they are not checked.
+func (s *SecureCookie) NewCookie(r *http.Request, name string, value interface{}, opts *CookieOptions) (*http.Cookie, error) {
+	codec, err := s.forWriting(r)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := codec.Encode(name, value)
+	if err != nil {
+		return nil, err
+	}
+	return s.cookie(name, encoded, opts)
+}
+
+// NewChunkedCookies is like NewCookie for values too large for a single
+// cookie: the value is encoded by EncodeChunks and returned as cookies named
+// like those of WriteChunked, so that ReadChunked decodes them.
+func (s *SecureCookie) NewChunkedCookies(r *http.Request, name string, value interface{}, opts *CookieOptions, size int) ([]*http.Cookie, error) {
+	codec, err := s.forWriting(r)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := codec.EncodeChunks(name, value, size)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.cookie(name, "", opts)
+	if err != nil {
+		return nil, err
+	}
+	return chunkCookies(c, chunks), nil
+}
+
+// SetCookie encodes value for r and sets it as the named cookie on w, with
+// the attributes of NewCookie. Cookies with a __Host- or __Secure- name
+// prefix must be Secure, see CheckDowngrade.
+func (s *SecureCookie) SetCookie(w http.ResponseWriter, r *http.Request, name string, value interface{}, opts *CookieOptions) error {
+	c, err := s.NewCookie(r, name, value, opts)
+	if err != nil {
+		return err
+	}
+	if opts == nil || !opts.Partitioned {
+		return WriteCookie(w, r, c)
+	}
+	if err := CheckDowngrade(r, c); err != nil {
+		return err
+	}
+	w.Header().Add("Set-Cookie", c.String()+"; Partitioned")
+	return nil
+}
+
+// ExpiredCookie returns a cookie deleting the named cookie written by
+// NewCookie with opts: it has the same name, including the prefix added by
+// AutoPrefix, path, domain and Secure attribute, which browsers require to
+// replace it, an empty value and a negative MaxAge.
+func ExpiredCookie(name string, opts *CookieOptions) (*http.Cookie, error) {
+	expired := *cookieOptions(opts, 0)
+	expired.MaxAge, expired.Partitioned = -1, false
+	return newCookie(name, "", &expired, time.Now())
+}
+
+// forWriting returns the codec encoding values sent in response to r, which
+// may be nil.
+func (s *SecureCookie) forWriting(r *http.Request) (*SecureCookie, error) {
+	if r == nil {
+		return s, nil
+	}
+	return s.forRequest(r)
+}
+
+// cookie returns the named cookie holding value, with the attributes of
+// opts, see NewCookie.
+func (s *SecureCookie) cookie(name, value string, opts *CookieOptions) (*http.Cookie, error) {
+	opts = cookieOptions(opts, int(s.maxAge))
+	if s.requireTLS && !opts.Secure {
+		return nil, fmt.Errorf("%w: %s", errInsecureCookie, name)
+	}
+	return newCookie(name, value, opts, s.now())
+}
+
+// newCookie returns the named cookie holding value, with the attributes of
+// opts. It fails if the attributes break the semantics of the prefix of the
+// name.
+func newCookie(name, value string, opts *CookieOptions, now time.Time) (*http.Cookie, error) {
+	c := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		MaxAge:   opts.MaxAge,
+		Secure:   opts.Secure,
+		HttpOnly: opts.HttpOnly,
+		SameSite: opts.SameSite,
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	if c.MaxAge > 0 {
+		c.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+	} else if c.MaxAge < 0 {
+		c.Expires = time.Unix(1, 0)
+	}
+	if opts.AutoPrefix && cookiePrefix(c.Name) == "" {
+		if c.Secure && c.Path == "/" && c.Domain == "" {
+			c.Name = hostPrefix + c.Name
+		} else if c.Secure {
+			c.Name = securePrefix + c.Name
+		}
+	}
+	if err := checkPrefix(c); err != nil {
+		return nil, err
+	}
+	if c.SameSite == http.SameSiteNoneMode && !c.Secure {
+		return nil, fmt.Errorf("%w: %s", errSameSiteNoneInsecure, c.Name)
+	}
+	if opts.Partitioned && !c.Secure {
+		return nil, fmt.Errorf("%w: %s", errPartitioned, c.Name)
+	}
+	return c, nil
+}
+
+// checkPrefix checks that the attributes of c satisfy the prefix of its name.
+func checkPrefix(c *http.Cookie) error {
+	switch cookiePrefix(c.Name) {
+	case hostPrefix:
+		if !c.Secure || c.Path != "/" || c.Domain != "" {
+			return fmt.Errorf("%w: %s", errHostPrefix, c.Name)
+		}
+	case securePrefix:
+		if !c.Secure {
+			return fmt.Errorf("%w: %s", errSecurePrefix, c.Name)
+		}
+	}
+	return nil
+}
+
+// GetCookie decodes the named cookie of r into dst. If name has no prefix,
+// the __Host- and __Secure- variants set with AutoPrefix are tried first. It
+// returns http.ErrNoCookie if r has no such cookie.
+func (s *SecureCookie) GetCookie(r *http.Request, name string, dst interface{}) error {
+	c, err := prefixedCookie(r, name)
+	if err != nil {
+		return err
+	}
+	codec, err := s.forRequest(r)
+	if err != nil {
+		return err
+	}
+	return codec.Decode(name, c.Value, dst)
+}
+
+// prefixedCookie returns the named cookie of r, preferring its prefixed
+// variants if name has no prefix.
+func prefixedCookie(r *http.Request, name string) (*http.Cookie, error) {
+	if cookiePrefix(name) == "" {
+		for _, prefix := range []string{hostPrefix, securePrefix} {
+			if c, err := r.Cookie(prefix + name); err == nil {
+				return c, nil
+			}
+		}
+	}
+	return r.Cookie(name)
+}
+
+// MissingCookieError is returned by DecodeCookie when the request has no
+// cookie with the name, e.g. on a first visit. It wraps http.ErrNoCookie.
+type MissingCookieError struct {
+	Name string
+}
+
+func (e *MissingCookieError) Error() string {
+	return "securecookie: cookie " + e.Name + " is missing"
+}
+
+func (e *MissingCookieError) Unwrap() error {
+	return http.ErrNoCookie
+}
+
+// InvalidCookieError is returned by DecodeCookie when the cookie is present
+// but fails to decode, e.g. because it was tampered with or expired. Err is
+// the decoding error.
+type InvalidCookieError struct {
+	Name string
+	Err  error
+}
+
+func (e *InvalidCookieError) Error() string {
+	return "securecookie: cookie " + e.Name + " is invalid: " + e.Err.Error()
+}
+
+func (e *InvalidCookieError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeCookie decodes the named cookie of r into dst, like GetCookie, but
+// tells an absent cookie, as a *MissingCookieError, from an invalid one, as
+// an *InvalidCookieError, so that first visits are not mistaken for attacks.
+func (s *SecureCookie) DecodeCookie(r *http.Request, name string, dst interface{}) error {
+	c, err := prefixedCookie(r, name)
+	if err != nil {
+		return &MissingCookieError{Name: name}
+	}
+	codec, err := s.forRequest(r)
+	if err != nil {
+		return err
+	}
+	if err := codec.Decode(name, c.Value, dst); err != nil {
+		return &InvalidCookieError{Name: name, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package securecookie
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetCookie(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := New([]byte("12345"), []byte("1234567890123456")).MaxAge(3600).SetClock(ClockFunc(func() time.Time { return now }))
+	w := httptest.NewRecorder()
+	if err := s.SetCookie(w, nil, "sid", "alice", nil); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected one cookie, got %v", cookies)
+	}
+	c := cookies[0]
+	if !c.Secure || !c.HttpOnly || c.SameSite != http.SameSiteLaxMode || c.Path != "/" || c.MaxAge != 3600 || !c.Expires.Equal(now.Add(time.Hour)) {
+		t.Fatalf("Unexpected default attributes: %+v", c)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(c)
+	var dst string
+	if err := s.GetCookie(r, "sid", &dst); err != nil || dst != "alice" {
+		t.Fatalf("Expected %q, got %q, %v", "alice", dst, err)
+	}
+	if err := s.GetCookie(r, "other", &dst); !errors.Is(err, http.ErrNoCookie) {
+		t.Fatalf("Expected http.ErrNoCookie, got %v", err)
+	}
+
+}
+
+func TestCookiePrefix(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	for _, tc := range []struct {
+		name string
+		opts CookieOptions
+		err  error
+	}{
+		{"__Host-sid", CookieOptions{}, errHostPrefix},
+		{"__Host-sid", CookieOptions{Secure: true, Path: "/app"}, errHostPrefix},
+		{"__Host-sid", CookieOptions{Secure: true, Domain: "example.com"}, errHostPrefix},
+		{"__Secure-sid", CookieOptions{Path: "/app"}, errSecurePrefix},
+		{"__Secure-sid", CookieOptions{Secure: true, Path: "/app"}, nil},
+	} {
+		if _, err := s.NewCookie(nil, tc.name, "alice", &tc.opts); !errors.Is(err, tc.err) {
+			t.Errorf("%s %+v: expected %v, got %v", tc.name, tc.opts, tc.err, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		opts CookieOptions
+		name string
+	}{
+		{CookieOptions{Secure: true}, "__Host-sid"},
+		{CookieOptions{Secure: true, Path: "/app"}, "__Secure-sid"},
+		{CookieOptions{}, "sid"},
+	} {
+		tc.opts.AutoPrefix = true
+		c, err := s.NewCookie(nil, "sid", "alice", &tc.opts)
+		if err != nil || c.Name != tc.name {
+			t.Fatalf("%+v: expected %s, got %v, %v", tc.opts, tc.name, c, err)
+		}
+		r := httptest.NewRequest("GET", "/", nil)
+		if c.Name != "sid" {
+			r.AddCookie(&http.Cookie{Name: "sid", Value: "stale"})
+		}
+		r.AddCookie(c)
+		var dst string
+		if err := s.GetCookie(r, "sid", &dst); err != nil || dst != "alice" {
+			t.Fatalf("%+v: expected %q, got %q, %v", tc.opts, "alice", dst, err)
+		}
+		expired, err := ExpiredCookie("sid", &tc.opts)
+		if err != nil || expired.Name != c.Name || expired.Path != c.Path || expired.Secure != c.Secure || expired.MaxAge >= 0 || expired.Value != "" {
+			t.Fatalf("%+v: expected %s to be expired, got %v, %v", tc.opts, c.Name, expired, err)
+		}
+	}
+}
+
+func TestNewChunkedCookies(t *testing.T) {
+	s := New([]byte("12345"), nil).MaxLength(0)
+	value := strings.Repeat("x", 5000)
+	cookies, err := s.NewChunkedCookies(nil, "sid", value, &CookieOptions{Path: "/app", HttpOnly: true}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 2 || cookies[1].Name != "sid.1" || cookies[1].Path != "/app" || !cookies[1].HttpOnly {
+		t.Fatalf("Unexpected cookies: %v", cookies)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	var dst string
+	if err := ReadChunked(r, s, "sid", &dst); err != nil || dst != value {
+		t.Fatalf("Expected the value back, got %d bytes, %v", len(dst), err)
+	}
+}
+
+func TestCookieSameSite(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	if _, err := s.NewCookie(nil, "sid", "alice", &CookieOptions{SameSite: http.SameSiteNoneMode}); !errors.Is(err, errSameSiteNoneInsecure) {
+		t.Fatalf("Expected errSameSiteNoneInsecure, got %v", err)
+	}
+	if _, err := s.NewCookie(nil, "sid", "alice", &CookieOptions{Partitioned: true}); !errors.Is(err, errPartitioned) {
+		t.Fatalf("Expected errPartitioned, got %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	opts := &CookieOptions{Secure: true, SameSite: http.SameSiteNoneMode, Partitioned: true, AutoPrefix: true}
+	if err := s.SetCookie(w, nil, "embed", "alice", opts); err != nil {
+		t.Fatal(err)
+	}
+	header := w.Header().Get("Set-Cookie")
+	if !strings.HasPrefix(header, "__Host-embed=") || !strings.Contains(header, "; SameSite=None") || !strings.HasSuffix(header, "; Partitioned") {
+		t.Fatalf("Unexpected header: %s", header)
+	}
+}
+
+func TestDecodeCookie(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	encoded, err := s.Encode("sid", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	var dst string
+	var missing *MissingCookieError
+	if err := s.DecodeCookie(r, "sid", &dst); !errors.As(err, &missing) || !errors.Is(err, http.ErrNoCookie) {
+		t.Fatalf("Expected a *MissingCookieError, got %v", err)
+	}
+	r.AddCookie(&http.Cookie{Name: "sid", Value: encoded[1:]})
+	var invalid *InvalidCookieError
+	if err := s.DecodeCookie(r, "sid", &dst); !errors.As(err, &invalid) || errors.Is(err, http.ErrNoCookie) {
+		t.Fatalf("Expected an *InvalidCookieError, got %v", err)
+	}
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "sid", Value: encoded})
+	if err := s.DecodeCookie(r, "sid", &dst); err != nil || dst != "alice" {
+		t.Fatalf("Expected %q, got %q, %v", "alice", dst, err)
+	}
+}
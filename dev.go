@@ -0,0 +1,49 @@
+package securecookie
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// NewDev returns a SecureCookie for local development, with keys read from
+// the file at path. On first run the keys are generated with
+// GenerateRandomKey and persisted there, so cookies survive restarts without
+// hardcoding keys in source code.
+//
+// NewDev logs a warning every time it is called: keys kept in a local file
+// are not suitable for production.
+func NewDev(path string) (*SecureCookie, error) {
+	log.Printf("securecookie: WARNING: using development keys from %s; do not use NewDev in production", path)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		pair := keyPair{HashKey: GenerateRandomKey(64), BlockKey: GenerateRandomKey(32)}
+		if pair.HashKey == nil || pair.BlockKey == nil {
+			return nil, errGeneratingIV
+		}
+		if data, err = json.MarshalIndent(pair, "", "  "); err != nil {
+			return nil, err
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		if err = os.WriteFile(path, data, 0o600); err != nil {
+			return nil, err
+		}
+		log.Printf("securecookie: generated new development keys in %s", path)
+	} else if err != nil {
+		return nil, err
+	}
+	pair, err := parseKeyPair(data)
+	if err != nil {
+		return nil, err
+	}
+	s := New(pair.HashKey, pair.BlockKey)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s, nil
+}
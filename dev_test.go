@@ -0,0 +1,31 @@
+package securecookie
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewDev(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	path := filepath.Join(t.TempDir(), "dev", "keys.json")
+	s1, err := NewDev(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := s1.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := NewDev(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err = s2.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected persisted keys to be reused, got %q, %v", dst, err)
+	}
+}
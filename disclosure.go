@@ -0,0 +1,159 @@
+package securecookie
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+const claimSaltSize = 16
+
+var (
+	errClaimUnknown = Error{msg: "claim is not in the token"}
+	errClaimDecrypt = Error{msg: "claim could not be decrypted"}
+)
+
+// Disclosure holds the decryption keys of a subset of the claims of a
+// selective disclosure token, by claim name. It can be marshaled to JSON to
+// be handed to the party allowed to read those claims.
+type Disclosure map[string][]byte
+
+// sealedClaims is the payload of a selective disclosure token. Each claim is
+// encrypted separately under a key derived from the master key, the token
+// salt and the claim name.
+type sealedClaims struct {
+	Salt   []byte            `json:"s"`
+	Claims map[string][]byte `json:"c"`
+}
+
+// SelectiveCodec issues tokens whose claims are encrypted individually, so the
+// holder can be given decryption material for only some of them, in the
+// spirit of SD-JWT selective disclosure. Parties verifying a token with
+// OpenDisclosed check its integrity with the shared hash key, but can only
+// read the claims they were given keys for.
+type SelectiveCodec struct {
+	codec  *SecureCookie
+	master []byte
+}
+
+// NewSelectiveCodec returns a SelectiveCodec authenticating tokens with
+// hashKey and deriving per-claim encryption keys from masterKey. Only the
+// issuer holds masterKey; verifiers need hashKey.
+func NewSelectiveCodec(hashKey, masterKey []byte) *SelectiveCodec {
+	if len(masterKey) == 0 {
+		panic(errBlockKeyNotSet)
+	}
+	return &SelectiveCodec{codec: New(hashKey, nil), master: masterKey}
+}
+
+// Codec returns the underlying SecureCookie, to change its options, e.g.
+// MaxAge. It must not be given a block key.
+func (c *SelectiveCodec) Codec() *SecureCookie {
+	return c.codec
+}
+
+// Encode encodes claims into a token, encrypting each one under its own key.
+func (c *SelectiveCodec) Encode(name string, claims map[string]interface{}) (string, error) {
+	salt := GenerateRandomKey(claimSaltSize)
+	if salt == nil {
+		return "", errGeneratingIV
+	}
+	sealed := sealedClaims{Salt: salt, Claims: make(map[string][]byte, len(claims))}
+	for claim, v := range claims {
+		plaintext, err := json.Marshal(v)
+		if err != nil {
+			return "", Error{msg: err.Error()}
+		}
+		aead, err := claimAEAD(c.claimKey(salt, claim))
+		if err != nil {
+			return "", err
+		}
+		nonce := GenerateRandomKey(aead.NonceSize())
+		if nonce == nil {
+			return "", errGeneratingIV
+		}
+		sealed.Claims[claim] = aead.Seal(nonce, nonce, plaintext, []byte(claim))
+	}
+	return c.codec.Encode(name, sealed)
+}
+
+// Decode decodes all claims of a token.
+func (c *SelectiveCodec) Decode(name, value string) (map[string]json.RawMessage, error) {
+	var sealed sealedClaims
+	if err := c.codec.Decode(name, value, &sealed); err != nil {
+		return nil, err
+	}
+	d := make(Disclosure, len(sealed.Claims))
+	for claim := range sealed.Claims {
+		d[claim] = c.claimKey(sealed.Salt, claim)
+	}
+	return openClaims(&sealed, d)
+}
+
+// Disclose returns the keys of the given claims of a token, after verifying
+// it.
+func (c *SelectiveCodec) Disclose(name, value string, claims ...string) (Disclosure, error) {
+	var sealed sealedClaims
+	if err := c.codec.Decode(name, value, &sealed); err != nil {
+		return nil, err
+	}
+	d := make(Disclosure, len(claims))
+	for _, claim := range claims {
+		if _, ok := sealed.Claims[claim]; !ok {
+			return nil, fmt.Errorf("%w: %s", errClaimUnknown, claim)
+		}
+		d[claim] = c.claimKey(sealed.Salt, claim)
+	}
+	return d, nil
+}
+
+// OpenDisclosed verifies a token issued by a SelectiveCodec using verifier,
+// a SecureCookie created with the same hash key and no block key, and
+// decrypts the claims disclosed by d. Other claims are omitted.
+func OpenDisclosed(verifier *SecureCookie, name, value string, d Disclosure) (map[string]json.RawMessage, error) {
+	var sealed sealedClaims
+	if err := verifier.Decode(name, value, &sealed); err != nil {
+		return nil, err
+	}
+	return openClaims(&sealed, d)
+}
+
+// openClaims decrypts the claims of sealed for which d has a key.
+func openClaims(sealed *sealedClaims, d Disclosure) (map[string]json.RawMessage, error) {
+	claims := make(map[string]json.RawMessage, len(d))
+	for claim, key := range d {
+		ciphertext, ok := sealed.Claims[claim]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errClaimUnknown, claim)
+		}
+		aead, err := claimAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(ciphertext) < aead.NonceSize() {
+			return nil, fmt.Errorf("%w: %s", errClaimDecrypt, claim)
+		}
+		nonce := ciphertext[:aead.NonceSize()]
+		plaintext, err := aead.Open(nil, nonce, ciphertext[aead.NonceSize():], []byte(claim))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errClaimDecrypt, claim)
+		}
+		claims[claim] = plaintext
+	}
+	return claims, nil
+}
+
+// claimKey derives the key of a claim of the token with the given salt.
+func (c *SelectiveCodec) claimKey(salt []byte, claim string) []byte {
+	return hkdf(sha256.New, c.master, salt, []byte("securecookie claim "+claim), 32)
+}
+
+func claimAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
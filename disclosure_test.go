@@ -0,0 +1,48 @@
+package securecookie
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSelectiveDisclosure(t *testing.T) {
+	hashKey, master := GenerateRandomKey(32), GenerateRandomKey(32)
+	issuer := NewSelectiveCodec(hashKey, master)
+	token, err := issuer.Encode("id", map[string]interface{}{
+		"email": "alice@example.com",
+		"age":   42,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := issuer.Decode("id", token)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("Expected all claims, got %v, %v", all, err)
+	}
+
+	d, err := issuer.Disclose("id", token, "age")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := OpenDisclosed(New(hashKey, nil), "id", token, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(claims["age"]) != "42" || claims["email"] != nil {
+		t.Fatalf("Expected only the age claim, got %v", claims)
+	}
+
+	// A key for one claim does not open another.
+	forged := Disclosure{"email": d["age"]}
+	if _, err = OpenDisclosed(New(hashKey, nil), "id", token, forged); !errors.Is(err, errClaimDecrypt) {
+		t.Fatalf("Expected errClaimDecrypt, got %v", err)
+	}
+	if _, err = issuer.Disclose("id", token, "name"); !errors.Is(err, errClaimUnknown) {
+		t.Fatalf("Expected errClaimUnknown, got %v", err)
+	}
+	var v int
+	if err = json.Unmarshal(all["age"], &v); err != nil || v != 42 {
+		t.Fatalf("Expected 42, got %d, %v", v, err)
+	}
+}
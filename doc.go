@@ -54,8 +54,22 @@ value:
 	}
 
 We stored a map[string]string, but secure cookies can hold any value that
-can be encoded using encoding/gob. To store custom types, they must be
-registered first using gob.Register(). For basic types this is not needed;
-it works out of the box.
+can be encoded using encoding/json, the default serializer. Set
+DefaultSerializer at init time to change it for every codec, e.g. to enforce
+a single encoding across services. The package only uses encoding/gob, in
+GobEncoder, when built with the gob tag.
+
+Encoded values are framed in binary and encoded to text in a single base64
+pass, so the only expansion is the 4/3 of base64 itself:
+
+	mac | header | name | timestamp | data
+
+The header is the length of the name as a little-endian uint16, whose high
+bits hold flags such as compression. The timestamp is a little-endian uint64,
+in milliseconds if its high bit is set, left out with OmitTimestamp. It is
+followed by optional fields such as a not-before time, and data is the
+serialized, optionally compressed and encrypted value. The MAC covers
+everything after it. From format version 2, see FormatVersion, values start
+with a version byte, also covered by the MAC.
 */
 package securecookie
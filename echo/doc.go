@@ -0,0 +1,6 @@
+// Package echo adapts the HTTP helpers of securecookie to Echo.
+//
+// This package requires github.com/labstack/echo/v4, which is not a
+// dependency of the default build. Add it with
+// "go get github.com/labstack/echo/v4" and build with "-tags echo".
+package echo
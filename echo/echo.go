@@ -0,0 +1,101 @@
+//go:build echo
+
+package echo
+
+import (
+	"errors"
+	"net/http"
+
+	labstack "github.com/labstack/echo/v4"
+
+	"github.com/monime-lab/gorilla-securecookie"
+)
+
+// Middleware decodes the cookies of specs once per request, like
+// securecookie.CookieMiddleware, including ClearInvalid and InvalidRedirect.
+// Handlers read the results with Get.
+func Middleware(specs ...securecookie.CookieSpec) labstack.MiddlewareFunc {
+	mw := securecookie.CookieMiddleware(specs...)
+	return func(next labstack.HandlerFunc) labstack.HandlerFunc {
+		return func(c labstack.Context) error {
+			var err error
+			mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c.SetRequest(r)
+				err = next(c)
+			})).ServeHTTP(c.Response(), c.Request())
+			return err
+		}
+	}
+}
+
+// Get returns the value decoded for the named cookie by Middleware, mapping
+// errors with HTTPError.
+func Get(c labstack.Context, name string) (interface{}, error) {
+	v, err := securecookie.FromContext(c.Request().Context(), name)
+	if err != nil {
+		return nil, HTTPError(err)
+	}
+	return v, nil
+}
+
+// Decode decodes the named cookie into dst, see SecureCookie.DecodeCookie,
+// mapping errors with HTTPError.
+func Decode(c labstack.Context, codec *securecookie.SecureCookie, name string, dst interface{}) error {
+	return HTTPError(codec.DecodeCookie(c.Request(), name, dst))
+}
+
+// Set encodes value and sets it as the named cookie, with the attributes of
+// opts, see SecureCookie.NewCookie.
+func Set(c labstack.Context, codec *securecookie.SecureCookie, name string, value interface{}, opts *securecookie.CookieOptions) error {
+	cookie, err := codec.NewCookie(c.Request(), name, value, opts)
+	if err != nil {
+		return err
+	}
+	c.SetCookie(cookie)
+	return nil
+}
+
+// Binder is an echo.Binder decoding the named cookie with Codec, e.g. to
+// call c.Bind on a session.
+type Binder struct {
+	Codec *securecookie.SecureCookie
+	Name  string
+}
+
+// Bind implements echo.Binder.
+func (b *Binder) Bind(i interface{}, c labstack.Context) error {
+	return Decode(c, b.Codec, b.Name, i)
+}
+
+// HTTPError maps the errors of securecookie to an *echo.HTTPError, keeping
+// err as its internal error:
+//
+//   - missing, invalid, not yet valid or expired cookies are 401
+//   - cookies over the maximum length are 431
+//   - failures of stores, see securecookie.Temporary, are 503
+//   - anything else is 500
+//
+// It returns nil for a nil err.
+func HTTPError(err error) error {
+	if err == nil {
+		return nil
+	}
+	code := http.StatusInternalServerError
+	var (
+		missing   *securecookie.MissingCookieError
+		invalid   *securecookie.InvalidCookieError
+		length    *securecookie.LengthError
+		notBefore *securecookie.NotBeforeError
+		expired   *securecookie.ExpiredError
+	)
+	switch {
+	case securecookie.Temporary(err):
+		code = http.StatusServiceUnavailable
+	case errors.As(err, &length):
+		code = http.StatusRequestHeaderFieldsTooLarge
+	case errors.As(err, &missing), errors.Is(err, http.ErrNoCookie), errors.As(err, &invalid),
+		errors.As(err, &notBefore), errors.As(err, &expired):
+		code = http.StatusUnauthorized
+	}
+	return labstack.NewHTTPError(code, http.StatusText(code)).SetInternal(err)
+}
@@ -0,0 +1,107 @@
+//go:build echo
+
+package echo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	labstack "github.com/labstack/echo/v4"
+
+	"github.com/monime-lab/gorilla-securecookie"
+)
+
+type failingChecker struct{}
+
+func (failingChecker) Revoked(context.Context, string, time.Time) (bool, error) {
+	return false, errors.New("store down")
+}
+
+func request(value string) *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	if value != "" {
+		r.AddCookie(&http.Cookie{Name: "session", Value: value})
+	}
+	return r
+}
+
+func TestHTTPError(t *testing.T) {
+	now := time.Now()
+	s := securecookie.New([]byte("12345"), nil).SetClock(securecookie.ClockFunc(func() time.Time { return now }))
+	encoded, err := s.Encode("session", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decode := func(s *securecookie.SecureCookie, value string) error {
+		var dst string
+		return s.DecodeCookie(request(value), "session", &dst)
+	}
+	expired := func() error {
+		var dst string
+		s := securecookie.New([]byte("12345"), nil).MaxAge(60).
+			SetClock(securecookie.ClockFunc(func() time.Time { return now.Add(2 * time.Minute) }))
+		return s.DecodeExpired("session", encoded, &dst, time.Hour)
+	}
+
+	for _, test := range []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"missing", decode(s, ""), http.StatusUnauthorized},
+		{"no cookie", http.ErrNoCookie, http.StatusUnauthorized},
+		{"invalid", decode(s, encoded+"x"), http.StatusUnauthorized},
+		{"expired", expired(), http.StatusUnauthorized},
+		{"too long", decode(s, strings.Repeat("a", 5000)), http.StatusRequestHeaderFieldsTooLarge},
+		{"temporary", decode(securecookie.New([]byte("12345"), nil).CheckRevocation(failingChecker{}, 0), encoded), http.StatusServiceUnavailable},
+		{"other", errors.New("other"), http.StatusInternalServerError},
+	} {
+		var he *labstack.HTTPError
+		if !errors.As(HTTPError(test.err), &he) {
+			t.Errorf("%s: expected an *echo.HTTPError for %v", test.name, test.err)
+			continue
+		}
+		if he.Code != test.code {
+			t.Errorf("%s: expected %d, got %d for %v", test.name, test.code, he.Code, test.err)
+		}
+		if he.Internal != test.err {
+			t.Errorf("%s: expected the internal error to be kept", test.name)
+		}
+	}
+	if err := HTTPError(nil); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	s := securecookie.New([]byte("12345"), nil)
+	encoded, err := s.Encode("session", map[string]interface{}{"user": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := securecookie.CookieSpec{Name: "session", Codec: s}
+	handler := Middleware(spec)(func(c labstack.Context) error {
+		v, err := Get(c, "session")
+		if err != nil {
+			return err
+		}
+		if user := (*v.(*map[string]interface{}))["user"]; user != "alice" {
+			t.Errorf("Expected alice, got %v", user)
+		}
+		return nil
+	})
+
+	e := labstack.New()
+	if err := handler(e.NewContext(request(encoded), httptest.NewRecorder())); err != nil {
+		t.Fatal(err)
+	}
+	var he *labstack.HTTPError
+	if err := handler(e.NewContext(request(""), httptest.NewRecorder())); !errors.As(err, &he) || he.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for a missing cookie, got %v", err)
+	}
+}
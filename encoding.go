@@ -0,0 +1,79 @@
+package securecookie
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// TextEncoding encodes values to text. It is implemented by
+// *base64.Encoding and *base32.Encoding.
+type TextEncoding interface {
+	EncodedLen(n int) int
+	Encode(dst, src []byte)
+	DecodedLen(n int) int
+	Decode(dst, src []byte) (int, error)
+}
+
+var (
+	// HexEncoding encodes values in lowercase hexadecimal and decodes
+	// either case, for case-insensitive transports. Values are twice as
+	// long as the data, against 4/3 for base64.
+	HexEncoding TextEncoding = hexEncoding{}
+	// Base32Encoding encodes values in unpadded uppercase base32 and
+	// decodes either case, e.g. for DNS labels or gateways that mangle
+	// base64 symbols. Values are 8/5 as long as the data.
+	Base32Encoding TextEncoding = base32Encoding{base32.StdEncoding.WithPadding(base32.NoPadding)}
+)
+
+var errEncodingNotCanonical = Error{msg: "value is not canonically encoded"}
+
+// SetEncoding sets the text encoding of encoded values, e.g.
+// base64.RawURLEncoding to drop the '=' padding, which wastes bytes and is
+// percent-encoded by some proxies, or HexEncoding. Values encoded with another
+// encoding no longer decode, so changing it invalidates existing cookies.
+//
+// Default is base64.URLEncoding.
+func (s *SecureCookie) SetEncoding(enc TextEncoding) *SecureCookie {
+	s.encoding = enc
+	return s
+}
+
+// StrictEncoding makes Decode reject values that are not exactly as Encode
+// would have encoded them, such as base64 with missing or extra padding,
+// non-zero trailing bits, or hex and base32 in the other case. Otherwise
+// several strings decode to the same value, which defeats replay detection
+// or caching keyed on the raw cookie value.
+//
+// Default is false.
+func (s *SecureCookie) StrictEncoding(strict bool) *SecureCookie {
+	s.strictEncoding = strict
+	return s
+}
+
+// textEncoding returns the encoding of encoded values.
+func (s *SecureCookie) textEncoding() TextEncoding {
+	if s.encoding == nil {
+		return base64.URLEncoding
+	}
+	return s.encoding
+}
+
+type hexEncoding struct{}
+
+func (hexEncoding) EncodedLen(n int) int { return hex.EncodedLen(n) }
+
+func (hexEncoding) Encode(dst, src []byte) { hex.Encode(dst, src) }
+
+func (hexEncoding) DecodedLen(n int) int { return hex.DecodedLen(n) }
+
+func (hexEncoding) Decode(dst, src []byte) (int, error) { return hex.Decode(dst, src) }
+
+type base32Encoding struct {
+	*base32.Encoding
+}
+
+func (e base32Encoding) Decode(dst, src []byte) (int, error) {
+	return e.Encoding.Decode(dst, bytes.ToUpper(src))
+}
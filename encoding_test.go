@@ -0,0 +1,97 @@
+package securecookie
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestSetEncoding(t *testing.T) {
+	for _, enc := range []*base64.Encoding{
+		base64.RawURLEncoding,
+		base64.StdEncoding,
+		base64.RawStdEncoding,
+	} {
+		s := New([]byte("12345"), []byte("1234567890123456")).SetEncoding(enc)
+		encoded, err := s.Encode("sid", "value")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := enc.DecodeString(encoded); err != nil {
+			t.Fatalf("Expected a value in the configured encoding, got %q", encoded)
+		}
+		var dst string
+		if err := s.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+			t.Fatalf("Expected %q, got %q, %v", "value", dst, err)
+		}
+		if n, _ := s.EstimatedLength("sid", "value"); n != len(encoded) {
+			t.Fatalf("Expected estimated length %d, got %d", len(encoded), n)
+		}
+	}
+
+	s := New([]byte("12345"), nil).SetEncoding(base64.RawURLEncoding)
+	for i := 0; i < 3; i++ {
+		encoded, _ := s.Encode("sid", strings.Repeat("x", i))
+		if strings.Contains(encoded, "=") {
+			t.Fatalf("Expected no padding, got %q", encoded)
+		}
+	}
+}
+
+func TestHexAndBase32Encoding(t *testing.T) {
+	for _, tt := range []struct {
+		enc      TextEncoding
+		alphabet string
+	}{
+		{HexEncoding, "0123456789abcdef"},
+		{Base32Encoding, "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"},
+	} {
+		s := New([]byte("12345"), []byte("1234567890123456")).SetEncoding(tt.enc)
+		encoded, err := s.Encode("sid", "value")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Trim(encoded, tt.alphabet) != "" {
+			t.Fatalf("Expected only %q, got %q", tt.alphabet, encoded)
+		}
+		for _, v := range []string{encoded, strings.ToLower(encoded), strings.ToUpper(encoded)} {
+			var dst string
+			if err := s.Decode("sid", v, &dst); err != nil || dst != "value" {
+				t.Fatalf("Expected %q decoding %q, got %q, %v", "value", v, dst, err)
+			}
+		}
+	}
+}
+
+func TestStrictEncoding(t *testing.T) {
+	s := New([]byte("12345"), nil).SetEncoding(base64.StdEncoding)
+	encoded, err := s.Encode("sid", "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := base64.StdEncoding.DecodeString(encoded)
+	if len(b)%3 == 0 {
+		t.Fatal("Expected a value with padding")
+	}
+	// Set the trailing bits, which are ignored when decoding.
+	last := strings.TrimRight(encoded, "=")
+	i := strings.IndexByte(stdAlphabet, last[len(last)-1])
+	tampered := last[:len(last)-1] + string(stdAlphabet[i|1]) + encoded[len(last):]
+	if tampered == encoded {
+		tampered = last[:len(last)-1] + string(stdAlphabet[i&^1]) + encoded[len(last):]
+	}
+
+	var dst string
+	if err := s.Decode("sid", tampered, &dst); err != nil {
+		t.Fatalf("Expected a lenient decoding, got %v", err)
+	}
+	s.StrictEncoding(true)
+	if err := s.Decode("sid", encoded, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Decode("sid", tampered, &dst); err != errEncodingNotCanonical {
+		t.Fatalf("Expected errEncodingNotCanonical, got %v", err)
+	}
+}
+
+const stdAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
@@ -0,0 +1,110 @@
+package securecookie
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// serializers are the serializers selectable by name, e.g. in NewFromEnv.
+var serializers = map[string]Serializer{
+	"json": JSONEncoder{},
+	"nop":  NopEncoder{},
+}
+
+// NewFromEnv returns a codec chain configured from environment variables, for
+// use with EncodeMulti and DecodeMulti. Variable names are the upper-cased
+// prefix followed by an underscore and:
+//
+//	HASH_KEY      base64-encoded hash key of the newest generation (required)
+//	BLOCK_KEY     base64-encoded block key of the newest generation
+//	HASH_KEY_n    hash key of the n-th previous generation, n = 1, 2, ...
+//	BLOCK_KEY_n   block key of the n-th previous generation
+//	MAX_AGE       see SecureCookie.MaxAge, in seconds
+//	MAX_LENGTH    see SecureCookie.MaxLength, in bytes
+//	SERIALIZER    "json", "nop", or "msgpack" and "proto" when built with
+//	              the msgpack and protobuf tags
+//
+// Generations are read until the first missing HASH_KEY_n. For example, with
+// prefix "session" the newest hash key is read from SESSION_HASH_KEY.
+func NewFromEnv(prefix string) ([]Codec, error) {
+	if prefix != "" {
+		prefix = strings.ToUpper(prefix) + "_"
+	}
+	var codecs []Codec
+	for gen := 0; ; gen++ {
+		suffix := ""
+		if gen > 0 {
+			suffix = "_" + strconv.Itoa(gen)
+		}
+		hashVar, blockVar := prefix+"HASH_KEY"+suffix, prefix+"BLOCK_KEY"+suffix
+		hashKey, err := envKey(hashVar)
+		if err != nil {
+			return nil, err
+		}
+		if hashKey == nil {
+			if gen == 0 {
+				return nil, fmt.Errorf("%w: %s", errHashKeyNotSet, hashVar)
+			}
+			break
+		}
+		blockKey, err := envKey(blockVar)
+		if err != nil {
+			return nil, err
+		}
+		codecs = append(codecs, New(hashKey, blockKey))
+	}
+
+	var opts []func(*SecureCookie)
+	if v, ok := os.LookupEnv(prefix + "MAX_AGE"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, envError(prefix+"MAX_AGE", err)
+		}
+		opts = append(opts, func(s *SecureCookie) { s.MaxAge(n) })
+	}
+	if v, ok := os.LookupEnv(prefix + "MAX_LENGTH"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, envError(prefix+"MAX_LENGTH", err)
+		}
+		opts = append(opts, func(s *SecureCookie) { s.MaxLength(n) })
+	}
+	if v, ok := os.LookupEnv(prefix + "SERIALIZER"); ok {
+		sz, found := serializers[strings.ToLower(v)]
+		if !found {
+			return nil, envError(prefix+"SERIALIZER", fmt.Errorf("unknown serializer %q", v))
+		}
+		opts = append(opts, func(s *SecureCookie) { s.SetSerializer(sz) })
+	}
+	for _, c := range codecs {
+		s := c.(*SecureCookie)
+		for _, opt := range opts {
+			opt(s)
+		}
+		if s.err != nil {
+			return nil, s.err
+		}
+	}
+	return codecs, nil
+}
+
+// envKey returns the base64-decoded value of an environment variable, or nil
+// if it is not set or empty.
+func envKey(name string) ([]byte, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, envError(name, err)
+	}
+	return key, nil
+}
+
+func envError(name string, err error) error {
+	return Error{msg: fmt.Sprintf("invalid %s: %v", name, err)}
+}
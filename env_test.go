@@ -0,0 +1,39 @@
+package securecookie
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	if _, err := NewFromEnv("session"); err == nil {
+		t.Fatal("Expected an error without hash key")
+	}
+
+	key := func(n int) string { return base64.StdEncoding.EncodeToString(GenerateRandomKey(n)) }
+	t.Setenv("SESSION_HASH_KEY", key(32))
+	t.Setenv("SESSION_BLOCK_KEY", key(32))
+	t.Setenv("SESSION_HASH_KEY_1", key(32))
+	t.Setenv("SESSION_HASH_KEY_3", key(32))
+	t.Setenv("SESSION_MAX_AGE", "3600")
+	t.Setenv("SESSION_SERIALIZER", "nop")
+	codecs, err := NewFromEnv("session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codecs) != 2 {
+		t.Fatalf("Expected 2 generations, got %d", len(codecs))
+	}
+	s := codecs[0].(*SecureCookie)
+	if s.maxAge != 3600 || s.block == nil {
+		t.Fatalf("Expected configured codec, got maxAge %d, block %v", s.maxAge, s.block)
+	}
+	if _, ok := s.sz.(NopEncoder); !ok {
+		t.Fatalf("Expected NopEncoder, got %T", s.sz)
+	}
+
+	t.Setenv("SESSION_SERIALIZER", "xml")
+	if _, err = NewFromEnv("session"); err == nil {
+		t.Fatal("Expected an error for an unknown serializer")
+	}
+}
@@ -0,0 +1,30 @@
+package securecookie
+
+import (
+	"fmt"
+	"time"
+)
+
+// flagExpiry marks values with an absolute expiry, stored as a little-endian
+// uint64 of Unix seconds after the not-before time, if any.
+const flagExpiry uint16 = 1 << 10
+
+var errValueExpired = Error{msg: "value is expired"}
+
+// ExpiresAt makes the value fail to decode from t, whatever the MaxAge of
+// the decoding codec, so that services with different settings agree on
+// its validity. MaxAge still applies if it expires sooner. t is rounded
+// down to the second.
+func ExpiresAt(t time.Time) EncodeOption {
+	return func(o *encodeOptions) {
+		o.expiry = t.Unix()
+	}
+}
+
+// checkExpiry checks an absolute expiry read from an encoded value.
+func (s *SecureCookie) checkExpiry(exp uint64) error {
+	if s.timestamp()-int64((s.leeway+s.grace)/time.Second) >= int64(exp) {
+		return fmt.Errorf("%w: at %s", errValueExpired, time.Unix(int64(exp), 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package securecookie
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExpiresAt(t *testing.T) {
+	issued := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := issued
+	s := New([]byte("12345"), nil).MaxAge(0).SetClock(ClockFunc(func() time.Time { return now }))
+	encoded, err := s.EncodeWith("sid", "value", NotBefore(issued), ExpiresAt(issued.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	now = issued.Add(time.Hour - time.Second)
+	if err := s.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected %q, got %q, %v", "value", dst, err)
+	}
+	now = issued.Add(time.Hour)
+	if err := s.Decode("sid", encoded, &dst); !errors.Is(err, errValueExpired) {
+		t.Fatalf("Expected errValueExpired, got %v", err)
+	}
+	if err := s.Leeway(time.Minute).Decode("sid", encoded, &dst); err != nil {
+		t.Fatalf("Expected the leeway to apply, got %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+// Package fasthttp adapts securecookie to fasthttp, and so to Fiber, whose
+// handlers reach the *fasthttp.RequestCtx with c.Context().
+//
+// This package requires github.com/valyala/fasthttp, which is not a
+// dependency of the default build. Add it with
+// "go get github.com/valyala/fasthttp" and build with "-tags fasthttp".
+package fasthttp
@@ -0,0 +1,109 @@
+//go:build fasthttp
+
+package fasthttp
+
+import (
+	"net/http"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/monime-lab/gorilla-securecookie"
+)
+
+const (
+	hostPrefix   = "__Host-"
+	securePrefix = "__Secure-"
+)
+
+// Decode decodes the named cookie of ctx into dst, telling an absent cookie,
+// as a *securecookie.MissingCookieError, from an invalid one, as a
+// *securecookie.InvalidCookieError, like SecureCookie.DecodeCookie. The
+// cookie is read in place, see SecureCookie.DecodeBytes.
+//
+// RequireTLS and BindClientCertificate, which rely on *http.Request, are not
+// applied; check ctx.IsTLS() or use SecureCookie.WithClientCertificate.
+func Decode(ctx *fasthttp.RequestCtx, codec *securecookie.SecureCookie, name string, dst interface{}) error {
+	value := cookie(ctx, name)
+	if value == nil {
+		return &securecookie.MissingCookieError{Name: name}
+	}
+	if err := codec.DecodeBytes(name, value, dst); err != nil {
+		return &securecookie.InvalidCookieError{Name: name, Err: err}
+	}
+	return nil
+}
+
+// Set encodes value and sets it as the named cookie on the response of ctx,
+// with the attributes of opts, see SecureCookie.NewCookie. Partitioned is
+// not supported and, as with Decode, RequireTLS and BindClientCertificate
+// are not applied.
+func Set(ctx *fasthttp.RequestCtx, codec *securecookie.SecureCookie, name string, value interface{}, opts *securecookie.CookieOptions) error {
+	c, err := codec.NewCookie(nil, name, value, opts)
+	if err != nil {
+		return err
+	}
+	setCookie(ctx, c)
+	return nil
+}
+
+// Delete expires the named cookie on the client, with the name and
+// attributes Set writes for opts, see securecookie.ExpiredCookie.
+func Delete(ctx *fasthttp.RequestCtx, name string, opts *securecookie.CookieOptions) error {
+	c, err := securecookie.ExpiredCookie(name, opts)
+	if err != nil {
+		return err
+	}
+	setCookie(ctx, c)
+	return nil
+}
+
+// setCookie sets c on the response of ctx.
+func setCookie(ctx *fasthttp.RequestCtx, c *http.Cookie) {
+	fc := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(fc)
+	fc.SetKey(c.Name)
+	fc.SetValue(c.Value)
+	fc.SetPath(c.Path)
+	fc.SetDomain(c.Domain)
+	fc.SetMaxAge(c.MaxAge)
+	if !c.Expires.IsZero() {
+		fc.SetExpire(c.Expires)
+	}
+	fc.SetSecure(c.Secure)
+	fc.SetHTTPOnly(c.HttpOnly)
+	fc.SetSameSite(sameSite(c.SameSite))
+	ctx.Response.Header.SetCookie(fc)
+}
+
+// cookie returns the value of the named cookie of ctx, preferring its
+// __Host- and __Secure- variants if name has no prefix, or nil.
+func cookie(ctx *fasthttp.RequestCtx, name string) []byte {
+	if !hasPrefix(name, hostPrefix) && !hasPrefix(name, securePrefix) {
+		var buf [64]byte
+		for _, prefix := range []string{hostPrefix, securePrefix} {
+			key := append(append(buf[:0], prefix...), name...)
+			if v := ctx.Request.Header.CookieBytes(key); v != nil {
+				return v
+			}
+		}
+	}
+	return ctx.Request.Header.Cookie(name)
+}
+
+func hasPrefix(s, prefix string) bool {
+	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
+}
+
+func sameSite(s http.SameSite) fasthttp.CookieSameSite {
+	switch s {
+	case http.SameSiteLaxMode:
+		return fasthttp.CookieSameSiteLaxMode
+	case http.SameSiteStrictMode:
+		return fasthttp.CookieSameSiteStrictMode
+	case http.SameSiteNoneMode:
+		return fasthttp.CookieSameSiteNoneMode
+	case http.SameSiteDefaultMode:
+		return fasthttp.CookieSameSiteDefaultMode
+	}
+	return fasthttp.CookieSameSiteDisabled
+}
@@ -0,0 +1,95 @@
+//go:build fasthttp
+
+package fasthttp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/monime-lab/gorilla-securecookie"
+)
+
+func TestDecode(t *testing.T) {
+	s := securecookie.New([]byte("12345"), []byte("1234567890123456"))
+	encoded, err := s.Encode("session", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefixed, err := s.Encode("__Host-session", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := &fasthttp.RequestCtx{}
+	var dst string
+	var missing *securecookie.MissingCookieError
+	if err := Decode(ctx, s, "session", &dst); !errors.As(err, &missing) {
+		t.Fatalf("Expected a MissingCookieError, got %v", err)
+	}
+
+	ctx.Request.Header.SetCookie("session", encoded)
+	if err := Decode(ctx, s, "session", &dst); err != nil || dst != "alice" {
+		t.Fatalf("Expected alice, got %q, %v", dst, err)
+	}
+	// The header buffer is reused by fasthttp once the request is done.
+	ctx.Request.Reset()
+	if dst != "alice" {
+		t.Fatalf("Expected the decoded value to outlive the request, got %q", dst)
+	}
+
+	ctx.Request.Header.SetCookie("session", encoded)
+	ctx.Request.Header.SetCookie("__Host-session", prefixed)
+	if err := Decode(ctx, s, "session", &dst); err != nil || dst != "bob" {
+		t.Fatalf("Expected the __Host- cookie to win, got %q, %v", dst, err)
+	}
+
+	ctx.Request.Reset()
+	ctx.Request.Header.SetCookie("session", encoded+"x")
+	var invalid *securecookie.InvalidCookieError
+	if err := Decode(ctx, s, "session", &dst); !errors.As(err, &invalid) {
+		t.Fatalf("Expected an InvalidCookieError, got %v", err)
+	}
+}
+
+func TestSet(t *testing.T) {
+	s := securecookie.New([]byte("12345"), []byte("1234567890123456"))
+	ctx := &fasthttp.RequestCtx{}
+	opts := &securecookie.CookieOptions{Path: "/app", MaxAge: 60, Secure: true, HttpOnly: true}
+	if err := Set(ctx, s, "session", "alice", opts); err != nil {
+		t.Fatal(err)
+	}
+	c := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(c)
+	c.SetKey("session")
+	if !ctx.Response.Header.Cookie(c) {
+		t.Fatal("Expected the cookie to be set")
+	}
+	if string(c.Path()) != "/app" || c.MaxAge() != 60 || !c.Secure() || !c.HTTPOnly() {
+		t.Fatalf("Expected the attributes of opts, got %s", c)
+	}
+	var dst string
+	if err := s.Decode("session", string(c.Value()), &dst); err != nil || dst != "alice" {
+		t.Fatalf("Expected alice, got %q, %v", dst, err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := &fasthttp.RequestCtx{}
+	opts := &securecookie.CookieOptions{Secure: true, HttpOnly: true, AutoPrefix: true}
+	if err := Delete(ctx, "session", opts); err != nil {
+		t.Fatal(err)
+	}
+	c := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(c)
+	c.SetKey("__Host-session")
+	if !ctx.Response.Header.Cookie(c) {
+		t.Fatal("Expected the prefixed cookie to be expired")
+	}
+	// fasthttp writes no negative Max-Age; the cookie expires in the past.
+	if string(c.Path()) != "/" || !c.Secure() || !c.Expire().Before(time.Now()) || len(c.Value()) != 0 {
+		t.Fatalf("Expected the attributes of Set and a past expiry, got %s", c)
+	}
+}
@@ -0,0 +1,104 @@
+package securecookie
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"net"
+	"net/http"
+)
+
+// A Fingerprinter returns a fingerprint of the client that sent r, e.g. from
+// its address or User-Agent. Fingerprints are only mixed into the MAC, never
+// stored in values.
+type Fingerprinter func(r *http.Request) []byte
+
+// WithFingerprint returns a copy of s that binds values to fp: values encoded
+// by the copy only decode with the same fingerprint, so a stolen cookie cannot
+// be replayed from another client. A nil or empty fp leaves values unbound.
+//
+// Fingerprints that change during a session, like addresses of mobile
+// clients, log users out; choose them accordingly.
+func (s *SecureCookie) WithFingerprint(fp []byte) *SecureCookie {
+	return s.bind("fingerprint", fp)
+}
+
+// FingerprintRequest returns a copy of s bound to the fingerprint of r, see
+// WithFingerprint.
+func (s *SecureCookie) FingerprintRequest(r *http.Request, f Fingerprinter) *SecureCookie {
+	return s.WithFingerprint(f(r))
+}
+
+// UserAgentFingerprint is a Fingerprinter returning a hash of the
+// User-Agent header.
+func UserAgentFingerprint(r *http.Request) []byte {
+	sum := sha256.Sum256([]byte(r.UserAgent()))
+	return sum[:]
+}
+
+// IPPrefixFingerprint returns a Fingerprinter hashing the network of the
+// client address, see ProxyTrust.ClientIP, with the given prefix lengths,
+// e.g. 24 and 64, so that clients moving within a network keep their
+// fingerprint.
+func IPPrefixFingerprint(p *ProxyTrust, v4Bits, v6Bits int) Fingerprinter {
+	return func(r *http.Request) []byte {
+		ip := p.ClientIP(r)
+		if ip == nil {
+			return nil
+		}
+		var masked net.IP
+		if ip4 := ip.To4(); ip4 != nil {
+			masked = ip4.Mask(net.CIDRMask(v4Bits, 32))
+		} else {
+			masked = ip.Mask(net.CIDRMask(v6Bits, 128))
+		}
+		sum := sha256.Sum256(masked)
+		return sum[:]
+	}
+}
+
+// Fingerprints returns a Fingerprinter combining fs.
+func Fingerprints(fs ...Fingerprinter) Fingerprinter {
+	return func(r *http.Request) []byte {
+		h := sha256.New()
+		for _, f := range fs {
+			fp := f(r)
+			binary.Write(h, binary.LittleEndian, uint32(len(fp)))
+			h.Write(fp)
+		}
+		return h.Sum(nil)
+	}
+}
+
+// bind returns a copy of s whose MACs also cover data. It returns s if data
+// is empty.
+func (s *SecureCookie) bind(label string, data []byte) *SecureCookie {
+	if len(data) == 0 {
+		return s
+	}
+	c := *s
+	c.bound = appendBinding(append([]byte(nil), s.bound...), label, data)
+	return &c
+}
+
+// appendBinding appends data to bindings, labelled so that bindings of
+// different kinds never collide.
+func appendBinding(bindings []byte, label string, data []byte) []byte {
+	bindings = binary.LittleEndian.AppendUint32(bindings, uint32(len(label)))
+	bindings = append(bindings, label...)
+	bindings = binary.LittleEndian.AppendUint32(bindings, uint32(len(data)))
+	return append(bindings, data...)
+}
+
+// authenticated returns the data covered by the MAC of payload: payload
+// itself, followed by the audience and bindings of s if any.
+func (s *SecureCookie) authenticated(payload []byte) []byte {
+	if len(s.bound) == 0 && s.audience == "" {
+		return payload
+	}
+	b := append(make([]byte, 0, len(payload)+len(s.audience)+len(s.bound)+16), payload...)
+	if s.audience != "" {
+		b = appendBinding(b, "audience", []byte(s.audience))
+	}
+	b = append(b, s.bound...)
+	return binary.LittleEndian.AppendUint32(b, uint32(len(b)-len(payload)))
+}
@@ -0,0 +1,76 @@
+package securecookie
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	s := New([]byte("12345"), []byte("1234567890123456"))
+	f := Fingerprints(UserAgentFingerprint, IPPrefixFingerprint(TrustProxies("10.0.0.0/8"), 24, 64))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "192.0.2.10:1234"
+	r.Header.Set("User-Agent", "browser")
+
+	encoded, err := s.FingerprintRequest(r, f).Encode("sid", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := s.Decode("sid", encoded, &dst); err != ErrMacInvalid {
+		t.Fatalf("Expected ErrMacInvalid without the fingerprint, got %v", err)
+	}
+
+	// Clients moving within the prefix keep their fingerprint.
+	r.RemoteAddr = "192.0.2.99:4321"
+	if err := s.FingerprintRequest(r, f).Decode("sid", encoded, &dst); err != nil || dst != "alice" {
+		t.Fatalf("Expected %q, got %q, %v", "alice", dst, err)
+	}
+	r.Header.Set("User-Agent", "other")
+	if err := s.FingerprintRequest(r, f).Decode("sid", encoded, &dst); err != ErrMacInvalid {
+		t.Fatalf("Expected ErrMacInvalid, got %v", err)
+	}
+
+	// Unbound values do not decode with a fingerprint.
+	plain, err := s.Encode("sid", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WithFingerprint([]byte("fp")).Decode("sid", plain, &dst); err != ErrMacInvalid {
+		t.Fatalf("Expected ErrMacInvalid, got %v", err)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.9, 198.51.100.7, 10.0.0.2")
+	if ip := (*ProxyTrust)(nil).ClientIP(r); ip.String() != "10.0.0.1" {
+		t.Fatalf("Expected the remote address, got %v", ip)
+	}
+	p := TrustProxies("10.0.0.0/8")
+	if ip := p.ClientIP(r); ip.String() != "198.51.100.7" {
+		t.Fatalf("Expected the first untrusted hop, got %v", ip)
+	}
+	if ip := (&ProxyTrust{All: true}).ClientIP(r); ip.String() != "10.0.0.2" {
+		t.Fatalf("Expected the last hop, got %v", ip)
+	}
+
+	// Every line of the header is read.
+	r.Header.Set("X-Forwarded-For", "203.0.113.9")
+	r.Header.Add("X-Forwarded-For", "198.51.100.7, 10.0.0.2")
+	if ip := p.ClientIP(r); ip.String() != "198.51.100.7" {
+		t.Fatalf("Expected the first untrusted hop, got %v", ip)
+	}
+	r.Header.Set("X-Forwarded-For", "10.0.0.3, 10.0.0.2")
+	if ip := p.ClientIP(r); ip.String() != "10.0.0.3" {
+		t.Fatalf("Expected the leftmost hop when all are trusted, got %v", ip)
+	}
+
+	r.Header.Del("X-Forwarded-For")
+	r.Header.Set("Forwarded", `for=203.0.113.9, for="[2001:db8::1]:4711";proto=https`)
+	r.Header.Add("Forwarded", "for=10.0.0.2")
+	if ip := p.ClientIP(r); ip.String() != "2001:db8::1" {
+		t.Fatalf("Expected the first untrusted hop, got %v", ip)
+	}
+}
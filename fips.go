@@ -0,0 +1,57 @@
+package securecookie
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"reflect"
+)
+
+var (
+	errFIPSHash   = Error{msg: "hash function is not FIPS approved"}
+	errFIPSCipher = Error{msg: "block cipher is not FIPS approved"}
+)
+
+// FIPS restricts s to FIPS approved algorithms: HMAC with SHA-256, SHA-384
+// or SHA-512 (or another SHA-2 variant), and AES. If the current hash
+// function or block cipher is not approved, or a later call to HashFunc or
+// BlockFunc selects one that is not, the error is returned by every call to
+// Encode and Decode; with NewStrict it is returned at construction:
+//
+//	s, err := securecookie.NewStrict(hashKey, blockKey, func(s *securecookie.SecureCookie) {
+//		s.FIPS(true)
+//	})
+//
+// Default is false.
+func (s *SecureCookie) FIPS(enabled bool) *SecureCookie {
+	s.fips = enabled
+	if !enabled {
+		return s
+	}
+	if !fipsHash(s.hashFunc) {
+		s.err = errFIPSHash
+	} else if s.block != nil && !fipsBlock(s.block) {
+		s.err = errFIPSCipher
+	}
+	return s
+}
+
+// FIPSMode reports whether s is restricted to FIPS approved algorithms.
+func (s *SecureCookie) FIPSMode() bool {
+	return s.fips
+}
+
+// fipsHash reports whether f returns a SHA-2 hash. The SHA-224 and SHA-256
+// digests share an implementation, as do the SHA-384 and SHA-512 ones.
+func fipsHash(f func() hash.Hash) bool {
+	t := reflect.TypeOf(f())
+	return t == reflect.TypeOf(sha256.New()) || t == reflect.TypeOf(sha512.New())
+}
+
+// fipsBlock reports whether block is an AES cipher.
+func fipsBlock(block cipher.Block) bool {
+	ref, err := aes.NewCipher(make([]byte, 16))
+	return err == nil && reflect.TypeOf(block) == reflect.TypeOf(ref)
+}
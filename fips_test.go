@@ -0,0 +1,39 @@
+package securecookie
+
+import (
+	"crypto/des"
+	"crypto/sha1"
+	"crypto/sha512"
+	"errors"
+	"testing"
+)
+
+func TestFIPS(t *testing.T) {
+	hashKey := GenerateRandomKey(32)
+	s, err := NewStrict(hashKey, GenerateRandomKey(32), func(s *SecureCookie) {
+		s.FIPS(true).HashFunc(sha512.New384)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.FIPSMode() {
+		t.Fatal("Expected FIPS mode")
+	}
+	encoded, err := s.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := s.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected value, got %q, %v", dst, err)
+	}
+
+	_, err = NewStrict(hashKey, nil, func(s *SecureCookie) { s.FIPS(true).HashFunc(sha1.New) })
+	if !errors.Is(err, errFIPSHash) {
+		t.Fatalf("Expected errFIPSHash, got %v", err)
+	}
+	s = New(hashKey, GenerateRandomKey(8)).BlockFunc(des.NewCipher).FIPS(true)
+	if _, err := s.Encode("sid", "value"); !errors.Is(err, errFIPSCipher) {
+		t.Fatalf("Expected errFIPSCipher, got %v", err)
+	}
+}
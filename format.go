@@ -0,0 +1,123 @@
+package securecookie
+
+import (
+	"encoding/binary"
+)
+
+// Wire format versions. Version 1 values have no version byte and start with
+// the MAC; later versions start with their version byte, covered by the MAC.
+const (
+	formatV1 byte = 1
+	formatV2 byte = 2
+)
+
+var errFormatVersion = Error{msg: "unsupported format version"}
+
+// FormatVersion sets the wire format version of encoded values, 1 or 2.
+// Version 2 values start with a version byte, so that future wire format
+// changes can be routed by version while older values keep decoding.
+//
+// Decode accepts values in every version, whatever the setting, so a
+// migration is: deploy decoders first, then switch encoders to the new
+// version, and let values in the old one expire. As version 1 values have
+// no version byte, those whose MAC starts with a version byte are tried
+// with that version first.
+//
+// Default is 1.
+func (s *SecureCookie) FormatVersion(v int) *SecureCookie {
+	if v != int(formatV1) && v != int(formatV2) {
+		s.err = errFormatVersion
+		return s
+	}
+	s.version = byte(v)
+	return s
+}
+
+// versionLen returns the length of the version byte of encoded values.
+func (s *SecureCookie) versionLen() int {
+	if s.version < formatV2 {
+		return 0
+	}
+	return 1
+}
+
+// seal returns the MAC of payload followed by payload, preceded by the
+// version byte from version 2.
+func (s *SecureCookie) seal(payload []byte) []byte {
+	h := s.newMac()
+	out := make([]byte, 0, s.versionLen()+h.Size()+len(payload))
+	if s.versionLen() != 0 {
+		out = append(out, s.version)
+		h.Write(out)
+	}
+	out = append(out, createMac(h, s.authenticated(payload))...)
+	return append(out, payload...)
+}
+
+// open verifies the MAC of a value returned by seal, routing it by version,
+// and returns its payload and version.
+func (s *SecureCookie) open(b []byte) ([]byte, byte, error) {
+	if len(b) == 0 || b[0] != formatV2 {
+		payload, err := s.openVersion(nil, b)
+		return payload, formatV1, err
+	}
+	payload, err := s.openVersion(b[:1], b[1:])
+	if err == nil {
+		return payload, formatV2, nil
+	}
+	if payload, err1 := s.openVersion(nil, b); err1 == nil {
+		return payload, formatV1, nil
+	}
+	return nil, 0, err
+}
+
+// openVersion verifies the MAC of a value without its version byte, if any.
+func (s *SecureCookie) openVersion(version, b []byte) ([]byte, error) {
+	if len(b) <= s.hmacSize {
+		return nil, errValueToDecodeTooSmall
+	}
+	mac, payload := b[:s.hmacSize], b[s.hmacSize:]
+	if len(payload) < 2 {
+		return nil, errValueToDecodeTooSmall
+	}
+	// The flags are checked again by the MAC; reading them first gives a
+	// better error than a MAC failure.
+	header := binary.LittleEndian.Uint16(payload[:2])
+	if header&flagKeysStretched != s.flags&flagKeysStretched {
+		return nil, errKeyStretchedMismatch
+	}
+	h := s.newMac()
+	h.Write(version)
+	if err := verifyMac(h, s.authenticated(payload), mac); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// fieldsLen returns the length of the fields between the name and the data
+// of values with the given header.
+func fieldsLen(header uint16) int {
+	n := timestampLen(header)
+	if header&flagNotBefore != 0 {
+		n += 8
+	}
+	if header&flagExpiry != 0 {
+		n += 8
+	}
+	if header&flagTokenID != 0 {
+		n += tokenIDSize
+	}
+	return n
+}
+
+// valueInfo holds the fields of a decoded value. Times are 0 if unset.
+type valueInfo struct {
+	// timestamp is as stored, in milliseconds if timestampMillis is set.
+	timestamp uint64
+	notBefore int64
+	expiry    int64
+	// version is the format version, 0 for upstream values.
+	version byte
+	// tokenID is the token ID, base64url-encoded without padding.
+	tokenID string
+}
@@ -0,0 +1,49 @@
+package securecookie
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestFormatVersion(t *testing.T) {
+	v1 := New([]byte("12345"), []byte("1234567890123456"))
+	v2 := New([]byte("12345"), []byte("1234567890123456")).FormatVersion(2)
+	encoded, err := v2.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := base64.URLEncoding.DecodeString(encoded)
+	if b[0] != formatV2 {
+		t.Fatalf("Expected version byte %d, got %d", formatV2, b[0])
+	}
+	if n, _ := v2.EstimatedLength("sid", "value"); n != len(encoded) {
+		t.Fatalf("Expected estimated length %d, got %d", len(encoded), n)
+	}
+	// Both versions decode whatever the setting.
+	for _, codec := range []*SecureCookie{v1, v2} {
+		var dst string
+		if err := codec.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+			t.Fatalf("Expected %q, got %q, %v", "value", dst, err)
+		}
+	}
+	// Version 1 values whose MAC starts with the version byte still decode.
+	for found := false; !found; {
+		old, _ := v1.Encode("sid", "value")
+		b, _ := base64.URLEncoding.DecodeString(old)
+		if found = b[0] == formatV2; found {
+			var dst string
+			if err := v2.Decode("sid", old, &dst); err != nil || dst != "value" {
+				t.Fatalf("Expected %q, got %q, %v", "value", dst, err)
+			}
+		}
+	}
+	// The version byte is authenticated.
+	b[0] = formatV1
+	var dst string
+	if err := v2.Decode("sid", base64.URLEncoding.EncodeToString(b), &dst); err == nil {
+		t.Fatal("Expected failure decoding a value with a modified version")
+	}
+	if err := New([]byte("12345"), nil).FormatVersion(3).err; err != errFormatVersion {
+		t.Fatalf("Expected errFormatVersion, got %v", err)
+	}
+}
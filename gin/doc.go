@@ -0,0 +1,6 @@
+// Package gin adapts the HTTP helpers of securecookie to Gin.
+//
+// This package requires github.com/gin-gonic/gin, which is not a dependency
+// of the default build. Add it with "go get github.com/gin-gonic/gin" and
+// build with "-tags gin".
+package gin
@@ -0,0 +1,166 @@
+//go:build gin
+
+package gin
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	gingonic "github.com/gin-gonic/gin"
+
+	"github.com/monime-lab/gorilla-securecookie"
+)
+
+const (
+	specsKey  = "securecookie.specs"
+	valuesKey = "securecookie.values"
+)
+
+// Middleware decodes the cookies of specs once per request, like
+// securecookie.CookieMiddleware, including ClearInvalid and InvalidRedirect.
+// Handlers read the results with Get and change them with Set.
+func Middleware(specs ...securecookie.CookieSpec) gingonic.HandlerFunc {
+	declared := make(map[string]securecookie.CookieSpec, len(specs))
+	for _, spec := range specs {
+		declared[spec.Name] = spec
+	}
+	mw := securecookie.CookieMiddleware(specs...)
+	return func(c *gingonic.Context) {
+		c.Set(specsKey, declared)
+		c.Set(valuesKey, map[string]interface{}{})
+		served := false
+		mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			served = true
+			c.Request = r
+			writer := c.Writer
+			c.Writer = &responseWriter{ResponseWriter: writer, w: w}
+			defer func() { c.Writer = writer }()
+			c.Next()
+		})).ServeHTTP(c.Writer, c.Request)
+		if !served {
+			c.Abort()
+		}
+	}
+}
+
+// responseWriter writes through w, the writer of
+// securecookie.CookieMiddleware, so that modified cookies are set before the
+// response headers are written.
+type responseWriter struct {
+	gingonic.ResponseWriter
+	w http.ResponseWriter
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.w.Header()
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.w.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	return w.w.Write(b)
+}
+
+func (w *responseWriter) WriteString(s string) (int, error) {
+	return w.w.Write([]byte(s))
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.w.WriteHeader(w.Status())
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *responseWriter) Flush() {
+	w.w.(http.Flusher).Flush()
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.w.(http.Hijacker).Hijack()
+}
+
+// Get returns the value of the named cookie, as a pointer created by its
+// CookieSpec.New, or the value last given to Set. Errors are those of
+// securecookie.FromContext.
+func Get(c *gingonic.Context, name string) (interface{}, error) {
+	if v, ok := values(c)[name]; ok {
+		return v, nil
+	}
+	return securecookie.FromContext(c.Request.Context(), name)
+}
+
+// Set encodes value and sets it as the named cookie, with the codec and
+// attributes of its CookieSpec, and makes Get return it for the rest of the
+// request. Values modified in place must be set again for the cookie to be
+// updated. Set must be called before the response is written.
+func Set(c *gingonic.Context, name string, value interface{}) error {
+	spec, ok := lookup(c, name)
+	if !ok {
+		_, err := securecookie.FromContext(c.Request.Context(), name)
+		return err
+	}
+	var cookie *http.Cookie
+	if s, ok := spec.Codec.(*securecookie.SecureCookie); ok {
+		var err error
+		if cookie, err = s.NewCookie(c.Request, name, value, spec.Options); err != nil {
+			return err
+		}
+	} else {
+		codec, err := securecookie.RequestCodec(spec.Codec, c.Request)
+		if err != nil {
+			return err
+		}
+		encoded, err := codec.Encode(name, value)
+		if err != nil {
+			return err
+		}
+		cookie = newCookie(name, encoded, spec.Options)
+	}
+	http.SetCookie(c.Writer, cookie)
+	if values := values(c); values != nil {
+		values[name] = value
+	}
+	return nil
+}
+
+// Delete expires the named cookie, with the attributes of its CookieSpec.
+func Delete(c *gingonic.Context, name string) {
+	spec, ok := lookup(c, name)
+	if !ok {
+		return
+	}
+	cookie := newCookie(name, "", spec.Options)
+	cookie.MaxAge = -1
+	http.SetCookie(c.Writer, cookie)
+	delete(values(c), name)
+}
+
+func lookup(c *gingonic.Context, name string) (securecookie.CookieSpec, bool) {
+	v, _ := c.Get(specsKey)
+	spec, ok := v.(map[string]securecookie.CookieSpec)[name]
+	return spec, ok
+}
+
+func values(c *gingonic.Context) map[string]interface{} {
+	v, _ := c.Get(valuesKey)
+	values, _ := v.(map[string]interface{})
+	return values
+}
+
+// newCookie returns a cookie with the attributes of opts, for codecs other
+// than *securecookie.SecureCookie.
+func newCookie(name, value string, opts *securecookie.CookieOptions) *http.Cookie {
+	c := &http.Cookie{Name: name, Value: value, Path: "/", Secure: true, HttpOnly: true, SameSite: http.SameSiteLaxMode}
+	if opts != nil {
+		c.Path, c.Domain, c.MaxAge = opts.Path, opts.Domain, opts.MaxAge
+		c.Secure, c.HttpOnly, c.SameSite = opts.Secure, opts.HttpOnly, opts.SameSite
+		if c.Path == "" {
+			c.Path = "/"
+		}
+	}
+	return c
+}
@@ -0,0 +1,121 @@
+//go:build gin
+
+package gin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gingonic "github.com/gin-gonic/gin"
+
+	"github.com/monime-lab/gorilla-securecookie"
+)
+
+type session struct {
+	User string
+}
+
+func serve(spec securecookie.CookieSpec, value string, handler gingonic.HandlerFunc) *httptest.ResponseRecorder {
+	gingonic.SetMode(gingonic.TestMode)
+	router := gingonic.New()
+	router.GET("/", Middleware(spec), handler)
+	r := httptest.NewRequest("GET", "/", nil)
+	if value != "" {
+		r.AddCookie(&http.Cookie{Name: spec.Name, Value: value})
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	s := securecookie.New([]byte("12345"), nil)
+	spec := securecookie.CookieSpec{Name: "session", Codec: s, New: func() interface{} { return &session{} }}
+	encoded, err := s.Encode("session", session{User: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := func(c *gingonic.Context) {
+		v, err := Get(c, "session")
+		if err != nil {
+			c.String(http.StatusUnauthorized, err.Error())
+			return
+		}
+		c.String(http.StatusOK, v.(*session).User)
+	}
+
+	if w := serve(spec, encoded, user); w.Code != http.StatusOK || w.Body.String() != "alice" {
+		t.Fatalf("Expected alice, got %d %q", w.Code, w.Body.String())
+	}
+
+	missing := false
+	serve(spec, "", func(c *gingonic.Context) {
+		_, err := Get(c, "session")
+		missing = errors.Is(err, http.ErrNoCookie)
+	})
+	if !missing {
+		t.Fatal("Expected http.ErrNoCookie for a missing cookie")
+	}
+
+	if w := serve(spec, encoded+"x", user); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected an invalid cookie to fail to decode, got %d", w.Code)
+	}
+
+	spec.ClearInvalid, spec.InvalidRedirect = true, "/login"
+	served := false
+	w := serve(spec, encoded+"x", func(c *gingonic.Context) { served = true })
+	if served || w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" {
+		t.Fatalf("Expected a redirect to /login, got %d %q, served %v", w.Code, w.Header().Get("Location"), served)
+	}
+	if c := w.Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 {
+		t.Fatalf("Expected the invalid cookie to be cleared, got %v", c)
+	}
+}
+
+func TestSet(t *testing.T) {
+	s := securecookie.New([]byte("12345"), nil)
+	spec := securecookie.CookieSpec{Name: "session", Codec: s, New: func() interface{} { return &session{} }}
+	w := serve(spec, "", func(c *gingonic.Context) {
+		if err := Set(c, "session", &session{User: "bob"}); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := Get(c, "session"); err != nil || v.(*session).User != "bob" {
+			t.Fatalf("Expected bob, got %v, %v", v, err)
+		}
+		if err := Set(c, "other", "x"); err == nil {
+			t.Fatal("Expected an error for an undeclared cookie")
+		}
+	})
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected one cookie, got %v", cookies)
+	}
+	var dst session
+	if err := s.Decode("session", cookies[0].Value, &dst); err != nil || dst.User != "bob" {
+		t.Fatalf("Expected bob, got %q, %v", dst.User, err)
+	}
+}
+
+func TestMiddlewareModifiedInPlace(t *testing.T) {
+	s := securecookie.New([]byte("12345"), nil)
+	spec := securecookie.CookieSpec{Name: "session", Codec: s, New: func() interface{} { return &session{} }}
+	encoded, _ := s.Encode("session", session{User: "alice"})
+	w := serve(spec, encoded, func(c *gingonic.Context) {
+		v, err := Get(c, "session")
+		if err != nil {
+			t.Fatal(err)
+		}
+		v.(*session).User = "bob"
+		c.String(http.StatusOK, "ok")
+	})
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected the modified value to be set before the body, got %v", cookies)
+	}
+	var dst session
+	if err := s.Decode("session", cookies[0].Value, &dst); err != nil || dst.User != "bob" {
+		t.Fatalf("Expected bob, got %q, %v", dst.User, err)
+	}
+}
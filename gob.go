@@ -0,0 +1,61 @@
+//go:build gob
+
+package securecookie
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+)
+
+// This file is only built with "-tags gob", so binaries that do not need
+// encoding/gob do not link it.
+
+var errGobTypeNotAllowed = Error{msg: "gob destination type is not allowed"}
+
+// GobEncoder encodes cookie values using encoding/gob. Decoding is limited
+// to an allowlist of concrete destination types: decoding client-supplied
+// gob into interface-typed destinations can instantiate any type registered
+// with gob.Register, so they are always rejected. Interface-typed fields of
+// allowed types are still decoded; avoid them in cookie payloads.
+type GobEncoder struct {
+	allowed map[reflect.Type]bool
+}
+
+// NewGobEncoder returns a GobEncoder decoding only into values of the types
+// of the given examples, e.g. NewGobEncoder(Session{}, []string(nil)).
+func NewGobEncoder(types ...interface{}) *GobEncoder {
+	e := &GobEncoder{allowed: make(map[reflect.Type]bool, len(types))}
+	for _, v := range types {
+		e.allowed[reflect.TypeOf(v)] = true
+	}
+	return e
+}
+
+// decodesGob marks GobEncoder for AuditConfig.
+func (e *GobEncoder) decodesGob() {}
+
+// Serialize encodes a value using encoding/gob.
+func (e *GobEncoder) Serialize(src interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := gob.NewEncoder(buf)
+	if err := enc.Encode(src); err != nil {
+		return nil, Error{msg: err.Error()}
+	}
+	return buf.Bytes(), nil
+}
+
+// Deserialize decodes a value using encoding/gob. dst must be a pointer to
+// an allowed type.
+func (e *GobEncoder) Deserialize(src []byte, dst interface{}) error {
+	t := reflect.TypeOf(dst)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() == reflect.Interface || !e.allowed[t.Elem()] {
+		return fmt.Errorf("%w: %T", errGobTypeNotAllowed, dst)
+	}
+	dec := gob.NewDecoder(bytes.NewBuffer(src))
+	if err := dec.Decode(dst); err != nil {
+		return Error{msg: err.Error()}
+	}
+	return nil
+}
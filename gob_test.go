@@ -0,0 +1,53 @@
+//go:build gob
+
+package securecookie
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGobEncoderAllowlist(t *testing.T) {
+	sz := NewGobEncoder(FooBar{})
+	s := New([]byte("12345"), nil).SetSerializer(sz)
+	encoded, err := s.Encode("sid", FooBar{42, "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst FooBar
+	if err := s.Decode("sid", encoded, &dst); err != nil || dst.Foo != 42 {
+		t.Fatalf("Expected 42, got %v, %v", dst.Foo, err)
+	}
+
+	b, _ := sz.Serialize(FooBar{42, "bar"})
+	var any interface{}
+	if err := sz.Deserialize(b, &any); !errors.Is(err, errGobTypeNotAllowed) {
+		t.Fatalf("Expected errGobTypeNotAllowed, got %v", err)
+	}
+	var m map[string]string
+	if err := sz.Deserialize(b, &m); !errors.Is(err, errGobTypeNotAllowed) {
+		t.Fatalf("Expected errGobTypeNotAllowed, got %v", err)
+	}
+}
+
+// upstreamGob is map[string]interface{}{"user": "alice"} as encoded by
+// github.com/gorilla/securecookie v1.1.2 with the hash key "12345", no block
+// key, its default GobEncoder, the name "sid" and the timestamp 1792119057.
+const upstreamGob = "MTc5MjExOTA1N3xEWDhFQVFMX2dBQUJEQUVRQUFBWl80QUFBUVIxYzJWeUJuTjBjbWx1Wnd3SEFBVmhiR2xqWlE9PXw_crlt_ZroRtHJoVwtc0EzsQAW2Xpwd-eXL6Q0aKJqYA=="
+
+func TestLegacyDecodeGob(t *testing.T) {
+	s := New([]byte("12345"), nil).LegacyDecode(true).SetSerializer(NewGobEncoder(map[string]interface{}{}))
+	s.timeFunc = func() int64 { return 1792119060 }
+	var dst map[string]interface{}
+	if err := s.Decode("sid", upstreamGob, &dst); err != nil || dst["user"] != "alice" {
+		t.Fatalf("Expected alice, got %v, %v", dst, err)
+	}
+}
+
+func TestAuditConfigGob(t *testing.T) {
+	s := New(GenerateRandomKey(32), GenerateRandomKey(32)).MinAge(1).SetSerializer(NewGobEncoder(FooBar{}))
+	findings := AuditConfig(s)
+	if len(findings) != 1 || findings[0].Code != "gob-serializer" {
+		t.Fatalf("Expected finding gob-serializer only, got %v", findings)
+	}
+}
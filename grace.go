@@ -0,0 +1,70 @@
+package securecookie
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ExpiredError is returned by DecodeExpired for values expired within the
+// grace window. It wraps the expiry error Decode would have returned.
+type ExpiredError struct {
+	// ExpiredAt is the time the value expired.
+	ExpiredAt time.Time
+	err       error
+}
+
+func (e *ExpiredError) Error() string {
+	return fmt.Sprintf("%v, at %s", e.err, e.ExpiredAt.UTC().Format(time.RFC3339))
+}
+
+// Unwrap returns the expiry error.
+func (e *ExpiredError) Unwrap() error {
+	return e.err
+}
+
+// DecodeExpired decodes a cookie value like Decode, but values expired for
+// less than grace, through MaxAge or ExpiresAt, are still decoded into dst
+// and an *ExpiredError is returned, e.g. to show "session expired, log in
+// again as alice" rather than a generic failure. The value must not be
+// trusted as a valid session: with SingleUse, the token ID of an expired
+// value is not consumed.
+func (s *SecureCookie) DecodeExpired(name, value string, dst interface{}, grace time.Duration) error {
+	// A single pass tolerating the grace window, so that revocation is
+	// checked once and the token is only consumed for valid values.
+	c := *s
+	c.grace, c.consume = grace, nil
+	info := &valueInfo{}
+	err := c.decode(name, value, dst, s.maxLength, info)
+	if err == nil {
+		err = s.checkExpired(info)
+	}
+	var expired *ExpiredError
+	switch {
+	case err == nil:
+		if s.consume != nil {
+			return s.consumeToken(info)
+		}
+		return nil
+	case errors.As(err, &expired), errors.Is(err, errTimestampExpired), errors.Is(err, errValueExpired):
+		return err
+	}
+	s.sampleFailure(name, value, err)
+	return err
+}
+
+// checkExpired returns an *ExpiredError if a value decoded within a grace
+// window is expired.
+func (s *SecureCookie) checkExpired(info *valueInfo) error {
+	if info.timestamp != 0 && s.maxAge != 0 {
+		if err := s.checkAge(info.timestamp); err != nil {
+			return &ExpiredError{ExpiredAt: issuedAt(info.timestamp).Add(time.Duration(s.maxAge) * time.Second), err: err}
+		}
+	}
+	if info.expiry != 0 {
+		if err := s.checkExpiry(uint64(info.expiry)); err != nil {
+			return &ExpiredError{ExpiredAt: time.Unix(info.expiry, 0), err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package securecookie
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDecodeExpired(t *testing.T) {
+	issued := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := issued
+	s := New([]byte("12345"), nil).MaxAge(3600).SetClock(ClockFunc(func() time.Time { return now }))
+	encoded, err := s.Encode("sid", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	now = issued.Add(30 * time.Minute)
+	if err := s.DecodeExpired("sid", encoded, &dst, time.Hour); err != nil || dst != "alice" {
+		t.Fatalf("Expected %q, got %q, %v", "alice", dst, err)
+	}
+
+	dst = ""
+	now = issued.Add(90 * time.Minute)
+	err = s.DecodeExpired("sid", encoded, &dst, time.Hour)
+	var expired *ExpiredError
+	if !errors.As(err, &expired) || !errors.Is(err, errTimestampExpired) || dst != "alice" {
+		t.Fatalf("Expected an *ExpiredError and %q, got %q, %v", "alice", dst, err)
+	}
+	if !expired.ExpiredAt.Equal(issued.Add(time.Hour)) {
+		t.Fatalf("Expected expiry at %v, got %v", issued.Add(time.Hour), expired.ExpiredAt)
+	}
+	if err := s.Decode("sid", encoded, &dst); err != errTimestampExpired {
+		t.Fatalf("Expected Decode to be unaffected, got %v", err)
+	}
+
+	now = issued.Add(3 * time.Hour)
+	if err := s.DecodeExpired("sid", encoded, &dst, time.Hour); err != errTimestampExpired {
+		t.Fatalf("Expected errTimestampExpired past the grace window, got %v", err)
+	}
+
+	now = issued
+	encoded, _ = s.EncodeWith("sid", "bob", ExpiresAt(issued.Add(time.Minute)))
+	now = issued.Add(2 * time.Minute)
+	err = s.DecodeExpired("sid", encoded, &dst, time.Hour)
+	if !errors.As(err, &expired) || !expired.ExpiredAt.Equal(issued.Add(time.Minute)) || dst != "bob" {
+		t.Fatalf("Expected an *ExpiredError at the embedded expiry, got %q, %v", dst, err)
+	}
+}
+
+// countingChecker counts revocation lookups.
+type countingChecker struct{ n int }
+
+func (c *countingChecker) Revoked(ctx context.Context, id string, issuedAt time.Time) (bool, error) {
+	c.n++
+	return false, nil
+}
+
+func TestDecodeExpiredSingleUse(t *testing.T) {
+	issued := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := issued
+	m := NewMemoryRevocations()
+	m.now = func() time.Time { return now }
+	checker := &countingChecker{}
+	s := New([]byte("12345"), nil).MaxAge(3600).SetClock(ClockFunc(func() time.Time { return now })).
+		SingleUse(m, 0).CheckRevocation(checker, 0)
+	expiring, _ := s.Encode("sid", "alice")
+
+	now = issued.Add(90 * time.Minute)
+	var dst string
+	var expired *ExpiredError
+	if err := s.DecodeExpired("sid", expiring, &dst, time.Hour); !errors.As(err, &expired) {
+		t.Fatalf("Expected an *ExpiredError, got %v", err)
+	}
+	if checker.n != 1 {
+		t.Fatalf("Expected 1 revocation lookup, got %d", checker.n)
+	}
+	if m.Len() != 0 {
+		t.Fatal("Expected the token of an expired value not to be consumed")
+	}
+
+	valid, _ := s.Encode("sid", "bob")
+	if err := s.DecodeExpired("sid", valid, &dst, time.Hour); err != nil || dst != "bob" {
+		t.Fatalf("Expected %q, got %q, %v", "bob", dst, err)
+	}
+	if err := s.DecodeExpired("sid", valid, &dst, time.Hour); err != errTokenConsumed {
+		t.Fatalf("Expected errTokenConsumed, got %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+// Package grpc carries secure values in gRPC metadata, so that internal
+// calls can forward the same sealed session as browser cookies.
+//
+// This package requires google.golang.org/grpc, which is not a dependency of
+// the default build. Add it with "go get google.golang.org/grpc" and build
+// with "-tags grpc".
+package grpc
@@ -0,0 +1,227 @@
+//go:build grpc
+
+package grpc
+
+import (
+	"context"
+
+	googlegrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/monime-lab/gorilla-securecookie"
+)
+
+// DefaultKey is the metadata key of values when Metadata.Key is empty.
+const DefaultKey = "x-securecookie"
+
+// Metadata encodes values into the metadata of outgoing calls and decodes
+// them from incoming ones.
+type Metadata struct {
+	Codec securecookie.Codec
+	// Key is the metadata key carrying the value, in lower case. Default is
+	// DefaultKey.
+	Key string
+	// Name is the name values are encoded under, e.g. the name of the
+	// session cookie so that its value can be forwarded as is with
+	// WithEncoded. Default is Key.
+	Name string
+	// New returns a pointer to a new value to decode into, like
+	// securecookie.CookieSpec.New. Default is a pointer to a
+	// map[string]interface{}.
+	New func() interface{}
+	// Required makes server interceptors reject calls without a value with
+	// codes.Unauthenticated. Otherwise they are served, and FromContext
+	// returns the error.
+	Required bool
+}
+
+type contextKey struct {
+	key      string
+	incoming bool
+}
+
+type outgoing struct {
+	value   interface{}
+	encoded string
+}
+
+type incoming struct {
+	value interface{}
+	err   error
+}
+
+// WithValue returns a copy of ctx whose calls through the client
+// interceptors carry value, encoded with Codec.
+func (m *Metadata) WithValue(ctx context.Context, value interface{}) context.Context {
+	return context.WithValue(ctx, contextKey{key: m.key()}, &outgoing{value: value})
+}
+
+// WithEncoded returns a copy of ctx whose calls through the client
+// interceptors carry an already encoded value, e.g. the value of a session
+// cookie.
+func (m *Metadata) WithEncoded(ctx context.Context, encoded string) context.Context {
+	return context.WithValue(ctx, contextKey{key: m.key()}, &outgoing{encoded: encoded})
+}
+
+// FromContext returns the value decoded by the server interceptors, as a
+// pointer created by New, or a status error with codes.Unauthenticated, or
+// codes.Unavailable for failures of stores, see securecookie.Temporary.
+func (m *Metadata) FromContext(ctx context.Context) (interface{}, error) {
+	in, ok := ctx.Value(contextKey{key: m.key(), incoming: true}).(*incoming)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "securecookie: no value for "+m.key())
+	}
+	return in.value, in.err
+}
+
+// UnaryClientInterceptor returns an interceptor adding the value of the
+// context, see WithValue, to the metadata of unary calls.
+func (m *Metadata) UnaryClientInterceptor() googlegrpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *googlegrpc.ClientConn, invoker googlegrpc.UnaryInvoker, opts ...googlegrpc.CallOption) error {
+		ctx, err := m.outgoingContext(ctx)
+		if err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns an interceptor adding the value of the
+// context, see WithValue, to the metadata of streams.
+func (m *Metadata) StreamClientInterceptor() googlegrpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *googlegrpc.StreamDesc, cc *googlegrpc.ClientConn, method string, streamer googlegrpc.Streamer, opts ...googlegrpc.CallOption) (googlegrpc.ClientStream, error) {
+		ctx, err := m.outgoingContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// UnaryServerInterceptor returns an interceptor decoding the value of the
+// metadata of unary calls, for FromContext.
+func (m *Metadata) UnaryServerInterceptor() googlegrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *googlegrpc.UnaryServerInfo, handler googlegrpc.UnaryHandler) (interface{}, error) {
+		ctx, err := m.incomingContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor decoding the value of the
+// metadata of streams, for FromContext.
+func (m *Metadata) StreamServerInterceptor() googlegrpc.StreamServerInterceptor {
+	return func(srv interface{}, ss googlegrpc.ServerStream, info *googlegrpc.StreamServerInfo, handler googlegrpc.StreamHandler) error {
+		ctx, err := m.incomingContext(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// outgoingContext returns ctx with the value it carries added to its
+// outgoing metadata.
+func (m *Metadata) outgoingContext(ctx context.Context) (context.Context, error) {
+	out, ok := ctx.Value(contextKey{key: m.key()}).(*outgoing)
+	if !ok {
+		return ctx, nil
+	}
+	encoded := out.encoded
+	if out.value != nil {
+		var err error
+		if encoded, err = m.Codec.Encode(m.name(), out.value); err != nil {
+			return nil, err
+		}
+	}
+	if encoded == "" {
+		return ctx, nil
+	}
+	return metadata.AppendToOutgoingContext(ctx, m.key(), encoded), nil
+}
+
+// incomingContext decodes the value of the incoming metadata of ctx and
+// returns ctx holding the result. It returns an error if the value is
+// required and did not decode.
+func (m *Metadata) incomingContext(ctx context.Context) (context.Context, error) {
+	in := &incoming{}
+	md, _ := metadata.FromIncomingContext(ctx)
+	switch values := md.Get(m.key()); len(values) {
+	case 0:
+		in.err = status.Error(codes.Unauthenticated, "securecookie: no value for "+m.key())
+	case 1:
+		in.value = m.newValue()
+		if err := m.Codec.Decode(m.name(), values[0], in.value); err != nil {
+			in.value, in.err = nil, statusError(err)
+		}
+	default:
+		in.err = status.Error(codes.Unauthenticated, "securecookie: several values for "+m.key())
+	}
+	if in.err != nil && m.Required {
+		return nil, in.err
+	}
+	return context.WithValue(ctx, contextKey{key: m.key(), incoming: true}, in), nil
+}
+
+func (m *Metadata) key() string {
+	if m.Key != "" {
+		return m.Key
+	}
+	return DefaultKey
+}
+
+func (m *Metadata) name() string {
+	if m.Name != "" {
+		return m.Name
+	}
+	return m.key()
+}
+
+func (m *Metadata) newValue() interface{} {
+	if m.New != nil {
+		return m.New()
+	}
+	return &map[string]interface{}{}
+}
+
+// statusError maps a decoding error to a status error with a fixed message,
+// so that its details are not sent to the caller.
+func statusError(err error) error {
+	if securecookie.Temporary(err) {
+		return &decodeError{status: status.New(codes.Unavailable, "securecookie: temporarily unavailable"), err: err}
+	}
+	return &decodeError{status: status.New(codes.Unauthenticated, "securecookie: invalid value"), err: err}
+}
+
+// decodeError is a status error that keeps the decoding error for the
+// server, e.g. for logging, through Unwrap.
+type decodeError struct {
+	status *status.Status
+	err    error
+}
+
+func (e *decodeError) Error() string {
+	return e.status.Message()
+}
+
+func (e *decodeError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.err
+}
+
+// serverStream overrides the context of a stream.
+type serverStream struct {
+	googlegrpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
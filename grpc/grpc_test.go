@@ -0,0 +1,165 @@
+//go:build grpc
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	googlegrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/monime-lab/gorilla-securecookie"
+)
+
+type failingChecker struct{}
+
+func (failingChecker) Revoked(context.Context, string, time.Time) (bool, error) {
+	return false, errors.New("store down")
+}
+
+type stream struct {
+	googlegrpc.ServerStream
+	ctx context.Context
+}
+
+func (s *stream) Context() context.Context {
+	return s.ctx
+}
+
+// send runs the unary client interceptor of m with ctx and returns the
+// incoming context of the server.
+func send(t *testing.T, m *Metadata, ctx context.Context) context.Context {
+	var md metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *googlegrpc.ClientConn, opts ...googlegrpc.CallOption) error {
+		md, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	if err := m.UnaryClientInterceptor()(ctx, "/svc/M", nil, nil, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+// serve runs the unary server interceptor of m with ctx and returns the
+// result of FromContext in the handler.
+func serve(m *Metadata, ctx context.Context) (interface{}, error) {
+	var value interface{}
+	var valueErr error
+	_, err := m.UnaryServerInterceptor()(ctx, nil, &googlegrpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		value, valueErr = m.FromContext(ctx)
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, valueErr
+}
+
+func TestUnaryRoundTrip(t *testing.T) {
+	m := &Metadata{Codec: securecookie.New([]byte("12345"), nil)}
+	v, err := serve(m, send(t, m, m.WithValue(context.Background(), map[string]interface{}{"user": "alice"})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user := (*v.(*map[string]interface{}))["user"]; user != "alice" {
+		t.Fatalf("Expected alice, got %v", user)
+	}
+
+	encoded, err := m.Codec.Encode(DefaultKey, map[string]interface{}{"user": "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = serve(m, send(t, m, m.WithEncoded(context.Background(), encoded)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user := (*v.(*map[string]interface{}))["user"]; user != "bob" {
+		t.Fatalf("Expected bob, got %v", user)
+	}
+}
+
+func TestUnaryServerErrors(t *testing.T) {
+	s := securecookie.New([]byte("12345"), nil)
+	m := &Metadata{Codec: s}
+	encoded, err := s.Encode(DefaultKey, map[string]interface{}{"user": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	incoming := func(values ...string) context.Context {
+		md := metadata.MD{}
+		for _, v := range values {
+			md.Append(DefaultKey, v)
+		}
+		return metadata.NewIncomingContext(context.Background(), md)
+	}
+
+	for _, test := range []struct {
+		name string
+		m    *Metadata
+		ctx  context.Context
+		code codes.Code
+	}{
+		{"missing", m, context.Background(), codes.Unauthenticated},
+		{"invalid", m, incoming(encoded + "x"), codes.Unauthenticated},
+		{"several", m, incoming(encoded, encoded), codes.Unauthenticated},
+		{"temporary", &Metadata{Codec: securecookie.New([]byte("12345"), nil).CheckRevocation(failingChecker{}, 0)}, incoming(encoded), codes.Unavailable},
+	} {
+		if _, err := serve(test.m, test.ctx); status.Code(err) != test.code {
+			t.Errorf("%s: expected %v from FromContext, got %v", test.name, test.code, err)
+		}
+		required := *test.m
+		required.Required = true
+		served := false
+		_, err := required.UnaryServerInterceptor()(test.ctx, nil, &googlegrpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			served = true
+			return nil, nil
+		})
+		if served || status.Code(err) != test.code {
+			t.Errorf("%s: expected a required value to reject the call with %v, got %v", test.name, test.code, err)
+		}
+	}
+	if _, err := m.FromContext(context.Background()); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected Unauthenticated outside the interceptor, got %v", err)
+	}
+
+	// Callers get a fixed message; the server keeps the cause.
+	_, err = serve(m, incoming(encoded+"x"))
+	if msg := status.Convert(err).Message(); msg != "securecookie: invalid value" {
+		t.Fatalf("Expected a fixed message, got %q", msg)
+	}
+	var dst interface{}
+	if cause := s.Decode(DefaultKey, encoded+"x", &dst); errors.Unwrap(err) == nil || errors.Unwrap(err).Error() != cause.Error() {
+		t.Fatalf("Expected the cause %v to be kept, got %v", cause, errors.Unwrap(err))
+	}
+	_, err = serve(&Metadata{Codec: securecookie.New([]byte("12345"), nil).CheckRevocation(failingChecker{}, 0)}, incoming(encoded))
+	if msg := status.Convert(err).Message(); msg != "securecookie: temporarily unavailable" || !securecookie.Temporary(err) {
+		t.Fatalf("Expected a fixed message for a temporary error, got %q, %v", msg, err)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	m := &Metadata{Codec: securecookie.New([]byte("12345"), nil), Required: true}
+	ctx := send(t, m, m.WithValue(context.Background(), map[string]interface{}{"user": "alice"}))
+	handler := func(srv interface{}, ss googlegrpc.ServerStream) error {
+		v, err := m.FromContext(ss.Context())
+		if err != nil {
+			return err
+		}
+		if user := (*v.(*map[string]interface{}))["user"]; user != "alice" {
+			t.Errorf("Expected alice, got %v", user)
+		}
+		return nil
+	}
+	interceptor := m.StreamServerInterceptor()
+	if err := interceptor(nil, &stream{ctx: ctx}, &googlegrpc.StreamServerInfo{}, handler); err != nil {
+		t.Fatal(err)
+	}
+	if err := interceptor(nil, &stream{ctx: context.Background()}, &googlegrpc.StreamServerInfo{}, handler); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected Unauthenticated for a stream without a value, got %v", err)
+	}
+}
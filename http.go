@@ -0,0 +1,295 @@
+package securecookie
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	hostPrefix   = "__Host-"
+	securePrefix = "__Secure-"
+)
+
+// DowngradeError is returned by the HTTP helpers when writing a cookie would
+// silently weaken the security attributes of a cookie the client already
+// holds, e.g. behind a misconfigured proxy that reports plain HTTP.
+type DowngradeError struct {
+	// Name is the name of the cookie being written.
+	Name string
+	// Previous is the name of the cookie sent by the client with stronger
+	// semantics, which may be the same as Name.
+	Previous string
+}
+
+func (e *DowngradeError) Error() string {
+	if e.Name == e.Previous {
+		return "securecookie: cookie " + e.Name + " would be set without the Secure attribute"
+	}
+	return "securecookie: cookie " + e.Previous + " would be replaced by the weaker " + e.Name
+}
+
+// WriteCookie adds a Set-Cookie header for c to w, like http.SetCookie, after
+// checking that it does not downgrade a cookie the client sent with r. See
+// CheckDowngrade.
+func WriteCookie(w http.ResponseWriter, r *http.Request, c *http.Cookie) error {
+	if err := CheckDowngrade(r, c); err != nil {
+		return err
+	}
+	http.SetCookie(w, c)
+	return nil
+}
+
+// CheckDowngrade returns a *DowngradeError if setting c in response to r
+// would weaken a cookie previously issued with Secure or __Host-/__Secure-
+// prefix semantics.
+//
+// Browsers do not send cookie attributes back, so the check relies on
+// prefixes: c must be Secure if its name has a prefix, and it must keep the
+// prefix of any cookie the client sent for the same unprefixed name. r may be
+// nil, in which case only c itself is checked.
+func CheckDowngrade(r *http.Request, c *http.Cookie) error {
+	if cookiePrefix(c.Name) != "" && !c.Secure {
+		return &DowngradeError{Name: c.Name, Previous: c.Name}
+	}
+	if r == nil {
+		return nil
+	}
+	base := stripCookiePrefix(c.Name)
+	strength := prefixStrength(c.Name)
+	for _, rc := range r.Cookies() {
+		if stripCookiePrefix(rc.Name) != base {
+			continue
+		}
+		if prefixStrength(rc.Name) > strength || (prefixStrength(rc.Name) > 0 && !c.Secure) {
+			return &DowngradeError{Name: c.Name, Previous: rc.Name}
+		}
+	}
+	return nil
+}
+
+// cookiePrefix returns the security prefix of a cookie name, if any.
+func cookiePrefix(name string) string {
+	switch {
+	case strings.HasPrefix(name, hostPrefix):
+		return hostPrefix
+	case strings.HasPrefix(name, securePrefix):
+		return securePrefix
+	}
+	return ""
+}
+
+// stripCookiePrefix returns name without its security prefix.
+func stripCookiePrefix(name string) string {
+	return strings.TrimPrefix(name, cookiePrefix(name))
+}
+
+// prefixStrength orders prefixes by the guarantees they carry.
+func prefixStrength(name string) int {
+	switch cookiePrefix(name) {
+	case hostPrefix:
+		return 2
+	case securePrefix:
+		return 1
+	}
+	return 0
+}
+
+// Proxies --------------------------------------------------------------------
+
+var errSameSiteNoneInsecure = Error{msg: "SameSite=None requires a secure connection"}
+
+// ProxyTrust decides whether the headers set by reverse proxies and load
+// balancers, X-Forwarded-Proto and Forwarded, can be trusted to describe the
+// original request. Applications behind a TLS-terminating proxy use it so the
+// Secure attribute reflects the client connection rather than the hop from
+// the proxy.
+//
+// The zero value trusts no proxy headers.
+type ProxyTrust struct {
+	// Proxies are the networks of trusted proxies. Forwarded headers are only
+	// used on requests whose remote address is in one of them.
+	Proxies []*net.IPNet
+	// All trusts forwarded headers from any remote address. Only use it when
+	// the application is unreachable except through the proxy.
+	All bool
+}
+
+// TrustProxies returns a ProxyTrust trusting the given networks in CIDR
+// notation, e.g. "10.0.0.0/8". It panics if a network cannot be parsed.
+func TrustProxies(cidrs ...string) *ProxyTrust {
+	p := &ProxyTrust{}
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		p.Proxies = append(p.Proxies, n)
+	}
+	return p
+}
+
+// IsTLS reports whether r was received over TLS, either directly or, if the
+// proxy that forwarded it is trusted, by the client connection to the proxy.
+// A nil ProxyTrust only looks at r itself.
+func (p *ProxyTrust) IsTLS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	if !p.trusted(r) {
+		return false
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		proto, _, _ = strings.Cut(proto, ",")
+		return strings.EqualFold(strings.TrimSpace(proto), "https")
+	}
+	if fwd := r.Header.Get("Forwarded"); fwd != "" {
+		first, _, _ := strings.Cut(fwd, ",")
+		for _, pair := range strings.Split(first, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			if strings.EqualFold(k, "proto") {
+				return strings.EqualFold(strings.Trim(v, `"`), "https")
+			}
+		}
+	}
+	return false
+}
+
+// ResolveAttributes sets the Secure attribute of c according to IsTLS, and
+// returns an error if c uses SameSite=None over an insecure connection, which
+// browsers reject.
+func (p *ProxyTrust) ResolveAttributes(r *http.Request, c *http.Cookie) error {
+	c.Secure = p.IsTLS(r)
+	if c.SameSite == http.SameSiteNoneMode && !c.Secure {
+		return errSameSiteNoneInsecure
+	}
+	return nil
+}
+
+// ClientIP returns the address of the client that sent r. If the proxy that
+// forwarded r is trusted, X-Forwarded-For, or Forwarded if it is absent, is
+// read from right to left, as every proxy appends the address it received the
+// request from, and the first address that is not a trusted proxy is
+// returned: the entries on its left are set by the client and may be forged.
+// With All, only addresses in Proxies are skipped. Otherwise, the remote address is returned. It returns nil if the address
+// does not parse.
+func (p *ProxyTrust) ClientIP(r *http.Request) net.IP {
+	ip := parseHost(r.RemoteAddr)
+	if !p.trusted(r) {
+		return ip
+	}
+	hops := forwardedFor(r.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		if ip = parseHost(hops[i]); ip == nil || !p.isProxy(ip) {
+			return ip
+		}
+	}
+	return ip
+}
+
+// forwardedFor returns the addresses of X-Forwarded-For, or of the for
+// parameters of Forwarded, across all the lines of the header, from the
+// client to the last proxy.
+func forwardedFor(h http.Header) []string {
+	var hops []string
+	if lines := h.Values("X-Forwarded-For"); len(lines) != 0 {
+		for _, line := range lines {
+			for _, hop := range strings.Split(line, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		return hops
+	}
+	for _, line := range h.Values("Forwarded") {
+		for _, elem := range strings.Split(line, ",") {
+			for _, pair := range strings.Split(elem, ";") {
+				k, v, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if strings.EqualFold(k, "for") {
+					hops = append(hops, strings.Trim(v, `"`))
+				}
+			}
+		}
+	}
+	return hops
+}
+
+// parseHost parses an IP address, with or without a port.
+func parseHost(addr string) net.IP {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return net.ParseIP(strings.Trim(addr, "[]"))
+}
+
+func (p *ProxyTrust) trusted(r *http.Request) bool {
+	if p == nil {
+		return false
+	}
+	if p.All {
+		return true
+	}
+	ip := parseHost(r.RemoteAddr)
+	return ip != nil && p.isProxy(ip)
+}
+
+// isProxy reports whether ip is in the networks of trusted proxies.
+func (p *ProxyTrust) isProxy(ip net.IP) bool {
+	for _, n := range p.Proxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Duplicates -----------------------------------------------------------------
+
+// RejectedCookie describes a cookie that was sent along with others of the
+// same name and failed to decode.
+type RejectedCookie struct {
+	// Index is the position of the cookie among those with the same name, in
+	// the order the client sent them.
+	Index int
+	Value string
+	Err   error
+}
+
+// DecodeFirstValid decodes the first valid cookie with the given name sent
+// with r into dst, and returns the candidates rejected before it.
+//
+// A client sends several cookies with the same name when they were set with
+// different Path or Domain attributes, typically most specific path first.
+// Decoding only the first one makes sessions "work on some pages"; this
+// helper tries them all and reports the stale duplicates, which can then be
+// cleared.
+//
+// If no cookie has the name it returns http.ErrNoCookie. If none is valid it
+// returns the rejected candidates along with their errors joined.
+func DecodeFirstValid(r *http.Request, codec Codec, name string, dst interface{}) ([]RejectedCookie, error) {
+	var (
+		rejected []RejectedCookie
+		errs     []error
+		index    int
+	)
+	codec, err := RequestCodec(codec, r)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range r.Cookies() {
+		if c.Name != name {
+			continue
+		}
+		err := codec.Decode(name, c.Value, dst)
+		if err == nil {
+			return rejected, nil
+		}
+		rejected = append(rejected, RejectedCookie{Index: index, Value: c.Value, Err: err})
+		errs = append(errs, err)
+		index++
+	}
+	if index == 0 {
+		return nil, http.ErrNoCookie
+	}
+	return rejected, errors.Join(errs...)
+}
@@ -0,0 +1,87 @@
+package securecookie
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteCookieDowngrade(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://example.com/", nil)
+	r.AddCookie(&http.Cookie{Name: "__Host-sid", Value: "v"})
+
+	tests := []struct {
+		cookie    *http.Cookie
+		downgrade bool
+	}{
+		{&http.Cookie{Name: "__Host-sid", Secure: true, Path: "/"}, false},
+		{&http.Cookie{Name: "__Host-sid", Secure: false, Path: "/"}, true},
+		{&http.Cookie{Name: "__Secure-sid", Secure: true}, true},
+		{&http.Cookie{Name: "sid", Secure: true}, true},
+		{&http.Cookie{Name: "other", Secure: false}, false},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		err := WriteCookie(w, r, tt.cookie)
+		var de *DowngradeError
+		if got := errors.As(err, &de); got != tt.downgrade {
+			t.Errorf("%d: expected downgrade %v, got %v", i, tt.downgrade, err)
+		}
+		if written := w.Header().Get("Set-Cookie") != ""; written == tt.downgrade {
+			t.Errorf("%d: expected cookie written %v", i, !tt.downgrade)
+		}
+	}
+}
+
+func TestProxyTrust(t *testing.T) {
+	p := TrustProxies("10.0.0.0/8")
+	tests := []struct {
+		remote string
+		header string
+		value  string
+		tls    bool
+	}{
+		{"10.1.2.3:1234", "X-Forwarded-Proto", "https", true},
+		{"10.1.2.3:1234", "X-Forwarded-Proto", "http", false},
+		{"10.1.2.3:1234", "Forwarded", `for=1.2.3.4;proto="https", proto=http`, true},
+		{"192.168.1.1:1234", "X-Forwarded-Proto", "https", false},
+	}
+	for i, tt := range tests {
+		r := httptest.NewRequest("GET", "http://example.com/", nil)
+		r.RemoteAddr = tt.remote
+		r.Header.Set(tt.header, tt.value)
+		if got := p.IsTLS(r); got != tt.tls {
+			t.Errorf("%d: expected IsTLS %v, got %v", i, tt.tls, got)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "http://example.com/", nil)
+	c := &http.Cookie{Name: "sid", SameSite: http.SameSiteNoneMode}
+	if err := p.ResolveAttributes(r, c); err != errSameSiteNoneInsecure {
+		t.Fatalf("Expected errSameSiteNoneInsecure, got %v", err)
+	}
+}
+
+func TestDecodeFirstValid(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	stale, _ := New([]byte("54321"), nil).Encode("sid", "stale")
+	valid, _ := s.Encode("sid", "valid")
+	r := httptest.NewRequest("GET", "http://example.com/", nil)
+	r.AddCookie(&http.Cookie{Name: "sid", Value: stale})
+	r.AddCookie(&http.Cookie{Name: "other", Value: "x"})
+	r.AddCookie(&http.Cookie{Name: "sid", Value: valid})
+
+	var dst string
+	rejected, err := DecodeFirstValid(r, s, "sid", &dst)
+	if err != nil || dst != "valid" {
+		t.Fatalf("Expected valid cookie, got %q, %v", dst, err)
+	}
+	if len(rejected) != 1 || rejected[0].Value != stale || rejected[0].Err == nil {
+		t.Fatalf("Expected stale cookie to be reported, got %+v", rejected)
+	}
+
+	if _, err = DecodeFirstValid(r, s, "missing", &dst); err != http.ErrNoCookie {
+		t.Fatalf("Expected http.ErrNoCookie, got %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package securecookie
+
+import "testing"
+
+func TestImpersonationCodec(t *testing.T) {
+	hashKey, blockKey := GenerateRandomKey(32), GenerateRandomKey(32)
+	if _, err := NewImpersonationCodec(hashKey, blockKey, nil); err != errAuditNotSet {
+		t.Fatalf("Expected errAuditNotSet, got %v", err)
+	}
+	var events []ImpersonationEvent
+	c, err := NewImpersonationCodec(hashKey, blockKey, func(e ImpersonationEvent) {
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := c.Mint("imp", Impersonation{Operator: "alice", Subject: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imp, err := c.Open("imp", token)
+	if err != nil || imp.Operator != "alice" || imp.Subject != "bob" {
+		t.Fatalf("Expected alice as bob, got %+v, %v", imp, err)
+	}
+
+	// Regular cookies with the same keys are not impersonation tokens.
+	regular, _ := New(hashKey, blockKey).Encode("imp", Impersonation{Operator: "eve", Subject: "bob"})
+	if _, err = c.Open("imp", regular); err == nil {
+		t.Fatal("Expected a regular cookie to be rejected")
+	}
+
+	if len(events) != 3 || events[0].Opened || !events[1].Opened || events[2].Err == nil {
+		t.Fatalf("Expected mint, open and failed open events, got %+v", events)
+	}
+}
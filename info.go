@@ -0,0 +1,100 @@
+package securecookie
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DecodeInfo describes how a value was validated, e.g. to log, refresh or
+// alert on it.
+type DecodeInfo struct {
+	// IssuedAt is the time the value was encoded, and Age the time elapsed
+	// since, both zero if the value has no timestamp.
+	IssuedAt time.Time
+	Age      time.Duration
+	// NotBefore and Expiry are the times embedded in the value, zero if
+	// unset; see NotBefore and ExpiresAt.
+	NotBefore time.Time
+	Expiry    time.Time
+	// TokenID is the token ID of the value, empty if unset; see TokenIDs.
+	TokenID string
+	// KeyID is the KeyID of the codec that decoded the value.
+	KeyID string
+	// CodecIndex is the index of that codec in DecodeMultiWithInfo, and 0
+	// otherwise.
+	CodecIndex int
+	// Serializer is the type of the serializer, e.g. "securecookie.JSONEncoder".
+	Serializer string
+	// FormatVersion is the wire format version, see FormatVersion, or 0 for
+	// values of the upstream package, see LegacyDecode.
+	FormatVersion int
+}
+
+// infoCodec is implemented by codecs that return a DecodeInfo.
+type infoCodec interface {
+	DecodeWithInfo(name, value string, dst interface{}) (*DecodeInfo, error)
+}
+
+// DecodeWithInfo decodes a cookie value like Decode and describes how it
+// was validated.
+func (s *SecureCookie) DecodeWithInfo(name, value string, dst interface{}) (*DecodeInfo, error) {
+	info := &valueInfo{}
+	if err := s.decode(name, value, dst, s.maxLength, info); err != nil {
+		s.sampleFailure(name, value, err)
+		return nil, err
+	}
+	d := &DecodeInfo{
+		KeyID:         s.KeyID(),
+		Serializer:    fmt.Sprintf("%T", s.sz),
+		FormatVersion: int(info.version),
+		TokenID:       info.tokenID,
+	}
+	if ts := info.timestamp; ts&timestampMillis != 0 {
+		d.IssuedAt = issuedAt(ts)
+		d.Age = time.Duration(s.timestampMillis()-d.IssuedAt.UnixMilli()) * time.Millisecond
+	} else if ts != 0 {
+		d.IssuedAt = issuedAt(ts)
+		d.Age = time.Duration(s.timestamp()-int64(ts)) * time.Second
+	}
+	if info.notBefore != 0 {
+		d.NotBefore = time.Unix(info.notBefore, 0)
+	}
+	if info.expiry != 0 {
+		d.Expiry = time.Unix(info.expiry, 0)
+	}
+	return d, nil
+}
+
+// DecodeMultiWithInfo decodes a cookie value like DecodeMulti and describes
+// how it was validated. Codecs that do not implement DecodeWithInfo, unlike
+// SecureCookie, only fill CodecIndex.
+func DecodeMultiWithInfo(name string, value string, dst interface{}, codecs ...Codec) (*DecodeInfo, error) {
+	if len(codecs) == 0 {
+		return nil, errNoCodecs
+	}
+	var errs []error
+	for i, codec := range codecs {
+		var info *DecodeInfo
+		var err error
+		if ic, ok := codec.(infoCodec); ok {
+			info, err = ic.DecodeWithInfo(name, value, dst)
+		} else {
+			info, err = &DecodeInfo{}, codec.Decode(name, value, dst)
+		}
+		if err == nil {
+			info.CodecIndex = i
+			return info, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// issuedAt returns the time of a timestamp read from an encoded value.
+func issuedAt(ts uint64) time.Time {
+	if ts&timestampMillis != 0 {
+		return time.UnixMilli(int64(ts &^ timestampMillis))
+	}
+	return time.Unix(int64(ts), 0)
+}
@@ -0,0 +1,44 @@
+package securecookie
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecodeWithInfo(t *testing.T) {
+	issued := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := issued
+	clock := ClockFunc(func() time.Time { return now })
+	old := New([]byte("12345"), nil).SetClock(clock)
+	s := New([]byte("67890"), nil).FormatVersion(2).TimestampPrecision(time.Millisecond).SetClock(clock)
+	encoded, err := s.EncodeWith("sid", "value", ExpiresAt(issued.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(1500 * time.Millisecond)
+	var dst string
+	info, err := DecodeMultiWithInfo("sid", encoded, &dst, old, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DecodeInfo{
+		IssuedAt:      issued,
+		Age:           1500 * time.Millisecond,
+		Expiry:        issued.Add(time.Hour),
+		KeyID:         s.KeyID(),
+		CodecIndex:    1,
+		Serializer:    "securecookie.JSONEncoder",
+		FormatVersion: 2,
+	}
+	if !info.IssuedAt.Equal(want.IssuedAt) || !info.Expiry.Equal(want.Expiry) {
+		t.Fatalf("Expected %v and %v, got %+v", want.IssuedAt, want.Expiry, info)
+	}
+	info.IssuedAt, info.Expiry = want.IssuedAt, want.Expiry
+	if *info != want {
+		t.Fatalf("Expected %+v, got %+v", want, *info)
+	}
+
+	if _, err := old.DecodeWithInfo("sid", encoded, &dst); err == nil {
+		t.Fatal("Expected failure decoding with another key")
+	}
+}
@@ -0,0 +1,63 @@
+package securecookie
+
+import (
+	"crypto/hmac"
+	"encoding/binary"
+	"hash"
+)
+
+// Key derivation -------------------------------------------------------------
+
+// pbkdf2 derives a key of keyLen bytes from password and salt as described in
+// RFC 8018, section 5.2.
+func pbkdf2(h func() hash.Hash, password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(h, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	var buf [4]byte
+	dk := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf[:], uint32(block))
+		prf.Write(buf[:4])
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = u[:0]
+			u = prf.Sum(u)
+			for x := range u {
+				t[x] ^= u[x]
+			}
+		}
+	}
+	return dk[:keyLen]
+}
+
+// hkdf derives a key of keyLen bytes from secret as described in RFC 5869.
+// keyLen must not exceed 255 times the hash size.
+func hkdf(h func() hash.Hash, secret, salt, info []byte, keyLen int) []byte {
+	// Extract.
+	extractor := hmac.New(h, salt)
+	extractor.Write(secret)
+	prk := extractor.Sum(nil)
+	// Expand.
+	expander := hmac.New(h, prk)
+	out := make([]byte, 0, keyLen+expander.Size())
+	var prev []byte
+	for counter := byte(1); len(out) < keyLen; counter++ {
+		expander.Reset()
+		expander.Write(prev)
+		expander.Write(info)
+		expander.Write([]byte{counter})
+		out = expander.Sum(out)
+		prev = out[len(out)-expander.Size():]
+	}
+	return out[:keyLen]
+}
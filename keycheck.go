@@ -0,0 +1,127 @@
+package securecookie
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// minHashKeyLength is the minimum recommended length of hash keys.
+const minHashKeyLength = 32
+
+// minKeyEntropyBits is the minimum estimated entropy of printable keys.
+const minKeyEntropyBits = 128
+
+var (
+	errKeyTooShort   = Error{msg: "key is shorter than 32 bytes"}
+	errKeyAllZero    = Error{msg: "key is all zeros"}
+	errKeyLowEntropy = Error{msg: "key is printable text with low entropy"}
+	errKeysRelated   = Error{msg: "hash key and block key are identical or related"}
+)
+
+// ValidateKeys checks the quality of the hash and block keys, e.g. to catch
+// keys like []byte("12345") before they reach production. Problems are:
+//
+//   - a hash key shorter than 32 bytes;
+//   - a key made only of zero bytes;
+//   - a key made only of printable ASCII characters with an estimated
+//     entropy below 128 bits, such as a password or a repeated pattern;
+//   - a block key equal to, a prefix of, or a constant XOR of the hash key.
+//
+// If warn is nil the check is strict: problems are returned by every call to
+// Encode and Decode. Otherwise warn is called once with the problems found
+// and the keys are used anyway.
+func (s *SecureCookie) ValidateKeys(warn func(error)) *SecureCookie {
+	s.strictKeys = warn == nil
+	err := checkKeys(s.hashKey, s.blockKey)
+	if err == nil {
+		return s
+	}
+	if warn == nil {
+		s.setKeyErr(err)
+	} else {
+		warn(err)
+	}
+	return s
+}
+
+// checkKeys returns the problems found with the given keys, joined.
+func checkKeys(hashKey, blockKey []byte) error {
+	var errs []error
+	if len(hashKey) < minHashKeyLength {
+		errs = append(errs, fmt.Errorf("%w: hash key has %d bytes", errKeyTooShort, len(hashKey)))
+	}
+	for _, k := range []struct {
+		name string
+		key  []byte
+	}{{"hash key", hashKey}, {"block key", blockKey}} {
+		if len(k.key) == 0 {
+			continue
+		}
+		if allZero(k.key) {
+			errs = append(errs, fmt.Errorf("%w: %s", errKeyAllZero, k.name))
+		} else if printable(k.key) && entropyBits(k.key) < minKeyEntropyBits {
+			errs = append(errs, fmt.Errorf("%w: %s", errKeyLowEntropy, k.name))
+		}
+	}
+	if relatedKeys(hashKey, blockKey) {
+		errs = append(errs, errKeysRelated)
+	}
+	return errors.Join(errs...)
+}
+
+// relatedKeys reports whether a and b differ by the same XOR mask over their
+// common length, which covers identical keys, one key being a prefix of the
+// other, and complemented keys.
+func relatedKeys(a, b []byte) bool {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	if n == 0 {
+		return false
+	}
+	d := a[0] ^ b[0]
+	for i := 1; i < n; i++ {
+		if a[i]^b[i] != d {
+			return false
+		}
+	}
+	return true
+}
+
+func allZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func printable(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// entropyBits estimates the entropy of b from the Shannon entropy of its
+// byte distribution. It is a rough upper bound, good enough to flag keys
+// typed by humans.
+func entropyBits(b []byte) float64 {
+	var counts [256]int
+	for _, c := range b {
+		counts[c]++
+	}
+	var perByte float64
+	for _, n := range counts {
+		if n > 0 {
+			p := float64(n) / float64(len(b))
+			perByte -= p * math.Log2(p)
+		}
+	}
+	return perByte * float64(len(b))
+}
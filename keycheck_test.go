@@ -0,0 +1,46 @@
+package securecookie
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateKeys(t *testing.T) {
+	related := GenerateRandomKey(32)
+	complemented := make([]byte, len(related))
+	for i, c := range related {
+		complemented[i] = ^c
+	}
+	tests := []struct {
+		hashKey, blockKey []byte
+		want              error
+	}{
+		{GenerateRandomKey(32), GenerateRandomKey(32), nil},
+		{[]byte("12345"), nil, errKeyTooShort},
+		{make([]byte, 32), nil, errKeyAllZero},
+		{GenerateRandomKey(32), make([]byte, 16), errKeyAllZero},
+		{[]byte("passwordpasswordpasswordpassword"), nil, errKeyLowEntropy},
+		{[]byte("k8Qz!p2#Lm9@xW4$rT7^vB1&nC6*hJ3(Y"), nil, nil},
+		{related, related, errKeysRelated},
+		{related, related[:16], errKeysRelated},
+		{related, complemented, errKeysRelated},
+	}
+	for i, tt := range tests {
+		s := New(tt.hashKey, tt.blockKey).ValidateKeys(nil)
+		if tt.want == nil && s.err != nil {
+			t.Errorf("%d: expected no error, got %v", i, s.err)
+		}
+		if tt.want != nil && !errors.Is(s.err, tt.want) {
+			t.Errorf("%d: expected %v, got %v", i, tt.want, s.err)
+		}
+	}
+
+	var warned error
+	s := New([]byte("12345"), nil).ValidateKeys(func(err error) { warned = err })
+	if !errors.Is(warned, errKeyTooShort) || s.err != nil {
+		t.Fatalf("Expected a warning only, got %v, %v", warned, s.err)
+	}
+	if _, err := s.Encode("sid", "value"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,27 @@
+package securecookie
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// KeyID returns a fingerprint of the hash key: the hex-encoded first 8 bytes
+// of its SHA-256 digest. It identifies the key in logs and during rotations
+// without revealing it.
+func (s *SecureCookie) KeyID() string {
+	return keyFingerprint(s.hashKey)
+}
+
+// BlockKeyID returns a fingerprint of the block key, like KeyID, or an empty
+// string if no block key is set.
+func (s *SecureCookie) BlockKeyID() string {
+	if len(s.blockKey) == 0 {
+		return ""
+	}
+	return keyFingerprint(s.blockKey)
+}
+
+func keyFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
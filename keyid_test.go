@@ -0,0 +1,24 @@
+package securecookie
+
+import "testing"
+
+func TestKeyID(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	// First 8 bytes of SHA-256("12345").
+	if id := s.KeyID(); id != "5994471abb01112a" {
+		t.Fatalf("Unexpected key ID %q", id)
+	}
+	if id := s.BlockKeyID(); id != "" {
+		t.Fatalf("Expected no block key ID, got %q", id)
+	}
+	if New([]byte("12345"), []byte("1234567890123456")).BlockKeyID() == "" {
+		t.Fatal("Expected a block key ID")
+	}
+
+	f := NewFailureSampler(1)
+	var dst string
+	_ = s.SampleFailures(f).Decode("sid", "invalid", &dst)
+	if samples := f.Samples(); len(samples) != 1 || samples[0].KeyID != s.KeyID() {
+		t.Fatalf("Expected the key ID in the sample, got %+v", samples)
+	}
+}
@@ -0,0 +1,438 @@
+package securecookie
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// KeyPhase describes where a key generation is in its rotation lifecycle.
+type KeyPhase int
+
+const (
+	// KeyPending keys are accepted for decoding but never used for encoding.
+	// Distribute a key as pending everywhere before promoting it, so that
+	// instances which have not yet seen the promotion still accept its output.
+	KeyPending KeyPhase = iota
+	// KeyActive is the single key used for encoding. It is also the first key
+	// tried when decoding.
+	KeyActive
+	// KeyRetired keys are accepted for decoding until they are removed.
+	KeyRetired
+)
+
+// String returns the name of the phase.
+func (p KeyPhase) String() string {
+	switch p {
+	case KeyPending:
+		return "pending"
+	case KeyActive:
+		return "active"
+	case KeyRetired:
+		return "retired"
+	}
+	return "unknown"
+}
+
+var (
+	errKeyringEmpty     = Error{msg: "keyring has no active key"}
+	errKeyIDEmpty       = Error{msg: "key id is empty"}
+	errKeyIDDuplicate   = Error{msg: "key id already exists in keyring"}
+	errKeyIDUnknown     = Error{msg: "key id not found in keyring"}
+	errKeyActiveRemoval = Error{msg: "the active key cannot be retired or removed"}
+)
+
+// KeyInfo is a snapshot of a key generation held by a Keyring. It never
+// contains key material.
+type KeyInfo struct {
+	ID          string
+	Phase       KeyPhase
+	CreatedAt   time.Time
+	ActivatedAt time.Time
+	RetiredAt   time.Time
+}
+
+// keyringEntry is a key generation and the codec built from it.
+type keyringEntry struct {
+	KeyInfo
+	hashKey  []byte
+	blockKey []byte
+	codec    *SecureCookie
+}
+
+// Keyring holds several generations of hash and block keys and tracks their
+// rotation phase. It implements Codec: values are encoded with the active key
+// and decoded with any key that has not been removed.
+//
+// A Keyring is safe for concurrent use.
+type Keyring struct {
+	mu        sync.RWMutex
+	entries   []*keyringEntry
+	configure func(*SecureCookie)
+}
+
+// NewKeyring returns an empty Keyring.
+func NewKeyring() *Keyring {
+	return &Keyring{}
+}
+
+// Configure sets a function applied to the SecureCookie built for every key
+// generation, including those already in the keyring. Use it to change the
+// default options, e.g. MaxAge or the serializer.
+func (k *Keyring) Configure(f func(*SecureCookie)) *Keyring {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.configure = f
+	for _, e := range k.entries {
+		e.codec = k.newCodec(e.hashKey, e.blockKey)
+	}
+	return k
+}
+
+// Add adds a key generation to the keyring. The first key added becomes
+// active; any other key is added as pending and must be promoted. The keys
+// are copied.
+func (k *Keyring) Add(id string, hashKey, blockKey []byte) error {
+	if id == "" {
+		return errKeyIDEmpty
+	}
+	if len(hashKey) == 0 {
+		return errHashKeyNotSet
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.find(id) != nil {
+		return errKeyIDDuplicate
+	}
+	now := time.Now().UTC()
+	hashKey, blockKey = cloneKey(hashKey), cloneKey(blockKey)
+	e := &keyringEntry{
+		KeyInfo:  KeyInfo{ID: id, Phase: KeyPending, CreatedAt: now},
+		hashKey:  hashKey,
+		blockKey: blockKey,
+		codec:    k.newCodec(hashKey, blockKey),
+	}
+	if k.active() == nil {
+		e.Phase = KeyActive
+		e.ActivatedAt = now
+	}
+	k.entries = append(k.entries, e)
+	return nil
+}
+
+// Promote makes the given key active. The previously active key is retired.
+func (k *Keyring) Promote(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	e := k.find(id)
+	if e == nil {
+		return errKeyIDUnknown
+	}
+	if e.Phase == KeyActive {
+		return nil
+	}
+	now := time.Now().UTC()
+	if prev := k.active(); prev != nil {
+		prev.Phase = KeyRetired
+		prev.RetiredAt = now
+	}
+	e.Phase = KeyActive
+	e.ActivatedAt = now
+	e.RetiredAt = time.Time{}
+	return nil
+}
+
+// Retire marks the given key as retired. The active key cannot be retired;
+// promote another key first.
+func (k *Keyring) Retire(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	e := k.find(id)
+	if e == nil {
+		return errKeyIDUnknown
+	}
+	if e.Phase == KeyActive {
+		return errKeyActiveRemoval
+	}
+	if e.Phase != KeyRetired {
+		e.Phase = KeyRetired
+		e.RetiredAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// Remove drops the given key from the keyring. Values encoded with it will no
+// longer decode. The active key cannot be removed.
+func (k *Keyring) Remove(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for i, e := range k.entries {
+		if e.ID != id {
+			continue
+		}
+		if e.Phase == KeyActive {
+			return errKeyActiveRemoval
+		}
+		k.entries = append(k.entries[:i], k.entries[i+1:]...)
+		return nil
+	}
+	return errKeyIDUnknown
+}
+
+// Keys returns a snapshot of the key generations in the keyring, in the order
+// they were added.
+func (k *Keyring) Keys() []KeyInfo {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	infos := make([]KeyInfo, len(k.entries))
+	for i, e := range k.entries {
+		infos[i] = e.KeyInfo
+	}
+	return infos
+}
+
+// Codecs returns the codecs of the keyring in decoding order: the active key
+// first, then pending keys, then retired keys.
+func (k *Keyring) Codecs() []Codec {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	codecs := make([]Codec, 0, len(k.entries))
+	for _, phase := range []KeyPhase{KeyActive, KeyPending, KeyRetired} {
+		for _, e := range k.entries {
+			if e.Phase == phase {
+				codecs = append(codecs, e.codec)
+			}
+		}
+	}
+	return codecs
+}
+
+// Encode encodes a cookie value using the active key.
+func (k *Keyring) Encode(name string, value interface{}) (string, error) {
+	k.mu.RLock()
+	var codec *SecureCookie
+	if e := k.active(); e != nil {
+		codec = e.codec
+	}
+	k.mu.RUnlock()
+	if codec == nil {
+		return "", errKeyringEmpty
+	}
+	return codec.Encode(name, value)
+}
+
+// Decode decodes a cookie value trying every key in the keyring.
+//
+// On error, may return a joined error of all failed attempts.
+func (k *Keyring) Decode(name, value string, dst interface{}) error {
+	return DecodeMulti(name, value, dst, k.Codecs()...)
+}
+
+// replace replaces the keys of k with those of other, keeping the
+// configuration of k.
+func (k *Keyring) replace(other *Keyring) {
+	other.mu.RLock()
+	entries := other.entries
+	other.mu.RUnlock()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.entries = make([]*keyringEntry, len(entries))
+	for i, e := range entries {
+		ee := *e
+		ee.codec = k.newCodec(e.hashKey, e.blockKey)
+		k.entries[i] = &ee
+	}
+}
+
+func (k *Keyring) newCodec(hashKey, blockKey []byte) *SecureCookie {
+	s := New(hashKey, blockKey)
+	if k.configure != nil {
+		k.configure(s)
+	}
+	return s
+}
+
+func (k *Keyring) find(id string) *keyringEntry {
+	for _, e := range k.entries {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+func (k *Keyring) active() *keyringEntry {
+	for _, e := range k.entries {
+		if e.Phase == KeyActive {
+			return e
+		}
+	}
+	return nil
+}
+
+// Persistence ----------------------------------------------------------------
+
+const (
+	keyringMagic      = "sckr"
+	keyringVersion    = 1
+	keyringSaltSize   = 16
+	keyringHeaderSize = len(keyringMagic) + 1 + 4 + keyringSaltSize
+)
+
+// keyringMaxIterations bounds the PBKDF2 iteration count read from a sealed
+// keyring, whose header is only authenticated once the key is derived, so
+// that a corrupted or tampered header cannot stall LoadKeyring.
+const keyringMaxIterations = 10000000
+
+// keyringIterations is the PBKDF2 iteration count used to seal keyrings. It
+// is stored in the sealed form, so changing it does not affect loading.
+// Tests lower it to keep the suite fast.
+var keyringIterations = 600000
+
+var (
+	errPassphraseEmpty   = Error{msg: "keyring passphrase is empty"}
+	errKeyringMalformed  = Error{msg: "sealed keyring is malformed"}
+	errKeyringOpenFailed = Error{msg: "sealed keyring could not be opened"}
+)
+
+// keyringState is the serialized form of a Keyring.
+type keyringState struct {
+	Keys []keyringStateEntry `json:"keys"`
+}
+
+type keyringStateEntry struct {
+	ID          string    `json:"id"`
+	Phase       KeyPhase  `json:"phase"`
+	CreatedAt   time.Time `json:"created_at"`
+	ActivatedAt time.Time `json:"activated_at,omitempty"`
+	RetiredAt   time.Time `json:"retired_at,omitempty"`
+	HashKey     []byte    `json:"hash_key"`
+	BlockKey    []byte    `json:"block_key,omitempty"`
+}
+
+// MarshalEncrypted returns the full rotation state of the keyring (keys, IDs,
+// timestamps and phases) sealed with a key derived from passphrase, suitable
+// for storing on disk or in a secret store. Use LoadKeyring to restore it.
+//
+// The key is derived using PBKDF2-HMAC-SHA256 with a random salt, and the
+// state is encrypted with AES-256-GCM.
+func (k *Keyring) MarshalEncrypted(passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errPassphraseEmpty
+	}
+	k.mu.RLock()
+	state := keyringState{Keys: make([]keyringStateEntry, len(k.entries))}
+	for i, e := range k.entries {
+		state.Keys[i] = keyringStateEntry{
+			ID:          e.ID,
+			Phase:       e.Phase,
+			CreatedAt:   e.CreatedAt,
+			ActivatedAt: e.ActivatedAt,
+			RetiredAt:   e.RetiredAt,
+			HashKey:     e.hashKey,
+			BlockKey:    e.blockKey,
+		}
+	}
+	k.mu.RUnlock()
+	plaintext, err := json.Marshal(state)
+	if err != nil {
+		return nil, Error{msg: err.Error()}
+	}
+	salt := GenerateRandomKey(keyringSaltSize)
+	if salt == nil {
+		return nil, errGeneratingIV
+	}
+	header := make([]byte, 0, keyringHeaderSize)
+	header = append(header, keyringMagic...)
+	header = append(header, keyringVersion)
+	header = binary.BigEndian.AppendUint32(header, uint32(keyringIterations))
+	header = append(header, salt...)
+	aead, err := keyringAEAD(passphrase, salt, keyringIterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := GenerateRandomKey(aead.NonceSize())
+	if nonce == nil {
+		return nil, errGeneratingIV
+	}
+	out := append(header, nonce...)
+	return aead.Seal(out, nonce, plaintext, header), nil
+}
+
+// LoadKeyring restores a keyring sealed with Keyring.MarshalEncrypted.
+//
+// The returned keyring has the default codec options; call Configure to change
+// them.
+func LoadKeyring(sealed, passphrase []byte) (*Keyring, error) {
+	if len(passphrase) == 0 {
+		return nil, errPassphraseEmpty
+	}
+	if len(sealed) < keyringHeaderSize ||
+		string(sealed[:len(keyringMagic)]) != keyringMagic ||
+		sealed[len(keyringMagic)] != keyringVersion {
+		return nil, errKeyringMalformed
+	}
+	header := sealed[:keyringHeaderSize]
+	iter := binary.BigEndian.Uint32(header[len(keyringMagic)+1:])
+	salt := header[keyringHeaderSize-keyringSaltSize:]
+	if iter == 0 || iter > keyringMaxIterations {
+		return nil, errKeyringMalformed
+	}
+	aead, err := keyringAEAD(passphrase, salt, int(iter))
+	if err != nil {
+		return nil, err
+	}
+	rest := sealed[keyringHeaderSize:]
+	if len(rest) < aead.NonceSize() {
+		return nil, errKeyringMalformed
+	}
+	nonce, ciphertext := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, errKeyringOpenFailed
+	}
+	var state keyringState
+	if err = json.Unmarshal(plaintext, &state); err != nil {
+		return nil, errKeyringMalformed
+	}
+	k := NewKeyring()
+	for _, s := range state.Keys {
+		if s.ID == "" || len(s.HashKey) == 0 || k.find(s.ID) != nil {
+			return nil, errKeyringMalformed
+		}
+		if s.Phase != KeyPending && s.Phase != KeyActive && s.Phase != KeyRetired {
+			return nil, errKeyringMalformed
+		}
+		if s.Phase == KeyActive && k.active() != nil {
+			return nil, errKeyringMalformed
+		}
+		k.entries = append(k.entries, &keyringEntry{
+			KeyInfo: KeyInfo{
+				ID:          s.ID,
+				Phase:       s.Phase,
+				CreatedAt:   s.CreatedAt,
+				ActivatedAt: s.ActivatedAt,
+				RetiredAt:   s.RetiredAt,
+			},
+			hashKey:  s.HashKey,
+			blockKey: s.BlockKey,
+			codec:    k.newCodec(s.HashKey, s.BlockKey),
+		})
+	}
+	return k, nil
+}
+
+// keyringAEAD derives the sealing key from passphrase and returns an
+// AES-256-GCM instance using it.
+func keyringAEAD(passphrase, salt []byte, iter int) (cipher.AEAD, error) {
+	key := pbkdf2(sha256.New, passphrase, salt, iter, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,380 @@
+package securecookie
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrKeyringConflict is returned by KeyringStore.Save when the stored state
+// was changed by someone else since it was loaded.
+var ErrKeyringConflict = Error{msg: "keyring was modified concurrently"}
+
+var errKeyringUpdateRetries = Error{msg: "keyring update gave up after repeated conflicts"}
+
+// keyringUpdateAttempts is how many times SharedKeyring.Update retries on
+// ErrKeyringConflict.
+const keyringUpdateAttempts = 5
+
+// KeyringStore persists the sealed state of a keyring (see
+// Keyring.MarshalEncrypted) so that several processes can share it.
+//
+// Versions implement optimistic locking: Load returns the current version,
+// or 0 if nothing has been stored yet, and Save must only store the state if
+// the stored version still equals the given one, incrementing it by one.
+// Otherwise Save returns ErrKeyringConflict.
+type KeyringStore interface {
+	Load(ctx context.Context) (sealed []byte, version int64, err error)
+	Save(ctx context.Context, sealed []byte, version int64) error
+}
+
+// KeyringNotifier can optionally be implemented by a KeyringStore that is able
+// to push change notifications, e.g. through pub/sub. SharedKeyring.Watch uses
+// it in addition to polling.
+type KeyringNotifier interface {
+	// Notify returns a channel that receives a value every time the stored
+	// state changes. The channel must be closed when ctx is done.
+	Notify(ctx context.Context) (<-chan struct{}, error)
+}
+
+// SharedKeyring is a Keyring whose rotation state is kept in a KeyringStore,
+// so that a horizontally scaled fleet shares a single set of keys. It
+// implements Codec using the most recently loaded state.
+//
+// A SharedKeyring is safe for concurrent use.
+type SharedKeyring struct {
+	store      KeyringStore
+	passphrase []byte
+
+	mu        sync.RWMutex
+	keyring   *Keyring
+	version   int64
+	configure func(*SecureCookie)
+}
+
+// NewSharedKeyring returns a SharedKeyring backed by store. The stored state
+// is sealed and opened using passphrase. Call Refresh or Watch to load it.
+func NewSharedKeyring(store KeyringStore, passphrase []byte) *SharedKeyring {
+	return &SharedKeyring{
+		store:      store,
+		passphrase: passphrase,
+		keyring:    NewKeyring(),
+	}
+}
+
+// Configure sets a function applied to every SecureCookie built from the
+// shared keys. See Keyring.Configure.
+func (s *SharedKeyring) Configure(f func(*SecureCookie)) *SharedKeyring {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configure = f
+	s.keyring.Configure(f)
+	return s
+}
+
+// Keyring returns the most recently loaded keyring. It must be treated as
+// read-only; use Update to change the shared state.
+func (s *SharedKeyring) Keyring() *Keyring {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keyring
+}
+
+// Refresh loads the shared state if it changed since it was last loaded.
+func (s *SharedKeyring) Refresh(ctx context.Context) error {
+	sealed, version, err := s.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	current := s.version
+	s.mu.RUnlock()
+	if version == current {
+		return nil
+	}
+	k, err := s.open(sealed, version)
+	if err != nil {
+		return err
+	}
+	s.swap(k, version)
+	return nil
+}
+
+// Update applies f to the latest shared state and stores the result, e.g. to
+// add or promote a key. If another process changes the state concurrently,
+// the state is reloaded and f is applied again, so f must not have side
+// effects besides modifying the keyring it is given.
+func (s *SharedKeyring) Update(ctx context.Context, f func(*Keyring) error) error {
+	for i := 0; i < keyringUpdateAttempts; i++ {
+		sealed, version, err := s.store.Load(ctx)
+		if err != nil {
+			return err
+		}
+		k, err := s.open(sealed, version)
+		if err != nil {
+			return err
+		}
+		if err = f(k); err != nil {
+			return err
+		}
+		if sealed, err = k.MarshalEncrypted(s.passphrase); err != nil {
+			return err
+		}
+		err = s.store.Save(ctx, sealed, version)
+		if errors.Is(err, ErrKeyringConflict) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		s.swap(k, version+1)
+		return nil
+	}
+	return errKeyringUpdateRetries
+}
+
+// Watch refreshes the shared state every interval, and whenever the store
+// reports a change if it implements KeyringNotifier, until ctx is done.
+// Refresh errors are passed to onError, which may be nil; the previously
+// loaded state stays in use.
+func (s *SharedKeyring) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	var notify <-chan struct{}
+	if n, ok := s.store.(KeyringNotifier); ok {
+		if ch, err := n.Notify(ctx); err != nil {
+			if onError != nil {
+				onError(err)
+			}
+		} else {
+			notify = ch
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case _, ok := <-notify:
+			if !ok {
+				notify = nil
+				continue
+			}
+		}
+		if err := s.Refresh(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// Encode encodes a cookie value using the active shared key.
+func (s *SharedKeyring) Encode(name string, value interface{}) (string, error) {
+	return s.Keyring().Encode(name, value)
+}
+
+// Decode decodes a cookie value trying every shared key.
+func (s *SharedKeyring) Decode(name, value string, dst interface{}) error {
+	return s.Keyring().Decode(name, value, dst)
+}
+
+func (s *SharedKeyring) open(sealed []byte, version int64) (*Keyring, error) {
+	var k *Keyring
+	if version == 0 {
+		k = NewKeyring()
+	} else {
+		var err error
+		if k, err = LoadKeyring(sealed, s.passphrase); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.RLock()
+	configure := s.configure
+	s.mu.RUnlock()
+	if configure != nil {
+		k.Configure(configure)
+	}
+	return k, nil
+}
+
+func (s *SharedKeyring) swap(k *Keyring, version int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if version < s.version {
+		return
+	}
+	s.keyring = k
+	s.version = version
+}
+
+// Postgres -------------------------------------------------------------------
+
+// PostgresKeyringSchema creates the table used by PostgresKeyringStore with
+// its default table name.
+const PostgresKeyringSchema = `CREATE TABLE IF NOT EXISTS securecookie_keyrings (
+	name    TEXT PRIMARY KEY,
+	state   BYTEA NOT NULL,
+	version BIGINT NOT NULL
+)`
+
+// PostgresKeyringStore is a KeyringStore backed by a PostgreSQL table; see
+// PostgresKeyringSchema. Any database/sql driver for PostgreSQL can be used.
+type PostgresKeyringStore struct {
+	DB *sql.DB
+	// Table is the table name. Default is "securecookie_keyrings".
+	Table string
+	// Name identifies the keyring, allowing one table to hold several.
+	Name string
+}
+
+// Load implements KeyringStore.
+func (p *PostgresKeyringStore) Load(ctx context.Context) ([]byte, int64, error) {
+	var (
+		sealed  []byte
+		version int64
+	)
+	query := fmt.Sprintf("SELECT state, version FROM %s WHERE name = $1", p.table())
+	err := p.DB.QueryRowContext(ctx, query, p.Name).Scan(&sealed, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, nil
+	}
+	return sealed, version, err
+}
+
+// Save implements KeyringStore.
+func (p *PostgresKeyringStore) Save(ctx context.Context, sealed []byte, version int64) error {
+	var (
+		res sql.Result
+		err error
+	)
+	if version == 0 {
+		query := fmt.Sprintf("INSERT INTO %s (name, state, version) VALUES ($1, $2, 1) "+
+			"ON CONFLICT (name) DO NOTHING", p.table())
+		res, err = p.DB.ExecContext(ctx, query, p.Name, sealed)
+	} else {
+		query := fmt.Sprintf("UPDATE %s SET state = $1, version = version + 1 "+
+			"WHERE name = $2 AND version = $3", p.table())
+		res, err = p.DB.ExecContext(ctx, query, sealed, p.Name, version)
+	}
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrKeyringConflict
+	}
+	return nil
+}
+
+func (p *PostgresKeyringStore) table() string {
+	if p.Table == "" {
+		return "securecookie_keyrings"
+	}
+	return p.Table
+}
+
+// Redis ----------------------------------------------------------------------
+
+// RedisScripter runs a Lua script on a Redis server. It is a small adapter
+// over the client library in use; with go-redis it is:
+//
+//	func (c adapter) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+//		return c.Client.Eval(ctx, script, keys, args...).Result()
+//	}
+type RedisScripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// RedisSubscriber optionally delivers messages published on a Redis channel.
+// The returned channel must be closed when ctx is done.
+type RedisSubscriber interface {
+	Subscribe(ctx context.Context, channel string) (<-chan string, error)
+}
+
+const (
+	redisKeyringLoad = `return redis.call('HMGET', KEYS[1], 'state', 'version')`
+	redisKeyringSave = `local v = tonumber(redis.call('HGET', KEYS[1], 'version') or '0')
+if v ~= tonumber(ARGV[2]) then return 0 end
+redis.call('HSET', KEYS[1], 'state', ARGV[1], 'version', v + 1)
+if ARGV[3] ~= '' then redis.call('PUBLISH', ARGV[3], v + 1) end
+return 1`
+)
+
+var errRedisReply = Error{msg: "unexpected reply from redis"}
+
+// RedisKeyringStore is a KeyringStore backed by a Redis hash. If Client also
+// implements RedisSubscriber, changes are published on Channel and the store
+// implements KeyringNotifier.
+type RedisKeyringStore struct {
+	Client RedisScripter
+	// Key is the Redis key holding the keyring.
+	Key string
+	// Channel is the pub/sub channel on which changes are announced. Leave
+	// empty to rely on polling only.
+	Channel string
+}
+
+// Load implements KeyringStore.
+func (r *RedisKeyringStore) Load(ctx context.Context) ([]byte, int64, error) {
+	reply, err := r.Client.Eval(ctx, redisKeyringLoad, []string{r.Key})
+	if err != nil {
+		return nil, 0, err
+	}
+	fields, ok := reply.([]interface{})
+	if !ok || len(fields) != 2 {
+		return nil, 0, errRedisReply
+	}
+	if fields[0] == nil || fields[1] == nil {
+		return nil, 0, nil
+	}
+	sealed, ok := fields[0].(string)
+	if !ok {
+		return nil, 0, errRedisReply
+	}
+	var version int64
+	if _, err = fmt.Sscan(fmt.Sprint(fields[1]), &version); err != nil {
+		return nil, 0, errRedisReply
+	}
+	return []byte(sealed), version, nil
+}
+
+// Save implements KeyringStore.
+func (r *RedisKeyringStore) Save(ctx context.Context, sealed []byte, version int64) error {
+	reply, err := r.Client.Eval(ctx, redisKeyringSave, []string{r.Key}, string(sealed), version, r.Channel)
+	if err != nil {
+		return err
+	}
+	if n, ok := reply.(int64); !ok || n != 1 {
+		return ErrKeyringConflict
+	}
+	return nil
+}
+
+// Notify implements KeyringNotifier when Client implements RedisSubscriber
+// and Channel is set.
+func (r *RedisKeyringStore) Notify(ctx context.Context) (<-chan struct{}, error) {
+	sub, ok := r.Client.(RedisSubscriber)
+	if !ok || r.Channel == "" {
+		return nil, nil
+	}
+	msgs, err := sub.Subscribe(ctx, r.Channel)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan struct{}, 1)
+	go func() {
+		defer close(ch)
+		for range msgs {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,75 @@
+package securecookie
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// memoryKeyringStore is a KeyringStore kept in memory, for testing.
+type memoryKeyringStore struct {
+	mu      sync.Mutex
+	sealed  []byte
+	version int64
+	// conflicts is the number of upcoming Save calls that fail.
+	conflicts int
+}
+
+func (m *memoryKeyringStore) Load(ctx context.Context) ([]byte, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sealed, m.version, nil
+}
+
+func (m *memoryKeyringStore) Save(ctx context.Context, sealed []byte, version int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.conflicts > 0 {
+		m.conflicts--
+		return ErrKeyringConflict
+	}
+	if version != m.version {
+		return ErrKeyringConflict
+	}
+	m.sealed, m.version = sealed, version+1
+	return nil
+}
+
+func TestSharedKeyring(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryKeyringStore{conflicts: 1}
+	passphrase := []byte("passphrase")
+	a := NewSharedKeyring(store, passphrase)
+	b := NewSharedKeyring(store, passphrase)
+
+	err := a.Update(ctx, func(k *Keyring) error {
+		return k.Add("k1", GenerateRandomKey(32), nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.version != 1 {
+		t.Fatalf("Expected version 1, got %d", store.version)
+	}
+	encoded, err := a.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dst string
+	if err = b.Decode("sid", encoded, &dst); err == nil {
+		t.Fatal("Expected decoding to fail before refresh")
+	}
+	if err = b.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected refreshed keyring to decode, got %q, %v", dst, err)
+	}
+
+	store.conflicts = keyringUpdateAttempts
+	err = b.Update(ctx, func(k *Keyring) error { return nil })
+	if err != errKeyringUpdateRetries {
+		t.Fatalf("Expected errKeyringUpdateRetries, got %v", err)
+	}
+}
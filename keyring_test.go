@@ -0,0 +1,152 @@
+package securecookie
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+func init() {
+	// Sealing with the production iteration count is slow, especially with
+	// the race detector.
+	keyringIterations = 1000
+}
+
+func TestKeyringRotation(t *testing.T) {
+	k := NewKeyring()
+	if _, err := k.Encode("sid", "value"); err != errKeyringEmpty {
+		t.Fatalf("Expected errKeyringEmpty, got %v", err)
+	}
+	if err := k.Add("k1", []byte("hash-key-1"), []byte("1234567890123456")); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Add("k1", []byte("hash-key-1"), nil); err != errKeyIDDuplicate {
+		t.Fatalf("Expected errKeyIDDuplicate, got %v", err)
+	}
+	old, err := k.Encode("sid", "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = k.Add("k2", []byte("hash-key-2"), []byte("6543210987654321")); err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Promote("k2"); err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Remove("k2"); err != errKeyActiveRemoval {
+		t.Fatalf("Expected errKeyActiveRemoval, got %v", err)
+	}
+	infos := k.Keys()
+	if infos[0].Phase != KeyRetired || infos[1].Phase != KeyActive {
+		t.Fatalf("Unexpected phases: %v, %v", infos[0].Phase, infos[1].Phase)
+	}
+
+	var dst string
+	if err = k.Decode("sid", old, &dst); err != nil || dst != "old" {
+		t.Fatalf("Expected retired key to decode, got %q, %v", dst, err)
+	}
+	if err = k.Remove("k1"); err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Decode("sid", old, &dst); err == nil {
+		t.Fatal("Expected removed key to fail decoding")
+	}
+}
+
+func TestKeyringMarshalEncrypted(t *testing.T) {
+	k := NewKeyring()
+	_ = k.Add("k1", GenerateRandomKey(32), GenerateRandomKey(32))
+	_ = k.Add("k2", GenerateRandomKey(32), nil)
+	encoded, err := k.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := k.MarshalEncrypted([]byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadKeyring(sealed, []byte("wrong")); err != errKeyringOpenFailed {
+		t.Fatalf("Expected errKeyringOpenFailed, got %v", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err = LoadKeyring(sealed, []byte("passphrase")); err != errKeyringOpenFailed {
+		t.Fatalf("Expected errKeyringOpenFailed, got %v", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+
+	loaded, err := LoadKeyring(sealed, []byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, got := k.Keys(), loaded.Keys()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d keys, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !got[i].CreatedAt.Equal(want[i].CreatedAt) || got[i].ID != want[i].ID || got[i].Phase != want[i].Phase {
+			t.Errorf("Expected %+v, got %+v", want[i], got[i])
+		}
+	}
+	var dst string
+	if err = loaded.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected loaded keyring to decode, got %q, %v", dst, err)
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	dk := pbkdf2(sha256.New, []byte("password"), []byte("salt"), 2, 32)
+	want := "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"
+	if hex.EncodeToString(dk) != want {
+		t.Fatalf("Expected %s, got %x", want, dk)
+	}
+}
+
+func TestHKDF(t *testing.T) {
+	// RFC 5869, test case 1.
+	ikm, _ := hex.DecodeString("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	okm := hkdf(sha256.New, ikm, salt, info, 42)
+	want := "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"
+	if hex.EncodeToString(okm) != want {
+		t.Fatalf("Expected %s, got %x", want, okm)
+	}
+}
+
+func TestLoadKeyringMalformed(t *testing.T) {
+	hashKey := GenerateRandomKey(32)
+	k := NewKeyring()
+	_ = k.Add("k1", hashKey, nil)
+	encoded, _ := k.Encode("sid", "value")
+	wipe(hashKey)
+	sealed, err := k.MarshalEncrypted([]byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadKeyring(sealed, []byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err = loaded.Decode("sid", encoded, &dst); err != nil {
+		t.Fatalf("Expected the keys to be copied by Add, got %v", err)
+	}
+
+	// An iteration count over the maximum is rejected before deriving keys.
+	tampered := append([]byte(nil), sealed...)
+	binary.BigEndian.PutUint32(tampered[len(keyringMagic)+1:], math.MaxUint32)
+	if _, err = LoadKeyring(tampered, []byte("passphrase")); err != errKeyringMalformed {
+		t.Fatalf("Expected errKeyringMalformed, got %v", err)
+	}
+
+	_ = k.Add("k2", GenerateRandomKey(32), nil)
+	k.entries[1].Phase = KeyPhase(7)
+	sealed, _ = k.MarshalEncrypted([]byte("passphrase"))
+	if _, err = LoadKeyring(sealed, []byte("passphrase")); err != errKeyringMalformed {
+		t.Fatalf("Expected errKeyringMalformed for an unknown phase, got %v", err)
+	}
+}
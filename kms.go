@@ -0,0 +1,362 @@
+package securecookie
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KMSClient is the subset of the AWS KMS API used by KMSCodec. It is a small
+// adapter over the SDK in use; with aws-sdk-go-v2 it wraps GenerateDataKey
+// (with KeySpec AES_256) and Decrypt. Other key managers offering data keys,
+// such as VaultTransit, can implement it too.
+type KMSClient interface {
+	// GenerateDataKey returns a new 32 byte data key in plaintext and
+	// encrypted under the given KMS key.
+	GenerateDataKey(ctx context.Context, keyID string) (plaintext, ciphertext []byte, err error)
+	// Decrypt returns the plaintext of a data key encrypted by KMS. If KMS
+	// rejects the ciphertext, e.g. with InvalidCiphertextException or
+	// AccessDeniedException, the error must wrap ErrKMSKeyRejected; other
+	// errors are taken as transient.
+	Decrypt(ctx context.Context, ciphertext []byte) (plaintext []byte, err error)
+}
+
+const (
+	kmsDefaultTTL       = 5 * time.Minute
+	kmsDefaultCacheSize = 1024
+	kmsDefaultTimeout   = 10 * time.Second
+	kmsSeparator        = "."
+	// kmsFailureTTL is for how long a data key KMS rejected is not sent to
+	// KMS again.
+	kmsFailureTTL = time.Minute
+	// The default limit of calls to Decrypt, see DecryptRate.
+	kmsDefaultDecryptRate  = 10
+	kmsDefaultDecryptBurst = 50
+)
+
+var (
+	errKMSValueMalformed = Error{msg: "kms envelope is malformed"}
+	errKMSRateLimited    = Error{msg: "kms decrypt rate exceeded"}
+	errKMSUnavailable    = Error{msg: "kms call failed"}
+
+	// ErrKMSKeyRejected is wrapped by the errors of KMSClient.Decrypt when
+	// KMS rejects a data key, as opposed to failing to answer.
+	ErrKMSKeyRejected = Error{msg: "kms rejected the data key"}
+)
+
+// KMSCodec is a Codec using envelope encryption with keys managed by AWS KMS.
+//
+// Values are authenticated and encrypted with keys derived from a KMS data
+// key. The encrypted data key is carried along with the value, so any
+// instance with kms:Decrypt permission on the KMS key can decode it. Data keys
+// are cached in plaintext for a limited time, so KMS is not called on every
+// request.
+//
+// The data key of a value is decrypted before the value is authenticated, so
+// anyone can make a KMSCodec call KMS by sending values with made up data
+// keys. To bound the cost and latency this adds, data keys KMS rejected are
+// remembered for a minute, and calls to decrypt data keys that
+// are not cached are rate limited, see DecryptRate. Legitimate values only
+// need a call per data key and TTL: one per instance encoding values.
+//
+// Other errors of KMS, e.g. throttling, network errors or timeouts, are not
+// remembered and are reported by Temporary.
+//
+// A KMSCodec is safe for concurrent use.
+type KMSCodec struct {
+	client    KMSClient
+	keyID     string
+	ttl       time.Duration
+	timeout   time.Duration
+	stale     time.Duration
+	configure func(*SecureCookie)
+	now       func() time.Time
+
+	mu         sync.Mutex
+	current    *kmsDataKey
+	cache      map[string]*kmsDataKey
+	refreshing map[string]bool
+	failures   map[string]kmsFailure
+	// Token bucket limiting calls to Decrypt.
+	rate, burst, tokens float64
+	last                time.Time
+}
+
+// kmsFailure is an error of KMS decrypting a data key.
+type kmsFailure struct {
+	err     error
+	expires time.Time
+}
+
+// kmsDataKey is a decrypted data key and the codec derived from it.
+type kmsDataKey struct {
+	wrapped string
+	codec   *SecureCookie
+	expires time.Time
+}
+
+// NewKMSCodec returns a KMSCodec that generates data keys under the given KMS
+// key ID, ARN or alias.
+func NewKMSCodec(client KMSClient, keyID string) *KMSCodec {
+	return &KMSCodec{
+		client:     client,
+		keyID:      keyID,
+		ttl:        kmsDefaultTTL,
+		timeout:    kmsDefaultTimeout,
+		now:        time.Now,
+		cache:      make(map[string]*kmsDataKey),
+		refreshing: make(map[string]bool),
+		failures:   make(map[string]kmsFailure),
+		rate:       kmsDefaultDecryptRate,
+		burst:      kmsDefaultDecryptBurst,
+		tokens:     kmsDefaultDecryptBurst,
+	}
+}
+
+// TTL sets for how long a plaintext data key is cached, both for encoding
+// new values and for decoding values carrying that data key.
+//
+// Default is 5 minutes.
+func (k *KMSCodec) TTL(value time.Duration) *KMSCodec {
+	k.ttl = value
+	return k
+}
+
+// Timeout sets the timeout of calls to KMS, after which Encode or Decode
+// fails with an error reported by Temporary.
+//
+// Default is 10 seconds. 0 means no timeout.
+func (k *KMSCodec) Timeout(value time.Duration) *KMSCodec {
+	k.timeout = value
+	return k
+}
+
+// StaleWhileRevalidate allows data keys to be used for up to maxStale after
+// their TTL expired, while they are refreshed in the background. Without it,
+// all requests arriving when a popular data key expires wait for KMS, and all
+// fail if KMS is briefly unavailable.
+//
+// Default is 0 (expired data keys are refreshed synchronously).
+func (k *KMSCodec) StaleWhileRevalidate(maxStale time.Duration) *KMSCodec {
+	k.stale = maxStale
+	return k
+}
+
+// DecryptRate limits the calls to KMS decrypting data keys that are not
+// cached to perSecond on average, with bursts of up to burst calls. Values
+// over the limit fail with an error reported by Temporary.
+//
+// Default is 10 per second with bursts of 50. 0 disables the limit.
+func (k *KMSCodec) DecryptRate(perSecond float64, burst int) *KMSCodec {
+	k.rate, k.burst, k.tokens = perSecond, float64(burst), float64(burst)
+	return k
+}
+
+// Configure sets a function applied to the SecureCookie derived from every
+// data key. Use it to change the default options, e.g. MaxAge.
+func (k *KMSCodec) Configure(f func(*SecureCookie)) *KMSCodec {
+	k.configure = f
+	return k
+}
+
+// Encode encodes a cookie value using the current data key, generating a new
+// one through KMS if it expired.
+func (k *KMSCodec) Encode(name string, value interface{}) (string, error) {
+	dk, err := k.dataKey()
+	if err != nil {
+		return "", err
+	}
+	encoded, err := dk.codec.Encode(name, value)
+	if err != nil {
+		return "", err
+	}
+	return dk.wrapped + kmsSeparator + encoded, nil
+}
+
+// Decode decodes a cookie value, decrypting its data key through KMS unless
+// it is cached.
+func (k *KMSCodec) Decode(name, value string, dst interface{}) error {
+	wrapped, encoded, ok := strings.Cut(value, kmsSeparator)
+	if !ok || wrapped == "" {
+		return errKMSValueMalformed
+	}
+	dk, err := k.unwrap(wrapped)
+	if err != nil {
+		return err
+	}
+	return dk.codec.Decode(name, encoded, dst)
+}
+
+// dataKey returns the data key used for encoding.
+func (k *KMSCodec) dataKey() (*kmsDataKey, error) {
+	now := k.now()
+	k.mu.Lock()
+	dk := k.current
+	if dk != nil && now.Before(dk.expires) {
+		k.mu.Unlock()
+		return dk, nil
+	}
+	if dk != nil && k.usableStale(dk, now) {
+		// The empty string never is a wrapped key, so it marks the refresh
+		// of the current data key.
+		if !k.refreshing[""] {
+			k.refreshing[""] = true
+			go func() {
+				_, _ = k.generate()
+				k.mu.Lock()
+				delete(k.refreshing, "")
+				k.mu.Unlock()
+			}()
+		}
+		k.mu.Unlock()
+		return dk, nil
+	}
+	k.mu.Unlock()
+	return k.generate()
+}
+
+// generate creates a new data key through KMS and makes it current.
+func (k *KMSCodec) generate() (*kmsDataKey, error) {
+	ctx, cancel := backgroundContext(k.timeout)
+	defer cancel()
+	plaintext, ciphertext, err := k.client.GenerateDataKey(ctx, k.keyID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errKMSUnavailable, err)
+	}
+	now := k.now()
+	dk := k.newDataKey(base64.RawURLEncoding.EncodeToString(ciphertext), plaintext, now)
+	k.mu.Lock()
+	k.current = dk
+	k.store(dk, now)
+	k.mu.Unlock()
+	return dk, nil
+}
+
+// unwrap returns the data key for the given encrypted data key.
+func (k *KMSCodec) unwrap(wrapped string) (*kmsDataKey, error) {
+	now := k.now()
+	k.mu.Lock()
+	dk, ok := k.cache[wrapped]
+	if ok && now.Before(dk.expires) {
+		k.mu.Unlock()
+		return dk, nil
+	}
+	if ok && k.usableStale(dk, now) {
+		if !k.refreshing[wrapped] {
+			k.refreshing[wrapped] = true
+			go func() {
+				_, _ = k.decrypt(wrapped)
+				k.mu.Lock()
+				delete(k.refreshing, wrapped)
+				k.mu.Unlock()
+			}()
+		}
+		k.mu.Unlock()
+		return dk, nil
+	}
+	if f, ok := k.failures[wrapped]; ok && now.Before(f.expires) {
+		k.mu.Unlock()
+		return nil, f.err
+	}
+	if !k.allowDecrypt(now) {
+		k.mu.Unlock()
+		return nil, errKMSRateLimited
+	}
+	k.mu.Unlock()
+	return k.decrypt(wrapped)
+}
+
+// allowDecrypt reports whether the rate limit allows a call to Decrypt, and
+// counts it. It must be called with k.mu held.
+func (k *KMSCodec) allowDecrypt(now time.Time) bool {
+	if k.rate <= 0 {
+		return true
+	}
+	if !k.last.IsZero() {
+		k.tokens += now.Sub(k.last).Seconds() * k.rate
+		if k.tokens > k.burst {
+			k.tokens = k.burst
+		}
+	}
+	k.last = now
+	if k.tokens < 1 {
+		return false
+	}
+	k.tokens--
+	return true
+}
+
+// decrypt decrypts a data key through KMS and caches it, or caches the error
+// if KMS rejected it.
+func (k *KMSCodec) decrypt(wrapped string) (*kmsDataKey, error) {
+	ciphertext, err := base64.RawURLEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, errKMSValueMalformed
+	}
+	ctx, cancel := backgroundContext(k.timeout)
+	defer cancel()
+	plaintext, err := k.client.Decrypt(ctx, ciphertext)
+	now := k.now()
+	if err != nil && !errors.Is(err, ErrKMSKeyRejected) {
+		return nil, fmt.Errorf("%w: %v", errKMSUnavailable, err)
+	}
+	if err != nil {
+		k.mu.Lock()
+		if len(k.failures) >= kmsDefaultCacheSize {
+			for w, f := range k.failures {
+				if !now.Before(f.expires) {
+					delete(k.failures, w)
+				}
+			}
+			if len(k.failures) >= kmsDefaultCacheSize {
+				k.failures = make(map[string]kmsFailure)
+			}
+		}
+		k.failures[wrapped] = kmsFailure{err: err, expires: now.Add(kmsFailureTTL)}
+		k.mu.Unlock()
+		return nil, err
+	}
+	dk := k.newDataKey(wrapped, plaintext, now)
+	k.mu.Lock()
+	k.store(dk, now)
+	k.mu.Unlock()
+	return dk, nil
+}
+
+// usableStale reports whether an expired data key may still be used.
+func (k *KMSCodec) usableStale(dk *kmsDataKey, now time.Time) bool {
+	return k.stale > 0 && now.Before(dk.expires.Add(k.stale))
+}
+
+// store caches dk, evicting expired entries when the cache is full. It must
+// be called with k.mu held.
+func (k *KMSCodec) store(dk *kmsDataKey, now time.Time) {
+	if len(k.cache) >= kmsDefaultCacheSize {
+		for w, e := range k.cache {
+			if !now.Before(e.expires) {
+				delete(k.cache, w)
+			}
+		}
+		if len(k.cache) >= kmsDefaultCacheSize {
+			k.cache = make(map[string]*kmsDataKey)
+		}
+	}
+	k.cache[dk.wrapped] = dk
+}
+
+// newDataKey derives independent hash and block keys from a plaintext data
+// key and builds the codec using them.
+func (k *KMSCodec) newDataKey(wrapped string, plaintext []byte, now time.Time) *kmsDataKey {
+	hashKey := hkdf(sha256.New, plaintext, nil, []byte("securecookie kms hash"), 32)
+	blockKey := hkdf(sha256.New, plaintext, nil, []byte("securecookie kms block"), 32)
+	s := New(hashKey, blockKey)
+	if k.configure != nil {
+		k.configure(s)
+	}
+	return &kmsDataKey{wrapped: wrapped, codec: s, expires: now.Add(k.ttl)}
+}
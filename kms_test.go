@@ -0,0 +1,197 @@
+package securecookie
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeKMS wraps data keys with a local AES key and counts calls.
+type fakeKMS struct {
+	block              cipher.Block
+	generated, decrypt int
+	err                error
+	// hang makes Decrypt wait for its context to be done.
+	hang bool
+}
+
+func (f *fakeKMS) GenerateDataKey(ctx context.Context, keyID string) ([]byte, []byte, error) {
+	f.generated++
+	plaintext := GenerateRandomKey(32)
+	ciphertext, err := encrypt(f.block, append([]byte(nil), plaintext...))
+	return plaintext, ciphertext, err
+}
+
+func (f *fakeKMS) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	f.decrypt++
+	if f.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return decrypt(f.block, append([]byte(nil), ciphertext...))
+}
+
+func TestKMSCodec(t *testing.T) {
+	block, _ := aes.NewCipher(GenerateRandomKey(32))
+	kms := &fakeKMS{block: block}
+	now := time.Now()
+	enc := NewKMSCodec(kms, "alias/cookies")
+	enc.now = func() time.Time { return now }
+	dec := NewKMSCodec(kms, "alias/cookies")
+	dec.now = enc.now
+
+	var values []string
+	for i := 0; i < 3; i++ {
+		encoded, err := enc.Encode("sid", "value")
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, encoded)
+	}
+	for _, v := range values {
+		var dst string
+		if err := dec.Decode("sid", v, &dst); err != nil || dst != "value" {
+			t.Fatalf("Expected to decode, got %q, %v", dst, err)
+		}
+	}
+	if kms.generated != 1 || kms.decrypt != 1 {
+		t.Fatalf("Expected data key to be cached, got %d generated, %d decrypted", kms.generated, kms.decrypt)
+	}
+
+	now = now.Add(kmsDefaultTTL)
+	if _, err := enc.Encode("sid", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if kms.generated != 2 {
+		t.Fatalf("Expected a new data key after the TTL, got %d generated", kms.generated)
+	}
+
+	var dst string
+	if err := dec.Decode("sid", "no-separator", &dst); err != errKMSValueMalformed {
+		t.Fatalf("Expected errKMSValueMalformed, got %v", err)
+	}
+}
+
+func TestKMSCodecStaleWhileRevalidate(t *testing.T) {
+	block, _ := aes.NewCipher(GenerateRandomKey(32))
+	kms := &fakeKMS{block: block}
+	var mu sync.Mutex
+	now := time.Now()
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	c := NewKMSCodec(kms, "alias/cookies").StaleWhileRevalidate(time.Minute)
+	c.now = clock
+	encoded, err := c.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	now = now.Add(kmsDefaultTTL + time.Second)
+	mu.Unlock()
+	var dst string
+	if err = c.Decode("sid", encoded, &dst); err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the background refresh.
+	for i := 0; i < 100; i++ {
+		c.mu.Lock()
+		n := len(c.refreshing)
+		c.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if kms.decrypt != 1 {
+		t.Fatalf("Expected 1 background decrypt, got %d", kms.decrypt)
+	}
+
+	// Beyond the stale window, the data key is decrypted synchronously.
+	mu.Lock()
+	now = now.Add(kmsDefaultTTL + 2*time.Minute)
+	mu.Unlock()
+	if err = c.Decode("sid", encoded, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if kms.decrypt != 2 {
+		t.Fatalf("Expected 2 decrypts, got %d", kms.decrypt)
+	}
+}
+
+func TestKMSCodecForgedDataKeys(t *testing.T) {
+	block, _ := aes.NewCipher(GenerateRandomKey(32))
+	kms := &fakeKMS{block: block, err: fmt.Errorf("%w: invalid ciphertext", ErrKMSKeyRejected)}
+	now := time.Now()
+	k := NewKMSCodec(kms, "alias/cookies").DecryptRate(1, 3)
+	k.now = func() time.Time { return now }
+	forged := func(i int) string {
+		return base64.RawURLEncoding.EncodeToString([]byte("forged"+strconv.Itoa(i))) + ".value"
+	}
+
+	var dst string
+	for i := 0; i < 2; i++ {
+		if err := k.Decode("sid", forged(0), &dst); err != kms.err {
+			t.Fatalf("Expected the KMS error, got %v", err)
+		}
+	}
+	if kms.decrypt != 1 {
+		t.Fatalf("Expected the failure to be remembered, got %d calls", kms.decrypt)
+	}
+	for i := 1; i < 3; i++ {
+		_ = k.Decode("sid", forged(i), &dst)
+	}
+	if err := k.Decode("sid", forged(3), &dst); err != errKMSRateLimited || !Temporary(err) {
+		t.Fatalf("Expected errKMSRateLimited, got %v", err)
+	}
+	if kms.decrypt != 3 {
+		t.Fatalf("Expected 3 calls to KMS, got %d", kms.decrypt)
+	}
+	now = now.Add(time.Second)
+	if err := k.Decode("sid", forged(3), &dst); err != kms.err {
+		t.Fatalf("Expected the KMS error after a second, got %v", err)
+	}
+	now = now.Add(kmsFailureTTL)
+	if err := k.Decode("sid", forged(0), &dst); err != kms.err || kms.decrypt != 5 {
+		t.Fatalf("Expected KMS to be called again, got %v and %d calls", err, kms.decrypt)
+	}
+}
+
+func TestKMSCodecTransientErrors(t *testing.T) {
+	block, _ := aes.NewCipher(GenerateRandomKey(32))
+	kms := &fakeKMS{block: block, err: errors.New("throttled")}
+	enc := NewKMSCodec(kms, "alias/cookies")
+	encoded, err := enc.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := NewKMSCodec(kms, "alias/cookies")
+	var dst string
+	if err := k.Decode("sid", encoded, &dst); !Temporary(err) {
+		t.Fatalf("Expected a temporary error, got %v", err)
+	}
+	kms.err = nil
+	if err := k.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected the data key to decode once KMS recovers, got %q, %v", dst, err)
+	}
+
+	kms.hang = true
+	k = NewKMSCodec(kms, "alias/cookies").Timeout(10 * time.Millisecond)
+	if err := k.Decode("sid", encoded, &dst); !Temporary(err) || !errors.Is(err, errKMSUnavailable) {
+		t.Fatalf("Expected a hung KMS to time out, got %v", err)
+	}
+}
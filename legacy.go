@@ -0,0 +1,125 @@
+package securecookie
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+)
+
+// legacyTimestampLen is the number of digits of the timestamps of upstream
+// values, which holds until 2286.
+const legacyTimestampLen = 10
+
+var errLegacyOption = Error{msg: "option is not supported by the legacy format"}
+
+// LegacyDecode makes Decode also accept values encoded by the upstream
+// github.com/gorilla/securecookie package, so that cookies survive a
+// migration from it. The keys, hash function, block cipher and serializer
+// must match those used upstream, where the serializer defaults to
+// encoding/gob, see NewGobEncoder. MaxAge and MinAge apply as usual.
+//
+// Upstream values are recognized by their layout, which values in the
+// format of this package cannot plausibly match. They carry no audience or
+// binding, so codecs with either, e.g. from Audience or WithFingerprint,
+// reject them.
+//
+// Default is false.
+func (s *SecureCookie) LegacyDecode(enabled bool) *SecureCookie {
+	s.legacyDecode = enabled
+	return s
+}
+
+// LegacyEncode makes Encode produce values in the upstream format, e.g. to
+// keep serving services not migrated yet. Options of the format of this
+// package, such as compression, stretched keys or format versions, fail
+// with an error. The value prefix, if any, is still added.
+//
+// Default is false.
+func (s *SecureCookie) LegacyEncode(enabled bool) *SecureCookie {
+	s.legacyEncode = enabled
+	return s
+}
+
+// encodeLegacy encodes a serialized value in the upstream format:
+//
+//	base64(timestamp|base64(data)|mac)
+//
+// where the MAC covers "name|timestamp|base64(data)".
+func (s *SecureCookie) encodeLegacy(name string, data []byte) ([]byte, error) {
+	if s.flags != 0 || s.compressor != nil || s.millis || s.version >= formatV2 || s.storage != nil || s.absoluteTimeout != 0 || s.tokenIDs || len(s.bound) != 0 || s.audience != "" {
+		return nil, errLegacyOption
+	}
+	var err error
+	if s.block != nil {
+		if data, err = encrypt(s.block, data); err != nil {
+			return nil, err
+		}
+	}
+	b := []byte(fmt.Sprintf("%s|%d|%s|", name, s.timestamp(), encode(base64.URLEncoding, data)))
+	mac := createMac(hmac.New(s.hashFunc, s.hashKey), b[:len(b)-1])
+	b = append(b, mac...)[len(name)+1:]
+	return encode(base64.URLEncoding, b), nil
+}
+
+// legacyParts splits a value in the upstream format into its timestamp, data
+// and MAC, or returns false if it does not have that layout.
+func (s *SecureCookie) legacyParts(value string) (b []byte, parts [][]byte, ok bool) {
+	b, err := base64.URLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, nil, false
+	}
+	parts = bytes.SplitN(b, []byte("|"), 3)
+	if len(parts) != 3 || len(parts[0]) != legacyTimestampLen || len(parts[2]) != s.hmacSize {
+		return nil, nil, false
+	}
+	for _, c := range parts[0] {
+		if c < '0' || c > '9' {
+			return nil, nil, false
+		}
+	}
+	return b, parts, true
+}
+
+// decodeLegacy decodes a value in the upstream format, whose parts were
+// returned by legacyParts. info may be nil.
+func (s *SecureCookie) decodeLegacy(name string, b []byte, parts [][]byte, dst interface{}, info *valueInfo) error {
+	if len(s.bound) != 0 || s.audience != "" {
+		return errLegacyOption
+	}
+	signed := append([]byte(name+"|"), b[:len(b)-len(parts[2])-1]...)
+	if err := verifyMac(hmac.New(s.hashFunc, s.hashKey), signed, parts[2]); err != nil {
+		return err
+	}
+	// Ten digits always parse.
+	ts, _ := strconv.ParseUint(string(parts[0]), 10, 64)
+	if err := s.checkAge(ts); err != nil {
+		return err
+	}
+	if info == nil {
+		info = &valueInfo{}
+	}
+	info.timestamp = ts
+	if s.revocation != nil {
+		if err := s.checkRevoked(info); err != nil {
+			return err
+		}
+	}
+	if s.consume != nil {
+		return errTokenIDMissing
+	}
+	data, err := decode(base64.URLEncoding, parts[1])
+	if err != nil {
+		return err
+	}
+	if s.block != nil {
+		if data, err = decrypt(s.block, data); err != nil {
+			return err
+		}
+	}
+	if err := s.sz.Deserialize(data, dst); err != nil {
+		return fmt.Errorf("%w: %v", errDeserializeFailed, err)
+	}
+	return s.checkPayload(dst)
+}
@@ -0,0 +1,109 @@
+package securecookie
+
+import (
+	"errors"
+	"testing"
+)
+
+// upstreamValue is the string "value" as encoded by github.com/gorilla/securecookie
+// with the hash key "12345", no block key, JSONEncoder, the name "sid" and the
+// timestamp 1700000000.
+const upstreamValue = "MTcwMDAwMDAwMHxJblpoYkhWbElnbz18gExFieWNP9Snsjre5x0k3gL-aFAlE1l__wcQXQRWtiM="
+
+// upstreamEncrypted is the string "secret" as encoded by
+// github.com/gorilla/securecookie v1.1.2 with the hash key "12345", the block
+// key "1234567890123456", JSONEncoder, the name "sid" and the timestamp
+// 1792119057.
+const upstreamEncrypted = "MTc5MjExOTA1N3xHRUF5S1kyMWc2cTVNRkhOdjhqY21kelJLbS0yQTZ2aFRnPT18fUr17scukZ9o02xENX3IyykcmSu2JN6l3cqgCr7ltFQ="
+
+func TestLegacyDecode(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	s.timeFunc = func() int64 { return 1700000060 }
+	var dst string
+	if err := s.Decode("sid", upstreamValue, &dst); err == nil {
+		t.Fatal("Expected upstream values to be rejected by default")
+	}
+	s.LegacyDecode(true)
+	if err := s.Decode("sid", upstreamValue, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected %q, got %q, %v", "value", dst, err)
+	}
+	if err := s.Decode("other", upstreamValue, &dst); err != ErrMacInvalid {
+		t.Fatalf("Expected ErrMacInvalid, got %v", err)
+	}
+	s.MaxAge(30)
+	if err := s.Decode("sid", upstreamValue, &dst); err != errTimestampExpired {
+		t.Fatalf("Expected errTimestampExpired, got %v", err)
+	}
+
+	// Values in the format of this package still decode.
+	encoded, err := s.Encode("sid", "current")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Decode("sid", encoded, &dst); err != nil || dst != "current" {
+		t.Fatalf("Expected %q, got %q, %v", "current", dst, err)
+	}
+}
+
+func TestLegacyDecodeUpstream(t *testing.T) {
+	s := New([]byte("12345"), []byte("1234567890123456")).LegacyDecode(true)
+	s.timeFunc = func() int64 { return 1792119060 }
+	var dst string
+	if err := s.Decode("sid", upstreamEncrypted, &dst); err != nil || dst != "secret" {
+		t.Fatalf("Expected %q, got %q, %v", "secret", dst, err)
+	}
+}
+
+func TestLegacyDecodeBound(t *testing.T) {
+	s := New([]byte("12345"), nil).LegacyDecode(true)
+	s.timeFunc = func() int64 { return 1700000060 }
+	var dst string
+	for _, c := range []*SecureCookie{s.WithFingerprint([]byte("fp")), s.Audience("api")} {
+		if err := c.Decode("sid", upstreamValue, &dst); err != errLegacyOption {
+			t.Fatalf("Expected errLegacyOption for a bound codec, got %v", err)
+		}
+	}
+}
+
+func TestLegacyEncode(t *testing.T) {
+	s := New([]byte("12345"), nil).LegacyEncode(true)
+	s.timeFunc = func() int64 { return 1700000000 }
+	encoded, err := s.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoded != upstreamValue {
+		t.Fatalf("Expected %q, got %q", upstreamValue, encoded)
+	}
+
+	// Upstream binds the full name, and values are encrypted before their
+	// base64 encoding.
+	s = New([]byte("12345"), []byte("1234567890123456")).LegacyEncode(true).LegacyDecode(true)
+	encoded, err = s.Encode("__Host-sid", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := s.Decode("__Host-sid", encoded, &dst); err != nil || dst != "secret" {
+		t.Fatalf("Expected %q, got %q, %v", "secret", dst, err)
+	}
+	if err := s.Decode("sid", encoded, &dst); err != ErrMacInvalid {
+		t.Fatalf("Expected ErrMacInvalid, got %v", err)
+	}
+
+	if _, err := s.Compress(true).Encode("sid", "value"); err != errLegacyOption {
+		t.Fatalf("Expected errLegacyOption, got %v", err)
+	}
+}
+
+func TestLegacyDecodePayloadLimits(t *testing.T) {
+	encoded, err := New([]byte("12345"), nil).LegacyEncode(true).Encode("sid", map[string]interface{}{"a": 1, "b": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New([]byte("12345"), nil).LegacyDecode(true).MaxPayloadKeys(1)
+	var dst map[string]interface{}
+	if err := s.Decode("sid", encoded, &dst); !errors.Is(err, errPayloadTooManyKeys) {
+		t.Fatalf("Expected errPayloadTooManyKeys, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package securecookie
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	errManifestDuplicate  = Error{msg: "cookie is declared twice"}
+	errManifestUndeclared = Error{msg: "cookie is not declared in the manifest"}
+	errManifestMissing    = Error{msg: "declared cookie is not registered"}
+	errManifestTTL        = Error{msg: "cookie max age exceeds its declared ttl"}
+	errManifestSize       = Error{msg: "cookie max length exceeds its size budget"}
+	errManifestEncryption = Error{msg: "cookie must be encrypted"}
+)
+
+// ManifestEntry declares a secure cookie used by an application.
+type ManifestEntry struct {
+	Name    string `json:"name"`
+	Purpose string `json:"purpose,omitempty"`
+	// TTL is the maximum lifetime of the cookie, in seconds. 0 means no
+	// budget.
+	TTL int64 `json:"ttl,omitempty"`
+	// MaxSize is the maximum length of the encoded value, in bytes. 0 means
+	// no budget.
+	MaxSize   int  `json:"max_size,omitempty"`
+	Encrypted bool `json:"encrypted,omitempty"`
+}
+
+// Manifest declares all the secure cookies used by an application. It is
+// meant to be kept in the source tree as JSON, e.g.
+//
+//	{"cookies": [
+//		{"name": "session", "purpose": "login", "ttl": 86400, "max_size": 2048, "encrypted": true}
+//	]}
+type Manifest struct {
+	Cookies []ManifestEntry `json:"cookies"`
+}
+
+// ParseManifest parses a JSON manifest.
+func ParseManifest(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, Error{msg: fmt.Sprintf("invalid manifest: %v", err)}
+	}
+	seen := make(map[string]bool, len(m.Cookies))
+	for _, e := range m.Cookies {
+		if seen[e.Name] {
+			return nil, fmt.Errorf("%w: %s", errManifestDuplicate, e.Name)
+		}
+		seen[e.Name] = true
+	}
+	return m, nil
+}
+
+// Registry holds the codecs of the secure cookies of an application, by
+// cookie name, so that they can be verified against a Manifest at startup.
+type Registry struct {
+	mu     sync.RWMutex
+	codecs map[string]*SecureCookie
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{codecs: make(map[string]*SecureCookie)}
+}
+
+// Register adds the codec of the named cookie. It returns an error if the
+// name is already registered.
+func (r *Registry) Register(name string, s *SecureCookie) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.codecs[name]; ok {
+		return fmt.Errorf("%w: %s", errManifestDuplicate, name)
+	}
+	r.codecs[name] = s
+	return nil
+}
+
+// Codec returns the codec registered for the named cookie, or nil.
+func (r *Registry) Codec(name string) *SecureCookie {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.codecs[name]
+}
+
+// Verify checks that the registered cookies are exactly those declared in m
+// and that each codec fits its declaration: a max age within the TTL, a max
+// length within the size budget, and a block key if encryption is required.
+// All problems found are returned, joined.
+func (r *Registry) Verify(m *Manifest) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var errs []error
+	declared := make(map[string]bool, len(m.Cookies))
+	for _, e := range m.Cookies {
+		declared[e.Name] = true
+		s, ok := r.codecs[e.Name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: %s", errManifestMissing, e.Name))
+			continue
+		}
+		if e.TTL > 0 && (s.maxAge == 0 || s.maxAge > e.TTL) {
+			errs = append(errs, fmt.Errorf("%w: %s has %d, ttl is %d", errManifestTTL, e.Name, s.maxAge, e.TTL))
+		}
+		if e.MaxSize > 0 && (s.maxLength == 0 || s.maxLength > e.MaxSize) {
+			errs = append(errs, fmt.Errorf("%w: %s has %d, budget is %d", errManifestSize, e.Name, s.maxLength, e.MaxSize))
+		}
+		if e.Encrypted && s.block == nil {
+			errs = append(errs, fmt.Errorf("%w: %s", errManifestEncryption, e.Name))
+		}
+	}
+	var undeclared []string
+	for name := range r.codecs {
+		if !declared[name] {
+			undeclared = append(undeclared, name)
+		}
+	}
+	sort.Strings(undeclared)
+	for _, name := range undeclared {
+		errs = append(errs, fmt.Errorf("%w: %s", errManifestUndeclared, name))
+	}
+	return errors.Join(errs...)
+}
+
+// ManifestChange is a difference between two manifests. Old is nil for an
+// added cookie and New is nil for a removed one.
+type ManifestChange struct {
+	Name string
+	Old  *ManifestEntry
+	New  *ManifestEntry
+}
+
+// String describes the change, e.g. for release notes.
+func (c ManifestChange) String() string {
+	switch {
+	case c.Old == nil:
+		return fmt.Sprintf("+ %s %+v", c.Name, *c.New)
+	case c.New == nil:
+		return fmt.Sprintf("- %s %+v", c.Name, *c.Old)
+	}
+	return fmt.Sprintf("~ %s %+v -> %+v", c.Name, *c.Old, *c.New)
+}
+
+// DiffManifests returns the cookies added, removed or changed between two
+// manifests, sorted by name.
+func DiffManifests(old, new *Manifest) []ManifestChange {
+	entries := func(m *Manifest) map[string]*ManifestEntry {
+		byName := make(map[string]*ManifestEntry, len(m.Cookies))
+		for i := range m.Cookies {
+			byName[m.Cookies[i].Name] = &m.Cookies[i]
+		}
+		return byName
+	}
+	before, after := entries(old), entries(new)
+	var changes []ManifestChange
+	for name, o := range before {
+		if n, ok := after[name]; !ok || *n != *o {
+			changes = append(changes, ManifestChange{Name: name, Old: o, New: n})
+		}
+	}
+	for name, n := range after {
+		if _, ok := before[name]; !ok {
+			changes = append(changes, ManifestChange{Name: name, New: n})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
@@ -0,0 +1,57 @@
+package securecookie
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegistryVerify(t *testing.T) {
+	m, err := ParseManifest([]byte(`{"cookies": [
+		{"name": "session", "ttl": 3600, "max_size": 2048, "encrypted": true},
+		{"name": "prefs"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRegistry()
+	_ = r.Register("session", New([]byte("12345"), []byte("1234567890123456")).MaxAge(3600).MaxLength(2048))
+	_ = r.Register("prefs", New([]byte("12345"), nil))
+	if err := r.Verify(m); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := r.Register("prefs", New([]byte("12345"), nil)); !errors.Is(err, errManifestDuplicate) {
+		t.Fatalf("Expected errManifestDuplicate, got %v", err)
+	}
+
+	r = NewRegistry()
+	_ = r.Register("session", New([]byte("12345"), nil))
+	_ = r.Register("debug", New([]byte("12345"), nil))
+	err = r.Verify(m)
+	for _, want := range []error{errManifestTTL, errManifestSize, errManifestEncryption, errManifestMissing, errManifestUndeclared} {
+		if !errors.Is(err, want) {
+			t.Errorf("Expected %v in %v", want, err)
+		}
+	}
+}
+
+func TestDiffManifests(t *testing.T) {
+	old := &Manifest{Cookies: []ManifestEntry{{Name: "a"}, {Name: "b", TTL: 60}}}
+	new := &Manifest{Cookies: []ManifestEntry{{Name: "b", TTL: 120}, {Name: "c"}}}
+	changes := DiffManifests(old, new)
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %v", changes)
+	}
+	if changes[0].Name != "a" || changes[0].New != nil {
+		t.Errorf("Expected a removed, got %v", changes[0])
+	}
+	if changes[1].Name != "b" || changes[1].Old.TTL != 60 || changes[1].New.TTL != 120 {
+		t.Errorf("Expected b changed, got %v", changes[1])
+	}
+	if changes[2].Name != "c" || changes[2].Old != nil {
+		t.Errorf("Expected c added, got %v", changes[2])
+	}
+	if len(DiffManifests(old, old)) != 0 {
+		t.Error("Expected no changes")
+	}
+}
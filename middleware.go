@@ -0,0 +1,352 @@
+package securecookie
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	errCookieNotDeclared = Error{msg: "cookie was not declared for this route"}
+	errHijackUnsupported = Error{msg: "response writer does not support hijacking"}
+)
+
+// CookieSpec declares a secure cookie to decode for a route.
+type CookieSpec struct {
+	Name  string
+	Codec Codec
+	// New returns a pointer to a new value to decode the cookie into, e.g.
+	// func() interface{} { return &Session{} }. Default is a pointer to a
+	// map[string]interface{}.
+	New func() interface{}
+	// Options are the attributes of the cookie when Middleware sets it
+	// again, see NewCookie.
+	Options *CookieOptions
+	// ClearInvalid makes CookieMiddleware expire the cookie when it fails to
+	// decode because of the value itself, e.g. after tampering, expiry or a
+	// gap in key rotation, so that the browser stops sending it. Other
+	// failures, like those of revocation or spill stores, KMS or unknown
+	// errors, do not clear it.
+	ClearInvalid bool
+	// InvalidRedirect makes CookieMiddleware redirect requests whose cookie
+	// is invalid, as for ClearInvalid, there, e.g. to a login page, instead
+	// of serving them.
+	InvalidRedirect string
+}
+
+// decodedCookie is the result of decoding a declared cookie. If load is set,
+// the cookie is decoded on first use.
+type decodedCookie struct {
+	value interface{}
+	err   error
+	// name is the name of the cookie read, which may have a prefix.
+	name string
+	once sync.Once
+	load func(d *decodedCookie)
+}
+
+// resolve decodes the cookie if it is decoded lazily and was not yet.
+func (d *decodedCookie) resolve() *decodedCookie {
+	if d.load != nil {
+		d.once.Do(func() { d.load(d) })
+	}
+	return d
+}
+
+type contextKey int
+
+const decodedCookiesKey contextKey = 0
+
+// decodeSpecs decodes the declared cookies of r and returns a context holding
+// the results, along with them.
+func decodeSpecs(r *http.Request, specs []CookieSpec) (context.Context, map[string]*decodedCookie) {
+	results := make(map[string]*decodedCookie, len(specs))
+	if prev, ok := r.Context().Value(decodedCookiesKey).(map[string]*decodedCookie); ok {
+		for name, d := range prev {
+			results[name] = d
+		}
+	}
+	for _, spec := range specs {
+		d := &decodedCookie{}
+		if spec.New != nil {
+			d.value = spec.New()
+		} else {
+			d.value = &map[string]interface{}{}
+		}
+		var c *http.Cookie
+		var codec Codec
+		if c, d.err = prefixedCookie(r, spec.Name); d.err == nil {
+			d.name = c.Name
+			if codec, d.err = RequestCodec(spec.Codec, r); d.err == nil {
+				d.err = codec.Decode(spec.Name, c.Value, d.value)
+			}
+		}
+		results[spec.Name] = d
+	}
+	return context.WithValue(r.Context(), decodedCookiesKey, results), results
+}
+
+// FromContext returns the value decoded for the named cookie by ScopedMux,
+// Middleware or PolicyMiddleware, as a pointer created by CookieSpec.New. The
+// error is http.ErrNoCookie if the request did not carry the cookie, the
+// decoding error if it was invalid, or an error if the cookie was not
+// declared for the route.
+func FromContext(ctx context.Context, name string) (interface{}, error) {
+	results, _ := ctx.Value(decodedCookiesKey).(map[string]*decodedCookie)
+	d, ok := results[name]
+	if !ok {
+		return nil, errCookieNotDeclared
+	}
+	d.resolve()
+	if d.err != nil {
+		return nil, d.err
+	}
+	return d.value, nil
+}
+
+// ScopedMux wraps an http.ServeMux so that secure cookies are declared per
+// route pattern, including Go 1.22 patterns with methods and wildcards. Each
+// request only pays for decoding the cookies declared for the route it
+// matches, which matters for large APIs where most routes never touch
+// sessions. Handlers read the results with FromContext.
+type ScopedMux struct {
+	mux *http.ServeMux
+}
+
+// NewScopedMux returns a ScopedMux registering routes on mux. If mux is nil,
+// a new ServeMux is used.
+func NewScopedMux(mux *http.ServeMux) *ScopedMux {
+	if mux == nil {
+		mux = http.NewServeMux()
+	}
+	return &ScopedMux{mux: mux}
+}
+
+// Handle registers handler for pattern, decoding the given cookies before
+// calling it.
+func (m *ScopedMux) Handle(pattern string, handler http.Handler, cookies ...CookieSpec) {
+	if len(cookies) == 0 {
+		m.mux.Handle(pattern, handler)
+		return
+	}
+	m.mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, _ := decodeSpecs(r, cookies)
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	}))
+}
+
+// HandleFunc registers handler for pattern, decoding the given cookies before
+// calling it.
+func (m *ScopedMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request), cookies ...CookieSpec) {
+	m.Handle(pattern, http.HandlerFunc(handler), cookies...)
+}
+
+// ServeHTTP dispatches the request to the handler whose pattern matches.
+func (m *ScopedMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// Middleware returns a middleware decoding the named cookies with codec once
+// per request into maps, see CookieMiddleware.
+func Middleware(codec Codec, names ...string) func(http.Handler) http.Handler {
+	specs := make([]CookieSpec, len(names))
+	for i, name := range names {
+		specs[i] = CookieSpec{Name: name, Codec: codec}
+	}
+	return CookieMiddleware(specs...)
+}
+
+// CookieMiddleware returns a middleware decoding the given cookies once per
+// request; handlers read the results with FromContext. If a handler modifies
+// a decoded value, the cookie is encoded and set again, with the attributes
+// of its spec, before the response headers are written. Values whose
+// encoding fails keep their previous cookie. Cookies that fail to decode are
+// left as they are, so clients keep sending them, unless their spec sets
+// ClearInvalid.
+func CookieMiddleware(specs ...CookieSpec) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, results := decodeSpecs(r, specs)
+			if clearInvalid(w, r, specs, results) {
+				return
+			}
+			rw := &resetWriter{ResponseWriter: w, r: r, specs: specs, snapshots: make(map[string][]byte)}
+			for _, spec := range specs {
+				if d := results[spec.Name]; d.err == nil {
+					rw.snapshots[spec.Name] = snapshot(spec.Codec, d.value)
+				}
+			}
+			rw.results = results
+			next.ServeHTTP(rw, r.WithContext(ctx))
+			rw.reset()
+		})
+	}
+}
+
+// clearInvalid expires the invalid cookies of specs that ask for it, and
+// redirects r if one of them has an InvalidRedirect. It reports whether it
+// redirected.
+func clearInvalid(w http.ResponseWriter, r *http.Request, specs []CookieSpec, results map[string]*decodedCookie) bool {
+	redirect := ""
+	for _, spec := range specs {
+		d := results[spec.Name]
+		if d.err == nil || !invalidValue(d.err) {
+			continue
+		}
+		if spec.ClearInvalid {
+			opts := *cookieOptions(spec.Options, 0)
+			opts.MaxAge, opts.AutoPrefix, opts.Partitioned = -1, false, false
+			if c, err := newCookie(d.name, "", &opts, time.Now()); err == nil {
+				http.SetCookie(w, c)
+			}
+		}
+		if redirect == "" {
+			redirect = spec.InvalidRedirect
+		}
+	}
+	if redirect == "" {
+		return false
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+	return true
+}
+
+// valueErrors are the decoding errors caused by the value itself.
+var valueErrors = []error{
+	ErrMacInvalid, errValueToDecodeTooSmall, errValueToDecodeTooLong, errNameIsUnexpected,
+	errTimestampTooNew, errTimestampExpired, errTimestampMissing, errValueExpired, errNotYetValid,
+	errDecryptionFailed, errBase64DecodeFailed, errDeserializeFailed, errValuePrefixUnknown,
+	errEncodingNotCanonical, errCompressionUnknown, errDecompressionFailed, errDecompressedTooLarge,
+	errPayloadTooManyKeys, errPayloadTooDeep, errTokenRevoked, errTokenConsumed, errTokenIDMissing,
+	errKeyStretchedMismatch, errKMSValueMalformed, ErrKMSKeyRejected, errSpillReference,
+	errSpillMismatch, errChunkCount, errChunkMissing, errChunkInvalid, errSplitKeyIDFormat,
+	errKeyIDUnknown,
+}
+
+// invalidValue reports whether a decoding error is caused by the value
+// itself, as opposed to a configuration error, a failure of a store or
+// service, or an unknown error, which may be transient.
+func invalidValue(err error) bool {
+	if Temporary(err) {
+		return false
+	}
+	var (
+		length    *LengthError
+		expired   *ExpiredError
+		notBefore *NotBeforeError
+	)
+	if errors.As(err, &length) || errors.As(err, &expired) || errors.As(err, &notBefore) {
+		return true
+	}
+	for _, target := range valueErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Temporary reports whether err is a failure of a store or service consulted
+// while decoding or encoding, like a revocation or spill store or KMS, or a
+// rate limit, so that the same value may succeed later.
+func Temporary(err error) bool {
+	for _, target := range []error{errRevocationFailed, errConsumeFailed, errSpillFailed, errKMSRateLimited, errKMSUnavailable} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// resetWriter sets cookies whose decoded values were modified before the
+// response headers are written.
+type resetWriter struct {
+	http.ResponseWriter
+	r         *http.Request
+	specs     []CookieSpec
+	results   map[string]*decodedCookie
+	snapshots map[string][]byte
+	done      bool
+}
+
+func (w *resetWriter) WriteHeader(code int) {
+	w.reset()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *resetWriter) Write(b []byte) (int, error) {
+	w.reset()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sets the modified cookies and flushes the wrapped writer, if it
+// implements http.Flusher.
+func (w *resetWriter) Flush() {
+	w.reset()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the wrapped writer does. Modified
+// cookies are not set on a hijacked connection.
+func (w *resetWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errHijackUnsupported
+	}
+	w.done = true
+	return h.Hijack()
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (w *resetWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *resetWriter) reset() {
+	if w.done {
+		return
+	}
+	w.done = true
+	for _, spec := range w.specs {
+		before, ok := w.snapshots[spec.Name]
+		if !ok {
+			continue
+		}
+		d := w.results[spec.Name]
+		if bytes.Equal(before, snapshot(spec.Codec, d.value)) {
+			continue
+		}
+		var c *http.Cookie
+		var err error
+		if s, ok := spec.Codec.(*SecureCookie); ok {
+			c, err = s.NewCookie(w.r, spec.Name, d.value, spec.Options)
+		} else {
+			var encoded string
+			if encoded, err = spec.Codec.Encode(spec.Name, d.value); err == nil {
+				c, err = newCookie(spec.Name, encoded, cookieOptions(spec.Options, 0), time.Now())
+			}
+		}
+		if err == nil {
+			http.SetCookie(w.ResponseWriter, c)
+		}
+	}
+}
+
+// snapshot returns the serialized form of v, to detect modifications.
+func snapshot(codec Codec, v interface{}) []byte {
+	var b []byte
+	if s, ok := codec.(*SecureCookie); ok && s.sz != nil {
+		b, _ = s.sz.Serialize(v)
+	} else {
+		b, _ = json.Marshal(v)
+	}
+	return b
+}
@@ -0,0 +1,266 @@
+package securecookie
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testSession struct {
+	User string
+}
+
+func TestScopedMux(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	encoded, _ := s.Encode("session", testSession{User: "alice"})
+	decodes := 0
+	counting := codecFunc{s, func() { decodes++ }}
+
+	m := NewScopedMux(nil)
+	m.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
+		v, err := FromContext(r.Context(), "session")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(v.(*testSession).User))
+	}, CookieSpec{Name: "session", Codec: counting, New: func() interface{} { return &testSession{} }})
+	m.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := FromContext(r.Context(), "session"); err != errCookieNotDeclared {
+			t.Errorf("Expected errCookieNotDeclared, got %v", err)
+		}
+	})
+
+	for _, path := range []string{"/account", "/health"} {
+		r := httptest.NewRequest("GET", path, nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: encoded})
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		if path == "/account" && w.Body.String() != "alice" {
+			t.Fatalf("Expected alice, got %q", w.Body.String())
+		}
+	}
+	if decodes != 1 {
+		t.Fatalf("Expected 1 decode, got %d", decodes)
+	}
+}
+
+// codecFunc calls f before every Decode.
+type codecFunc struct {
+	Codec
+	f func()
+}
+
+func (c codecFunc) Decode(name, value string, dst interface{}) error {
+	c.f()
+	return c.Codec.Decode(name, value, dst)
+}
+
+func TestMiddleware(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	encoded, _ := s.Encode("session", testSession{User: "alice"})
+	mw := CookieMiddleware(CookieSpec{Name: "session", Codec: s, New: func() interface{} { return &testSession{} }})
+	serve := func(rename bool) *http.Cookie {
+		h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v, err := FromContext(r.Context(), "session")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rename {
+				v.(*testSession).User = "bob"
+			}
+			_, _ = w.Write([]byte("ok"))
+		}))
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: encoded})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if cookies := w.Result().Cookies(); len(cookies) == 1 {
+			return cookies[0]
+		}
+		return nil
+	}
+	if c := serve(false); c != nil {
+		t.Fatalf("Expected no cookie for an unmodified value, got %v", c)
+	}
+	c := serve(true)
+	if c == nil || !c.HttpOnly {
+		t.Fatalf("Expected the modified value to be set again, got %v", c)
+	}
+	var dst testSession
+	if err := s.Decode("session", c.Value, &dst); err != nil || dst.User != "bob" {
+		t.Fatalf("Expected bob, got %+v, %v", dst, err)
+	}
+
+	// Middleware decodes into maps.
+	h := Middleware(s, "session")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, err := FromContext(r.Context(), "session")
+		if err != nil || (*v.(*map[string]interface{}))["User"] != "alice" {
+			t.Errorf("Expected a map, got %v, %v", v, err)
+		}
+	}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: encoded})
+	h.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestMiddlewareInvalidCookie(t *testing.T) {
+	now := time.Now()
+	s := New([]byte("12345"), nil).MaxAge(60).SetClock(ClockFunc(func() time.Time { return now }))
+	encoded, _ := s.Encode("session", testSession{User: "alice"})
+	now = now.Add(time.Hour)
+	for _, clear := range []bool{false, true} {
+		h := CookieMiddleware(CookieSpec{Name: "session", Codec: s, ClearInvalid: clear})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := FromContext(r.Context(), "session"); err != errTimestampExpired {
+				t.Errorf("Expected errTimestampExpired, got %v", err)
+			}
+		}))
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: encoded})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		cookies := w.Result().Cookies()
+		if !clear && len(cookies) != 0 {
+			t.Fatalf("Expected an expired cookie to be left as is, got %v", cookies)
+		}
+		if clear && (len(cookies) != 1 || cookies[0].MaxAge != -1) {
+			t.Fatalf("Expected an expired cookie to be cleared, got %v", cookies)
+		}
+	}
+}
+
+func TestMiddlewareClearInvalid(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	spec := CookieSpec{Name: "session", Codec: s, Options: &CookieOptions{Path: "/app"}, ClearInvalid: true}
+	serve := func(spec CookieSpec, value string) *httptest.ResponseRecorder {
+		served := false
+		h := CookieMiddleware(spec)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			served = true
+		}))
+		r := httptest.NewRequest("GET", "/app", nil)
+		if value != "" {
+			r.AddCookie(&http.Cookie{Name: "session", Value: value})
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if served == (w.Code == http.StatusSeeOther) {
+			t.Fatalf("Expected either a redirect or the handler, got %d, %v", w.Code, served)
+		}
+		return w
+	}
+
+	w := serve(spec, "tampered")
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge != -1 || cookies[0].Path != "/app" {
+		t.Fatalf("Expected the invalid cookie to be cleared, got %v", cookies)
+	}
+	if w := serve(spec, ""); len(w.Result().Cookies()) != 0 {
+		t.Fatalf("Expected no cookie to be cleared, got %v", w.Result().Cookies())
+	}
+
+	spec.InvalidRedirect = "/login"
+	if w := serve(spec, "tampered"); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" {
+		t.Fatalf("Expected a redirect to /login, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	// Store failures do not clear the cookie.
+	encoded, _ := s.Encode("session", "alice")
+	spec.Codec = New([]byte("12345"), nil).CheckRevocation(&revocationList{err: errTokenRevoked}, 0)
+	if w := serve(spec, encoded); len(w.Result().Cookies()) != 0 || w.Code == http.StatusSeeOther {
+		t.Fatalf("Expected the cookie to be kept, got %d, %v", w.Code, w.Result().Cookies())
+	}
+
+	// Unknown errors, e.g. of an unreachable key manager, do not either.
+	spec.Codec = errorCodec{errors.New("dial tcp: connection refused")}
+	if w := serve(spec, encoded); len(w.Result().Cookies()) != 0 || w.Code == http.StatusSeeOther {
+		t.Fatalf("Expected the cookie to be kept, got %d, %v", w.Code, w.Result().Cookies())
+	}
+	spec.Codec = errorCodec{fmt.Errorf("%w: invalid ciphertext", ErrKMSKeyRejected)}
+	if w := serve(spec, encoded); w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected a rejected data key to be invalid, got %d", w.Code)
+	}
+}
+
+// errorCodec fails to decode with err.
+type errorCodec struct {
+	err error
+}
+
+func (c errorCodec) Encode(name string, value interface{}) (string, error) {
+	return "", c.err
+}
+
+func (c errorCodec) Decode(name, value string, dst interface{}) error {
+	return c.err
+}
+
+func TestTemporary(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	encoded, _ := s.Encode("session", "alice")
+	var dst string
+	err := s.CheckRevocation(&revocationList{err: errTokenRevoked}, 0).Decode("session", encoded, &dst)
+	if !Temporary(err) {
+		t.Fatalf("Expected a temporary error, got %v", err)
+	}
+	if Temporary(ErrMacInvalid) {
+		t.Fatal("Expected ErrMacInvalid not to be temporary")
+	}
+}
+
+func TestMiddlewareAutoPrefix(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	spec := CookieSpec{
+		Name:         "session",
+		Codec:        s,
+		New:          func() interface{} { return &testSession{} },
+		Options:      &CookieOptions{Secure: true, HttpOnly: true, AutoPrefix: true},
+		ClearInvalid: true,
+	}
+	encoded, _ := s.Encode("session", testSession{User: "alice"})
+	h := CookieMiddleware(spec)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, err := FromContext(r.Context(), "session")
+		if err != nil {
+			return
+		}
+		user := v.(*testSession).User
+		v.(*testSession).User = "bob"
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte(user))
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: encoded})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "__Host-session" || !w.Flushed {
+		t.Fatalf("Expected the modified value to be set as __Host-session, got %v", cookies)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Body.String() != "bob" {
+		t.Fatalf("Expected the prefixed cookie to be read back, got %q", w.Body.String())
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "__Host-session", Value: "tampered"})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].Name != "__Host-session" || cookies[0].MaxAge != -1 {
+		t.Fatalf("Expected the prefixed cookie to be cleared, got %v", cookies)
+	}
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	h := CookieMiddleware(CookieSpec{Name: "session", Codec: New([]byte("12345"), nil)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := w.(http.Hijacker).Hijack(); err != errHijackUnsupported {
+			t.Errorf("Expected errHijackUnsupported, got %v", err)
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
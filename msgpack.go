@@ -0,0 +1,41 @@
+//go:build msgpack
+
+package securecookie
+
+import (
+	"bytes"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// This file requires github.com/vmihailenco/msgpack/v5, which is not a
+// dependency of the default build. Add it with
+// "go get github.com/vmihailenco/msgpack/v5" and build with "-tags msgpack".
+
+func init() {
+	serializers["msgpack"] = MsgpackEncoder{}
+}
+
+// MsgpackEncoder encodes cookie values using MessagePack. Its output is
+// usually smaller than JSON, and readable by other languages.
+type MsgpackEncoder struct{}
+
+// Serialize encodes a value using MessagePack.
+func (e MsgpackEncoder) Serialize(src interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := msgpack.NewEncoder(buf)
+	enc.UseCompactInts(true)
+	if err := enc.Encode(src); err != nil {
+		return nil, Error{msg: err.Error()}
+	}
+	return buf.Bytes(), nil
+}
+
+// Deserialize decodes a value using MessagePack.
+func (e MsgpackEncoder) Deserialize(src []byte, dst interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(src))
+	if err := dec.Decode(dst); err != nil {
+		return Error{msg: err.Error()}
+	}
+	return nil
+}
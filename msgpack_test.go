@@ -0,0 +1,32 @@
+//go:build msgpack
+
+package securecookie
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMsgpackEncoder(t *testing.T) {
+	s := New([]byte("12345"), nil).SetSerializer(MsgpackEncoder{})
+	value := map[string]interface{}{"user": "alice", "admin": true}
+	encoded, err := s.Encode("sid", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst map[string]interface{}
+	if err := s.Decode("sid", encoded, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst, value) {
+		t.Fatalf("Expected %v, got %v", value, dst)
+	}
+	if serializers["msgpack"] == nil {
+		t.Fatal("Expected msgpack to be selectable by name")
+	}
+	for _, f := range AuditConfig(s) {
+		if f.Code == "custom-serializer" {
+			t.Fatalf("Expected MsgpackEncoder to be recognized, got %v", f)
+		}
+	}
+}
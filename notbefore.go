@@ -0,0 +1,53 @@
+package securecookie
+
+import (
+	"fmt"
+	"time"
+)
+
+// flagNotBefore marks values with a not-before time, stored as a
+// little-endian uint64 of Unix seconds after the timestamp.
+const flagNotBefore uint16 = 1 << 11
+
+var errNotYetValid = Error{msg: "value is not valid yet"}
+
+// NotBeforeError is returned when decoding a value, or validating Claims,
+// before its not-before time.
+type NotBeforeError struct {
+	NotBefore time.Time
+}
+
+func (e *NotBeforeError) Error() string {
+	return fmt.Sprintf("%v: until %s", errNotYetValid, e.NotBefore.UTC().Format(time.RFC3339))
+}
+
+// Unwrap returns errNotYetValid.
+func (e *NotBeforeError) Unwrap() error {
+	return errNotYetValid
+}
+
+// EncodeOption is an option of EncodeWith.
+type EncodeOption func(*encodeOptions)
+
+// encodeOptions holds the options of a single Encode.
+type encodeOptions struct {
+	notBefore int64
+	expiry    int64
+}
+
+// NotBefore makes the value fail to decode with a *NotBeforeError before t,
+// e.g. for tokens issued ahead of a scheduled access window. t is rounded
+// down to the second.
+func NotBefore(t time.Time) EncodeOption {
+	return func(o *encodeOptions) {
+		o.notBefore = t.Unix()
+	}
+}
+
+// checkNotBefore checks a not-before time read from an encoded value.
+func (s *SecureCookie) checkNotBefore(nbf uint64) error {
+	if s.timestamp()+int64(s.leeway/time.Second) < int64(nbf) {
+		return &NotBeforeError{NotBefore: time.Unix(int64(nbf), 0)}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package securecookie
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNotBefore(t *testing.T) {
+	now := int64(1700000000)
+	s := New([]byte("12345"), []byte("1234567890123456"))
+	s.timeFunc = func() int64 { return now }
+	encoded, err := s.EncodeWith("access", "window", NotBefore(time.Unix(now+3600, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	err = s.Decode("access", encoded, &dst)
+	var nbf *NotBeforeError
+	if !errors.As(err, &nbf) || nbf.NotBefore.Unix() != now+3600 || !errors.Is(err, errNotYetValid) {
+		t.Fatalf("Expected a *NotBeforeError, got %v", err)
+	}
+	now += 3600
+	if err := s.Decode("access", encoded, &dst); err != nil || dst != "window" {
+		t.Fatalf("Expected %q, got %q, %v", "window", dst, err)
+	}
+	if _, err := s.LegacyEncode(true).EncodeWith("access", "window", NotBefore(time.Now())); err != errLegacyOption {
+		t.Fatalf("Expected errLegacyOption, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package securecookie
+
+import "fmt"
+
+// OverflowPolicy is what a SecureCookie does with values longer than
+// MaxLength.
+type OverflowPolicy int
+
+const (
+	// OverflowError fails Encode with a *LengthError. It is the default.
+	OverflowError OverflowPolicy = iota
+	// OverflowChunk fails Encode with a *LengthError, but lifts MaxLength
+	// for the value as a whole in EncodeChunks and DecodeChunks, and so in
+	// WriteChunked and ReadChunked, which bound each chunk instead.
+	OverflowChunk
+	// OverflowSpill writes the data of the value to the Storage set with
+	// SpillTo, see SpillTo.
+	OverflowSpill
+)
+
+// String returns the name of the policy.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowError:
+		return "error"
+	case OverflowChunk:
+		return "chunk"
+	case OverflowSpill:
+		return "spill"
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+}
+
+// LengthError is returned for values longer than MaxLength. It matches
+// errors.Is for the error it replaces, so existing checks keep working.
+type LengthError struct {
+	// Name is the cookie name.
+	Name string
+	// Length is the length of the encoded value and MaxLength the length
+	// allowed, in bytes.
+	Length    int
+	MaxLength int
+	// Policy is the overflow policy of the codec, e.g. OverflowChunk if
+	// the caller should use WriteChunked.
+	Policy OverflowPolicy
+
+	err Error
+}
+
+func (e *LengthError) Error() string {
+	return fmt.Sprintf("%v: %s has %d bytes, max is %d", e.err, e.Name, e.Length, e.MaxLength)
+}
+
+// Unwrap returns the underlying error, errEncodedValueTooLong or
+// errValueToDecodeTooLong.
+func (e *LengthError) Unwrap() error {
+	return e.err
+}
+
+// Overflow sets what to do with values longer than MaxLength. Default is
+// OverflowError.
+func (s *SecureCookie) Overflow(p OverflowPolicy) *SecureCookie {
+	s.overflow = p
+	return s
+}
+
+// lengthError returns a *LengthError for a value of n bytes.
+func (s *SecureCookie) lengthError(err Error, name string, n int) error {
+	return &LengthError{Name: name, Length: n, MaxLength: s.maxLength, Policy: s.overflow, err: err}
+}
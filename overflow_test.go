@@ -0,0 +1,52 @@
+package securecookie
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLengthError(t *testing.T) {
+	s := New([]byte("12345"), nil).MaxLength(100)
+	_, err := s.Encode("sid", strings.Repeat("x", 200))
+	if !errors.Is(err, errEncodedValueTooLong) {
+		t.Fatalf("Expected errEncodedValueTooLong, got %v", err)
+	}
+	var lerr *LengthError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("Expected a *LengthError, got %T", err)
+	}
+	if lerr.Name != "sid" || lerr.Length <= 100 || lerr.MaxLength != 100 || lerr.Policy != OverflowError {
+		t.Fatalf("Unexpected error fields: %+v", lerr)
+	}
+
+	err = s.Decode("sid", strings.Repeat("x", 101), new(string))
+	if !errors.Is(err, errValueToDecodeTooLong) || !errors.As(err, &lerr) || lerr.Length != 101 {
+		t.Fatalf("Expected a *LengthError for errValueToDecodeTooLong, got %v", err)
+	}
+}
+
+func TestOverflowChunk(t *testing.T) {
+	s := New([]byte("12345"), []byte("1234567890123456")).MaxLength(100).Overflow(OverflowChunk)
+	value := strings.Repeat("x", 500)
+	_, err := s.Encode("sid", value)
+	var lerr *LengthError
+	if !errors.As(err, &lerr) || lerr.Policy != OverflowChunk {
+		t.Fatalf("Expected a *LengthError with OverflowChunk, got %v", err)
+	}
+	chunks, err := s.EncodeChunks("sid", value, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := s.DecodeChunks("sid", chunks, &dst); err != nil || dst != value {
+		t.Fatalf("Expected the chunked value, got %v", err)
+	}
+}
+
+func TestOverflowSpillWithoutStorage(t *testing.T) {
+	s := New([]byte("12345"), nil).MaxLength(100).Overflow(OverflowSpill)
+	if _, err := s.Encode("sid", strings.Repeat("x", 200)); err != errSpillNotConfigured {
+		t.Fatalf("Expected errSpillNotConfigured, got %v", err)
+	}
+}
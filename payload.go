@@ -0,0 +1,82 @@
+package securecookie
+
+import "fmt"
+
+var (
+	errPayloadTooManyKeys = Error{msg: "payload has too many keys"}
+	errPayloadTooDeep     = Error{msg: "payload is nested too deeply"}
+)
+
+// MaxPayloadKeys restricts the total number of map keys, counted across all
+// nesting levels, of map[string]interface{} payloads. It is checked before
+// Encode and after Decode, so a handler that keeps adding entries fails loudly
+// instead of growing the cookie until browsers drop it.
+//
+// Default is 0 (no restriction). Other payload types are not checked.
+func (s *SecureCookie) MaxPayloadKeys(value int) *SecureCookie {
+	s.maxPayloadKeys = value
+	return s
+}
+
+// MaxPayloadDepth restricts the nesting depth of map[string]interface{}
+// payloads. A flat map has a depth of 1; every nested map or slice adds one.
+//
+// Default is 0 (no restriction). Other payload types are not checked.
+func (s *SecureCookie) MaxPayloadDepth(value int) *SecureCookie {
+	s.maxPayloadDepth = value
+	return s
+}
+
+// checkPayload validates v against the payload limits, if v is a
+// map[string]interface{} or a pointer to one.
+func (s *SecureCookie) checkPayload(v interface{}) error {
+	if s.maxPayloadKeys == 0 && s.maxPayloadDepth == 0 {
+		return nil
+	}
+	var m map[string]interface{}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m = t
+	case *map[string]interface{}:
+		if t == nil {
+			return nil
+		}
+		m = *t
+	default:
+		return nil
+	}
+	keys, depth := payloadSize(m, 1)
+	if s.maxPayloadKeys != 0 && keys > s.maxPayloadKeys {
+		return fmt.Errorf("%w: %d", errPayloadTooManyKeys, keys)
+	}
+	if s.maxPayloadDepth != 0 && depth > s.maxPayloadDepth {
+		return fmt.Errorf("%w: %d", errPayloadTooDeep, depth)
+	}
+	return nil
+}
+
+// payloadSize returns the number of map keys and the maximum depth of v, which
+// is at the given depth.
+func payloadSize(v interface{}, depth int) (keys, maxDepth int) {
+	maxDepth = depth
+	var children []interface{}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys = len(t)
+		for _, c := range t {
+			children = append(children, c)
+		}
+	case []interface{}:
+		children = t
+	default:
+		return 0, depth - 1
+	}
+	for _, c := range children {
+		k, d := payloadSize(c, depth+1)
+		keys += k
+		if d > maxDepth {
+			maxDepth = d
+		}
+	}
+	return keys, maxDepth
+}
@@ -0,0 +1,42 @@
+package securecookie
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPayloadLimits(t *testing.T) {
+	s := New([]byte("12345"), nil).MaxPayloadKeys(3).MaxPayloadDepth(2)
+	ok := map[string]interface{}{
+		"a": "b",
+		"c": map[string]interface{}{"d": 1},
+	}
+	encoded, err := s.Encode("sid", ok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := map[string]interface{}{}
+	if err = s.Decode("sid", encoded, &dst); err != nil {
+		t.Fatal(err)
+	}
+
+	tooMany := map[string]interface{}{"a": 1, "b": 2, "c": 3, "d": 4}
+	if _, err = s.Encode("sid", tooMany); !errors.Is(err, errPayloadTooManyKeys) {
+		t.Fatalf("Expected errPayloadTooManyKeys, got %v", err)
+	}
+	tooDeep := map[string]interface{}{
+		"a": []interface{}{map[string]interface{}{"b": 1}},
+	}
+	if _, err = s.Encode("sid", tooDeep); !errors.Is(err, errPayloadTooDeep) {
+		t.Fatalf("Expected errPayloadTooDeep, got %v", err)
+	}
+
+	// A value encoded by a more permissive codec is rejected on decode.
+	encoded, err = New([]byte("12345"), nil).Encode("sid", tooMany)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Decode("sid", encoded, &dst); !errors.Is(err, errPayloadTooManyKeys) {
+		t.Fatalf("Expected errPayloadTooManyKeys, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package securecookie
+
+import "strings"
+
+const maxValuePrefixLength = 8
+
+var (
+	errValuePrefixInvalid = Error{msg: "value prefix must be 1 to 8 printable characters including one outside the base64 alphabet"}
+	errValuePrefixUnknown = Error{msg: "value prefix does not match"}
+)
+
+// ValuePrefix sets a short printable prefix, e.g. "v2." or "k3.", prepended to
+// encoded values. Decode rejects values without the prefix before doing any
+// base64 or cryptographic work, so in a DecodeMulti chain of differently
+// prefixed codecs only the matching one does real work. Prefixes also make
+// values easy to identify in logs and HAR files.
+//
+// The prefix must contain at least one character that is not in the URL-safe
+// base64 alphabet, such as '.' or '~', so unprefixed values can never match
+// it. Characters not allowed in cookie values are rejected.
+//
+// Default is "" (no prefix).
+func (s *SecureCookie) ValuePrefix(prefix string) *SecureCookie {
+	if prefix != "" && !validValuePrefix(prefix) {
+		s.err = errValuePrefixInvalid
+		return s
+	}
+	s.prefix = prefix
+	return s
+}
+
+// validValuePrefix reports whether prefix is short, cookie-safe and cannot be
+// confused with the start of a base64 value.
+func validValuePrefix(prefix string) bool {
+	if len(prefix) > maxValuePrefixLength {
+		return false
+	}
+	outside := false
+	for i := 0; i < len(prefix); i++ {
+		c := prefix[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte("\",;\\", c) >= 0 {
+			return false
+		}
+		if !isBase64URL(c) && c != '=' {
+			outside = true
+		}
+	}
+	return outside
+}
+
+func isBase64URL(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_'
+}
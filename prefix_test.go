@@ -0,0 +1,36 @@
+package securecookie
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValuePrefix(t *testing.T) {
+	for _, p := range []string{"v2", "v2;", "toolong.xx", "a b."} {
+		if New([]byte("12345"), nil).ValuePrefix(p).err != errValuePrefixInvalid {
+			t.Errorf("Expected prefix %q to be rejected", p)
+		}
+	}
+
+	v1 := New([]byte("12345"), nil)
+	v2 := New([]byte("54321"), nil).ValuePrefix("v2.")
+	encoded, err := v2.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "v2.") {
+		t.Fatalf("Expected prefixed value, got %s", encoded)
+	}
+	var dst string
+	if err = v2.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected to decode, got %q, %v", dst, err)
+	}
+	if err = DecodeMulti("sid", encoded, &dst, v2, v1); err != nil {
+		t.Fatal(err)
+	}
+
+	unprefixed, _ := v1.Encode("sid", "value")
+	if err = v2.Decode("sid", unprefixed, &dst); err != errValuePrefixUnknown {
+		t.Fatalf("Expected errValuePrefixUnknown, got %v", err)
+	}
+}
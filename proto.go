@@ -0,0 +1,48 @@
+//go:build protobuf
+
+package securecookie
+
+import "google.golang.org/protobuf/proto"
+
+// This file requires google.golang.org/protobuf, which is not a dependency
+// of the default build. Add it with "go get google.golang.org/protobuf" and
+// build with "-tags protobuf".
+
+var (
+	errValueNotProto    = Error{msg: "value not a proto.Message."}
+	errValueNotProtoPtr = Error{msg: "value not a pointer to a proto.Message."}
+)
+
+func init() {
+	serializers["proto"] = ProtoEncoder{}
+}
+
+// ProtoEncoder encodes cookie values using Protocol Buffers. Values must
+// implement proto.Message, so payloads defined in .proto files can be shared
+// with services written in other languages.
+type ProtoEncoder struct{}
+
+// Serialize encodes a proto.Message.
+func (e ProtoEncoder) Serialize(src interface{}) ([]byte, error) {
+	m, ok := src.(proto.Message)
+	if !ok {
+		return nil, errValueNotProto
+	}
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return nil, Error{msg: err.Error()}
+	}
+	return b, nil
+}
+
+// Deserialize decodes into a proto.Message.
+func (e ProtoEncoder) Deserialize(src []byte, dst interface{}) error {
+	m, ok := dst.(proto.Message)
+	if !ok {
+		return errValueNotProtoPtr
+	}
+	if err := proto.Unmarshal(src, m); err != nil {
+		return Error{msg: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build protobuf
+
+package securecookie
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestProtoEncoder(t *testing.T) {
+	s := New([]byte("12345"), nil).SetSerializer(ProtoEncoder{})
+	encoded, err := s.Encode("sid", wrapperspb.String("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := &wrapperspb.StringValue{}
+	if err := s.Decode("sid", encoded, dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.GetValue() != "alice" {
+		t.Fatalf("Expected alice, got %q", dst.GetValue())
+	}
+	if _, err := s.Encode("sid", "alice"); err != errValueNotProto {
+		t.Fatalf("Expected errValueNotProto, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package securecookie
+
+import (
+	"crypto/aes"
+	"crypto/sha256"
+)
+
+// WithPurpose returns a copy of s whose keys are derived from the keys of s
+// with HKDF-SHA256, using the purpose as context, e.g. "password-reset".
+// Values encoded for one purpose cannot be decoded for another, so tokens
+// issued for different features cannot be swapped between endpoints, while
+// only one key pair is configured. The copy keeps the options of s.
+func (s *SecureCookie) WithPurpose(purpose string) *SecureCookie {
+	c := *s
+	if len(s.hashKey) == 0 {
+		return &c
+	}
+	info := []byte("securecookie purpose " + purpose)
+	c.hashKey = hkdf(sha256.New, s.hashKey, nil, info, 32)
+	if s.blockKey != nil {
+		c.blockKey = hkdf(sha256.New, s.blockKey, nil, info, len(s.blockKey))
+		f := s.blockFunc
+		if f == nil {
+			f = aes.NewCipher
+		}
+		c.block = nil
+		c.BlockFunc(f)
+	}
+	return &c
+}
@@ -0,0 +1,26 @@
+package securecookie
+
+import "testing"
+
+func TestWithPurpose(t *testing.T) {
+	s := New([]byte("12345"), []byte("1234567890123456")).MaxAge(60)
+	reset := s.WithPurpose("password-reset")
+	invite := s.WithPurpose("invite")
+	if reset.maxAge != 60 {
+		t.Fatal("Expected the options to be kept")
+	}
+
+	encoded, err := reset.Encode("token", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := s.WithPurpose("password-reset").Decode("token", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected value, got %q, %v", dst, err)
+	}
+	for _, c := range []*SecureCookie{s, invite} {
+		if err := c.Decode("token", encoded, &dst); err == nil {
+			t.Fatal("Expected decoding with another purpose to fail")
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package securecookie
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Refresher implements sliding sessions: once a cookie is older than a
+// threshold, it is encoded again with the current time and sent back, so
+// that active users keep their session while idle ones reach MaxAge. Values
+// keep their not-before time and absolute expiry, see SessionTimeouts.
+type Refresher struct {
+	Codec *SecureCookie
+	// Cookie is the template of the refreshed cookie: its name and
+	// attributes are used, and its value is ignored.
+	Cookie *http.Cookie
+	// Threshold is the age from which cookies are refreshed. 0 refreshes
+	// them on every request.
+	Threshold time.Duration
+	// New returns a pointer to a new value to decode the cookie into, like
+	// CookieSpec.New. Default is a pointer to a map[string]interface{}.
+	New func() interface{}
+}
+
+// Refresh decodes the cookie of r and, if it is older than the threshold,
+// sets it again on w. It returns the decoded value, as a pointer created by
+// New, and whether the cookie was refreshed.
+func (f *Refresher) Refresh(w http.ResponseWriter, r *http.Request) (interface{}, bool, error) {
+	c, err := r.Cookie(f.Cookie.Name)
+	if err != nil {
+		return nil, false, err
+	}
+	codec, err := f.Codec.forRequest(r)
+	if err != nil {
+		return nil, false, err
+	}
+	dst := f.newValue()
+	info, err := codec.DecodeWithInfo(f.Cookie.Name, c.Value, dst)
+	if err != nil {
+		return nil, false, err
+	}
+	if info.Age < f.Threshold {
+		return dst, false, nil
+	}
+	encoded, err := codec.reissue(f.Cookie.Name, dst, info)
+	if err != nil {
+		return dst, false, err
+	}
+	refreshed := *f.Cookie
+	refreshed.Value = encoded
+	http.SetCookie(w, &refreshed)
+	return dst, true, nil
+}
+
+// Middleware returns a handler that refreshes the cookie before calling next.
+// The decoded value, or the decoding error, is available to next through
+// FromContext, as for ScopedMux.
+func (f *Refresher) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := &decodedCookie{}
+		d.value, _, d.err = f.Refresh(w, r)
+		next.ServeHTTP(w, r.WithContext(withDecodedCookie(r.Context(), f.Cookie.Name, d)))
+	})
+}
+
+func (f *Refresher) newValue() interface{} {
+	if f.New != nil {
+		return f.New()
+	}
+	return &map[string]interface{}{}
+}
+
+// withDecodedCookie returns a context holding the decoded cookies of ctx and
+// d, for FromContext.
+func withDecodedCookie(ctx context.Context, name string, d *decodedCookie) context.Context {
+	results := map[string]*decodedCookie{name: d}
+	if prev, ok := ctx.Value(decodedCookiesKey).(map[string]*decodedCookie); ok {
+		for n, p := range prev {
+			if n != name {
+				results[n] = p
+			}
+		}
+	}
+	return context.WithValue(ctx, decodedCookiesKey, results)
+}
@@ -0,0 +1,55 @@
+package securecookie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRefresher(t *testing.T) {
+	issued := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := issued
+	s := New([]byte("12345"), nil).MaxAge(3600).SetClock(ClockFunc(func() time.Time { return now }))
+	f := &Refresher{
+		Codec:     s,
+		Cookie:    &http.Cookie{Name: "sid", Path: "/", HttpOnly: true},
+		Threshold: 10 * time.Minute,
+		New:       func() interface{} { return new(string) },
+	}
+	encoded, err := s.Encode("sid", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen interface{}
+	h := f.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = FromContext(r.Context(), "sid")
+	}))
+	serve := func() *http.Cookie {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: "sid", Value: encoded})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if v, ok := seen.(*string); !ok || *v != "alice" {
+			t.Fatalf("Expected the decoded value in the context, got %v", seen)
+		}
+		if cookies := w.Result().Cookies(); len(cookies) == 1 {
+			return cookies[0]
+		}
+		return nil
+	}
+
+	now = issued.Add(5 * time.Minute)
+	if c := serve(); c != nil {
+		t.Fatalf("Expected no refresh before the threshold, got %v", c)
+	}
+	now = issued.Add(30 * time.Minute)
+	c := serve()
+	if c == nil || !c.HttpOnly || c.Path != "/" {
+		t.Fatalf("Expected a refreshed cookie with the template attributes, got %v", c)
+	}
+	info, err := s.DecodeWithInfo("sid", c.Value, new(string))
+	if err != nil || info.Age != 0 {
+		t.Fatalf("Expected a cookie issued now, got %+v, %v", info, err)
+	}
+}
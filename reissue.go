@@ -0,0 +1,93 @@
+package securecookie
+
+import (
+	"net/http"
+	"reflect"
+	"time"
+)
+
+var errReissueNoCodecs = Error{msg: "reissue policy has no codecs"}
+
+// ReissuePolicy tells ReissueIfNeeded when a cookie is stale.
+type ReissuePolicy struct {
+	// Codecs decode the cookie, in order, and the first one encodes stale
+	// values again, e.g. Keyring.Codecs(), whose first codec is the active
+	// key. Values decoded by another codec are stale.
+	Codecs []Codec
+	// MaxAge is the age from which values are stale, 0 for none. Values
+	// without a timestamp never are.
+	MaxAge time.Duration
+	// Options are the attributes of the reissued cookie, see NewCookie.
+	Options *CookieOptions
+}
+
+// ReissueIfNeeded decodes the named cookie of r into dst and, if it is stale
+// according to policy, encodes it again with the first codec and sets it on
+// w. Values are stale if they were decoded by an older key, are older than
+// policy.MaxAge, or use another wire format than the first codec encodes,
+// see FormatVersion and LegacyDecode. Reissued values keep their not-before
+// time and absolute expiry. It reports whether the cookie was reissued.
+//
+// If name has no prefix, the __Host- and __Secure- variants set with
+// AutoPrefix are read first, like GetCookie. It returns http.ErrNoCookie if r
+// has no such cookie, and the decoding error if the cookie is invalid.
+func ReissueIfNeeded(w http.ResponseWriter, r *http.Request, name string, dst interface{}, policy *ReissuePolicy) (bool, error) {
+	if len(policy.Codecs) == 0 {
+		return false, errReissueNoCodecs
+	}
+	c, err := prefixedCookie(r, name)
+	if err != nil {
+		return false, err
+	}
+	codecs := make([]Codec, len(policy.Codecs))
+	for i, codec := range policy.Codecs {
+		if codecs[i], err = RequestCodec(codec, r); err != nil {
+			return false, err
+		}
+	}
+	info, err := DecodeMultiWithInfo(name, c.Value, dst, codecs...)
+	if err != nil {
+		return false, err
+	}
+	s, ok := codecs[0].(*SecureCookie)
+	stale := info.CodecIndex != 0 ||
+		(policy.MaxAge != 0 && !info.IssuedAt.IsZero() && info.Age >= policy.MaxAge) ||
+		(ok && info.FormatVersion != int(s.formatVersion()))
+	if !stale {
+		return false, nil
+	}
+	var reissued *http.Cookie
+	if ok {
+		encoded, err := s.reissue(name, dst, info)
+		if err != nil {
+			return false, err
+		}
+		reissued, err = s.cookie(name, encoded, policy.Options)
+		if err != nil {
+			return false, err
+		}
+	} else {
+		encoded, err := codecs[0].Encode(name, reflect.ValueOf(dst).Elem().Interface())
+		if err != nil {
+			return false, err
+		}
+		reissued, err = newCookie(name, encoded, cookieOptions(policy.Options, 0), time.Now())
+		if err != nil {
+			return false, err
+		}
+	}
+	http.SetCookie(w, reissued)
+	return true, nil
+}
+
+// formatVersion returns the wire format version of encoded values, 0 for the
+// format of the upstream package.
+func (s *SecureCookie) formatVersion() byte {
+	if s.legacyEncode {
+		return 0
+	}
+	if s.version < formatV1 {
+		return formatV1
+	}
+	return s.version
+}
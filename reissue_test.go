@@ -0,0 +1,73 @@
+package securecookie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReissueIfNeeded(t *testing.T) {
+	k := NewKeyring()
+	if err := k.Add("old", []byte("12345"), nil); err != nil {
+		t.Fatal(err)
+	}
+	old, err := k.Encode("sid", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Add("new", []byte("67890"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Promote("new"); err != nil {
+		t.Fatal(err)
+	}
+	current, err := k.Encode("sid", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &ReissuePolicy{Codecs: k.Codecs(), MaxAge: time.Hour}
+	cookieName := "sid"
+	serve := func(value string) (*http.Cookie, bool) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: cookieName, Value: value})
+		w := httptest.NewRecorder()
+		var dst string
+		reissued, err := ReissueIfNeeded(w, r, "sid", &dst, policy)
+		if err != nil || dst != "alice" {
+			t.Fatalf("Expected %q, got %q, %v", "alice", dst, err)
+		}
+		var c *http.Cookie
+		if cookies := w.Result().Cookies(); len(cookies) == 1 {
+			c = cookies[0]
+		}
+		return c, reissued
+	}
+
+	if c, reissued := serve(current); reissued || c != nil {
+		t.Fatalf("Expected no reissue for a current value, got %v", c)
+	}
+	c, reissued := serve(old)
+	if !reissued || c == nil || !c.HttpOnly {
+		t.Fatalf("Expected a value of an older key to be reissued, got %v", c)
+	}
+	info, err := DecodeMultiWithInfo("sid", c.Value, new(string), k.Codecs()...)
+	if err != nil || info.CodecIndex != 0 {
+		t.Fatalf("Expected the reissued value to use the active key, got %+v, %v", info, err)
+	}
+
+	// Cookies set with AutoPrefix are read by their unprefixed name.
+	cookieName = "__Host-sid"
+	policy.Options = &CookieOptions{Secure: true, AutoPrefix: true}
+	if c, reissued := serve(old); !reissued || c == nil || c.Name != "__Host-sid" {
+		t.Fatalf("Expected the prefixed cookie to be reissued, got %v", c)
+	}
+	cookieName, policy.Options = "sid", nil
+
+	// Values in an older wire format are reissued too.
+	k.Configure(func(s *SecureCookie) { s.FormatVersion(2) })
+	policy.Codecs = k.Codecs()
+	if _, reissued := serve(current); !reissued {
+		t.Fatal("Expected a version 1 value to be reissued")
+	}
+}
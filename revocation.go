@@ -0,0 +1,65 @@
+package securecookie
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// flagTokenID marks values with a random token ID, stored after the absolute
+// expiry, if any.
+const flagTokenID uint16 = 1 << 9
+
+// tokenIDSize is the size of token IDs, in bytes.
+const tokenIDSize = 16
+
+var (
+	errTokenRevoked     = Error{msg: "value was revoked"}
+	errRevocationFailed = Error{msg: "revocation could not be checked"}
+)
+
+// RevocationChecker tells whether decoded values were revoked, e.g. to log
+// out a stolen cookie or every session of a user.
+type RevocationChecker interface {
+	// Revoked reports whether the value with the given token ID, issued at
+	// the given time, was revoked. id is empty for values encoded without
+	// token IDs, and issuedAt is zero for values without a timestamp.
+	Revoked(ctx context.Context, id string, issuedAt time.Time) (bool, error)
+}
+
+// TokenIDs embeds a random 16-byte token ID, like the jti claim of JWTs, in
+// encoded values, so that they can be revoked one by one. The ID of a value
+// is returned by DecodeWithInfo.
+//
+// Default is false.
+func (s *SecureCookie) TokenIDs(enabled bool) *SecureCookie {
+	s.tokenIDs = enabled
+	return s
+}
+
+// CheckRevocation makes Decode consult rc, and fail for revoked values. Calls
+// use a background context, with the given timeout if it is not 0; errors of
+// rc fail Decode too.
+func (s *SecureCookie) CheckRevocation(rc RevocationChecker, timeout time.Duration) *SecureCookie {
+	s.revocation = rc
+	s.revocationTimeout = timeout
+	return s
+}
+
+// checkRevoked checks a decoded value against the revocation checker.
+func (s *SecureCookie) checkRevoked(info *valueInfo) error {
+	var issued time.Time
+	if info.timestamp != 0 {
+		issued = issuedAt(info.timestamp)
+	}
+	ctx, cancel := backgroundContext(s.revocationTimeout)
+	defer cancel()
+	revoked, err := s.revocation.Revoked(ctx, info.tokenID, issued)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRevocationFailed, err)
+	}
+	if revoked {
+		return errTokenRevoked
+	}
+	return nil
+}
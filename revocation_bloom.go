@@ -0,0 +1,136 @@
+package securecookie
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+)
+
+// BloomRevocations is a RevocationChecker that keeps revoked token IDs in
+// Bloom filters, using a few bytes per ID whatever their count. False
+// positives reject valid values, which then only force a new login.
+//
+// IDs are added to the current filter, and checked against it and the
+// previous one. Rotate, or Run, replaces the previous filter with the current
+// one and starts an empty one, so a revocation lasts between one and two
+// rotation intervals; the interval should be at least the MaxAge of values.
+//
+// A BloomRevocations is safe for concurrent use.
+type BloomRevocations struct {
+	mu       sync.RWMutex
+	current  []uint64
+	previous []uint64
+	bits     uint64
+	hashes   int
+	before   time.Time
+}
+
+// NewBloomRevocations returns a BloomRevocations sized for n revocations per
+// rotation interval with the given false positive rate, e.g. 0.001. The rate
+// is clamped between 1e-9 and 0.5.
+func NewBloomRevocations(n int, falsePositiveRate float64) *BloomRevocations {
+	if n < 1 {
+		n = 1
+	}
+	if !(falsePositiveRate >= 1e-9) {
+		falsePositiveRate = 1e-9
+	} else if falsePositiveRate > 0.5 {
+		falsePositiveRate = 0.5
+	}
+	m := math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	if m < 64 {
+		m = 64
+	}
+	k := int(math.Round(m / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	words := (uint64(m) + 63) / 64
+	return &BloomRevocations{
+		current:  make([]uint64, words),
+		previous: make([]uint64, words),
+		bits:     words * 64,
+		hashes:   k,
+	}
+}
+
+// Revoke revokes the value with the given token ID.
+func (b *BloomRevocations) Revoke(id string) {
+	h1, h2 := bloomHashes(id)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.bits
+		b.current[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// RevokeBefore revokes every value issued before t, e.g. to log out every
+// session. Values without a timestamp are not affected. Unlike token IDs, it
+// is exact and survives rotations.
+func (b *BloomRevocations) RevokeBefore(t time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t.After(b.before) {
+		b.before = t
+	}
+}
+
+// Revoked implements RevocationChecker.
+func (b *BloomRevocations) Revoked(ctx context.Context, id string, issuedAt time.Time) (bool, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if !issuedAt.IsZero() && issuedAt.Before(b.before) {
+		return true, nil
+	}
+	if id == "" {
+		return false, nil
+	}
+	h1, h2 := bloomHashes(id)
+	return b.contains(b.current, h1, h2) || b.contains(b.previous, h1, h2), nil
+}
+
+// Rotate drops the previous filter, keeps the current one as previous and
+// starts an empty one.
+func (b *BloomRevocations) Rotate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.previous, b.current = b.current, b.previous
+	for i := range b.current {
+		b.current[i] = 0
+	}
+}
+
+// Run calls Rotate every interval, until ctx is done.
+func (b *BloomRevocations) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.Rotate()
+		}
+	}
+}
+
+func (b *BloomRevocations) contains(filter []uint64, h1, h2 uint64) bool {
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.bits
+		if filter[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHashes returns the two hashes of id from which the filter positions
+// are derived.
+func bloomHashes(id string) (uint64, uint64) {
+	sum := sha256.Sum256([]byte(id))
+	return binary.LittleEndian.Uint64(sum[:8]), binary.LittleEndian.Uint64(sum[8:16]) | 1
+}
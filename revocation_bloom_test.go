@@ -0,0 +1,63 @@
+package securecookie
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBloomRevocations(t *testing.T) {
+	b := NewBloomRevocations(1000, 0.01)
+	s := New([]byte("12345"), nil).TokenIDs(true).CheckRevocation(b, 0)
+	a, err := s.Encode("sid", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := s.DecodeWithInfo("sid", a, new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.Revoke(info.TokenID)
+	var dst string
+	if err := s.Decode("sid", a, &dst); err != errTokenRevoked {
+		t.Fatalf("Expected errTokenRevoked, got %v", err)
+	}
+	b.Rotate()
+	if err := s.Decode("sid", a, &dst); err != errTokenRevoked {
+		t.Fatalf("Expected the revocation to survive one rotation, got %v", err)
+	}
+	b.Rotate()
+	if err := s.Decode("sid", a, &dst); err != nil {
+		t.Fatalf("Expected the revocation to expire, got %v", err)
+	}
+
+	// The false positive rate stays near the requested one.
+	for i := 0; i < 1000; i++ {
+		b.Revoke(fmt.Sprint("revoked", i))
+	}
+	positives := 0
+	for i := 0; i < 10000; i++ {
+		if revoked, _ := b.Revoked(context.Background(), fmt.Sprint("valid", i), time.Time{}); revoked {
+			positives++
+		}
+	}
+	if positives > 300 {
+		t.Fatalf("Expected about 100 false positives, got %d", positives)
+	}
+}
+
+func TestBloomRevocationsRate(t *testing.T) {
+	for _, rate := range []float64{0, -1, 1, 2, math.NaN(), math.Inf(1)} {
+		b := NewBloomRevocations(100, rate)
+		if b.bits == 0 || b.hashes < 1 || b.bits > 1<<20 {
+			t.Fatalf("Expected a bounded filter for rate %v, got %d bits and %d hashes", rate, b.bits, b.hashes)
+		}
+		b.Revoke("id")
+		if revoked, _ := b.Revoked(context.Background(), "id", time.Now()); !revoked {
+			t.Fatalf("Expected the id to be revoked for rate %v", rate)
+		}
+	}
+}
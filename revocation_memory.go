@@ -0,0 +1,96 @@
+package securecookie
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryRevocations is a RevocationChecker that keeps revoked token IDs in
+// memory until their TTL elapses. It suits single-instance applications, and
+// is a template for stores shared by several processes.
+//
+// A MemoryRevocations is safe for concurrent use.
+type MemoryRevocations struct {
+	mu     sync.Mutex
+	ids    map[string]time.Time
+	before time.Time
+	now    func() time.Time
+}
+
+// NewMemoryRevocations returns an empty MemoryRevocations. Run Sweep in a
+// goroutine to drop expired entries.
+func NewMemoryRevocations() *MemoryRevocations {
+	return &MemoryRevocations{ids: make(map[string]time.Time), now: time.Now}
+}
+
+// Revoke revokes the value with the given token ID for ttl, which should be
+// at least the time left until the value expires, e.g. its MaxAge.
+func (m *MemoryRevocations) Revoke(id string, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ids[id] = m.now().Add(ttl)
+}
+
+// RevokeBefore revokes every value issued before t, e.g. to log out every
+// session. Values without a timestamp are not affected.
+func (m *MemoryRevocations) RevokeBefore(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t.After(m.before) {
+		m.before = t
+	}
+}
+
+// Revoked implements RevocationChecker.
+func (m *MemoryRevocations) Revoked(ctx context.Context, id string, issuedAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !issuedAt.IsZero() && issuedAt.Before(m.before) {
+		return true, nil
+	}
+	if id == "" {
+		return false, nil
+	}
+	return m.active(id), nil
+}
+
+// active reports whether id is held and not expired. A zero expiry never
+// expires.
+func (m *MemoryRevocations) active(id string) bool {
+	expiry, ok := m.ids[id]
+	return ok && (expiry.IsZero() || m.now().Before(expiry))
+}
+
+// Len returns the number of revoked token IDs held, expired or not.
+func (m *MemoryRevocations) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.ids)
+}
+
+// Sweep drops expired token IDs every interval, until ctx is done.
+func (m *MemoryRevocations) Sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.sweep()
+		}
+	}
+}
+
+// sweep drops expired token IDs.
+func (m *MemoryRevocations) sweep() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for id, expiry := range m.ids {
+		if !expiry.IsZero() && !now.Before(expiry) {
+			delete(m.ids, id)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package securecookie
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryRevocations(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m := NewMemoryRevocations()
+	m.now = func() time.Time { return now }
+	s := New([]byte("12345"), nil).TokenIDs(true).CheckRevocation(m, 0)
+	s.timeFunc = func() int64 { return now.Unix() }
+	a, err := s.Encode("sid", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := s.DecodeWithInfo("sid", a, new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Revoke(info.TokenID, time.Minute)
+	var dst string
+	if err := s.Decode("sid", a, &dst); err != errTokenRevoked {
+		t.Fatalf("Expected errTokenRevoked, got %v", err)
+	}
+	now = now.Add(time.Minute)
+	if err := s.Decode("sid", a, &dst); err != nil {
+		t.Fatalf("Expected the revocation to expire, got %v", err)
+	}
+	m.sweep()
+	if n := m.Len(); n != 0 {
+		t.Fatalf("Expected expired entries to be swept, got %d", n)
+	}
+
+	m.RevokeBefore(now)
+	if err := s.Decode("sid", a, &dst); err != errTokenRevoked {
+		t.Fatalf("Expected errTokenRevoked, got %v", err)
+	}
+	b, err := s.Encode("sid", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Decode("sid", b, &dst); err != nil || dst != "b" {
+		t.Fatalf("Expected %q, got %q, %v", "b", dst, err)
+	}
+}
+
+func TestMemoryRevocationsSweep(t *testing.T) {
+	m := NewMemoryRevocations()
+	m.Revoke("id", 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Sweep(ctx, time.Millisecond)
+		close(done)
+	}()
+	for m.Len() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,97 @@
+package securecookie
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	redisRevoked = `if ARGV[1] ~= '' and redis.call('EXISTS', KEYS[1]) == 1 then return 1 end
+local b = redis.call('GET', KEYS[2])
+if b and ARGV[2] ~= '' and tonumber(ARGV[2]) < tonumber(b) then return 1 end
+return 0`
+	redisRevoke = `for i, k in ipairs(KEYS) do redis.call('SET', k, '1', 'PX', ARGV[1]) end
+return #KEYS`
+	redisRevokeBefore = `local b = tonumber(redis.call('GET', KEYS[1]) or '0')
+if tonumber(ARGV[1]) > b then redis.call('SET', KEYS[1], ARGV[1]) end
+return 1`
+	redisConsume = `local ok
+if ARGV[1] == '0' then ok = redis.call('SET', KEYS[1], '1', 'NX')
+else ok = redis.call('SET', KEYS[1], '1', 'NX', 'PX', ARGV[1]) end
+if ok then return 1 end
+return 0`
+)
+
+// RedisRevocations is a RevocationChecker backed by Redis, so that every
+// instance of a fleet sees revocations at once. Each call is a single script
+// run, i.e. one round trip.
+type RedisRevocations struct {
+	Client RedisScripter
+	// Prefix is prepended to the keys used. On Redis Cluster it must contain
+	// a hash tag, e.g. "{revoked}:", as calls touch several keys.
+	Prefix string
+}
+
+// Revoke revokes the values with the given token IDs for ttl, which should
+// be at least the time left until they expire, e.g. their MaxAge. Every ID
+// is stored in the same round trip.
+func (r *RedisRevocations) Revoke(ctx context.Context, ttl time.Duration, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.idKey(id)
+	}
+	ms := ttl.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	_, err := r.Client.Eval(ctx, redisRevoke, keys, ms)
+	return err
+}
+
+// RevokeBefore revokes every value issued before t, e.g. to log out every
+// session. Values without a timestamp are not affected.
+func (r *RedisRevocations) RevokeBefore(ctx context.Context, t time.Time) error {
+	_, err := r.Client.Eval(ctx, redisRevokeBefore, []string{r.beforeKey()}, t.UnixMilli())
+	return err
+}
+
+// Revoked implements RevocationChecker.
+func (r *RedisRevocations) Revoked(ctx context.Context, id string, issuedAt time.Time) (bool, error) {
+	var issued interface{} = ""
+	if !issuedAt.IsZero() {
+		issued = issuedAt.UnixMilli()
+	}
+	reply, err := r.Client.Eval(ctx, redisRevoked, []string{r.idKey(id), r.beforeKey()}, id, issued)
+	if err != nil {
+		return false, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return false, errRedisReply
+	}
+	return n == 1, nil
+}
+
+// Consume implements ConsumeStore. Used IDs share the keys of revoked ones.
+func (r *RedisRevocations) Consume(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	reply, err := r.Client.Eval(ctx, redisConsume, []string{r.idKey(id)}, ttl.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return false, errRedisReply
+	}
+	return n == 1, nil
+}
+
+func (r *RedisRevocations) idKey(id string) string {
+	return r.Prefix + "jti:" + id
+}
+
+func (r *RedisRevocations) beforeKey() string {
+	return r.Prefix + "before"
+}
@@ -0,0 +1,117 @@
+package securecookie
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeRedis runs the revocation scripts against a map, ignoring TTLs.
+type fakeRedis struct {
+	data  map[string]string
+	calls int
+}
+
+func (f *fakeRedis) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	f.calls++
+	switch script {
+	case redisRevoke:
+		for _, k := range keys {
+			f.data[k] = "1"
+		}
+		return int64(len(keys)), nil
+	case redisConsume:
+		if _, ok := f.data[keys[0]]; ok {
+			return int64(0), nil
+		}
+		f.data[keys[0]] = "1"
+		return int64(1), nil
+	case redisRevokeBefore:
+		var b, t int64
+		fmt.Sscan(f.data[keys[0]], &b)
+		fmt.Sscan(fmt.Sprint(args[0]), &t)
+		if t > b {
+			f.data[keys[0]] = fmt.Sprint(t)
+		}
+		return int64(1), nil
+	case redisRevoked:
+		if _, ok := f.data[keys[0]]; ok && args[0] != "" {
+			return int64(1), nil
+		}
+		var b, t int64
+		if s, ok := f.data[keys[1]]; ok && args[1] != "" {
+			fmt.Sscan(s, &b)
+			fmt.Sscan(fmt.Sprint(args[1]), &t)
+			if t < b {
+				return int64(1), nil
+			}
+		}
+		return int64(0), nil
+	}
+	return nil, fmt.Errorf("unexpected script %q", script)
+}
+
+func TestRedisRevocations(t *testing.T) {
+	now := int64(1700000000)
+	f := &fakeRedis{data: map[string]string{}}
+	r := &RedisRevocations{Client: f, Prefix: "{revoked}:"}
+	s := New([]byte("12345"), nil).TokenIDs(true).CheckRevocation(r, time.Second)
+	s.timeFunc = func() int64 { return now }
+	a, err := s.Encode("sid", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := s.DecodeWithInfo("sid", a, new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	f.calls = 0
+	if err := r.Revoke(ctx, time.Hour, info.TokenID, "other"); err != nil {
+		t.Fatal(err)
+	}
+	if f.calls != 1 || f.data["{revoked}:jti:"+info.TokenID] == "" {
+		t.Fatalf("Expected both IDs stored in one call, got %d calls, %v", f.calls, f.data)
+	}
+	var dst string
+	if err := s.Decode("sid", a, &dst); err != errTokenRevoked {
+		t.Fatalf("Expected errTokenRevoked, got %v", err)
+	}
+
+	now += 60
+	b, err := s.Encode("sid", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RevokeBefore(ctx, time.Unix(now, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Decode("sid", b, &dst); err != nil || dst != "b" {
+		t.Fatalf("Expected %q, got %q, %v", "b", dst, err)
+	}
+	now += 60
+	if err := r.RevokeBefore(ctx, time.Unix(now, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Decode("sid", b, &dst); err != errTokenRevoked {
+		t.Fatalf("Expected errTokenRevoked, got %v", err)
+	}
+}
+
+func TestRedisRevocationsConsume(t *testing.T) {
+	r := &RedisRevocations{Client: &fakeRedis{data: map[string]string{}}}
+	s := New([]byte("12345"), nil).SingleUse(r, time.Second)
+	encoded, err := s.Encode("grant", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := s.Decode("grant", encoded, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Decode("grant", encoded, &dst); err != errTokenConsumed {
+		t.Fatalf("Expected errTokenConsumed, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package securecookie
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type revocationList struct {
+	ids    map[string]bool
+	before time.Time
+	err    error
+}
+
+func (l *revocationList) Revoked(ctx context.Context, id string, issuedAt time.Time) (bool, error) {
+	return l.ids[id] || issuedAt.Before(l.before), l.err
+}
+
+func TestRevocation(t *testing.T) {
+	now := int64(1700000000)
+	s := New([]byte("12345"), []byte("1234567890123456")).TokenIDs(true)
+	s.timeFunc = func() int64 { return now }
+	a, err := s.Encode("sid", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Encode("sid", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := s.DecodeWithInfo("sid", a, new(string))
+	if err != nil || len(info.TokenID) != 22 {
+		t.Fatalf("Expected a token ID, got %+v, %v", info, err)
+	}
+
+	list := &revocationList{ids: map[string]bool{info.TokenID: true}}
+	s.CheckRevocation(list, time.Second)
+	var dst string
+	if err := s.Decode("sid", a, &dst); err != errTokenRevoked {
+		t.Fatalf("Expected errTokenRevoked, got %v", err)
+	}
+	if err := s.Decode("sid", b, &dst); err != nil || dst != "b" {
+		t.Fatalf("Expected %q, got %q, %v", "b", dst, err)
+	}
+
+	// Logout everywhere revokes values issued before a time.
+	list.before = time.Unix(now+1, 0)
+	if err := s.Decode("sid", b, &dst); err != errTokenRevoked {
+		t.Fatalf("Expected errTokenRevoked, got %v", err)
+	}
+
+	list.err = errors.New("unavailable")
+	if err := s.Decode("sid", b, &dst); !errors.Is(err, errRevocationFailed) {
+		t.Fatalf("Expected errRevocationFailed, got %v", err)
+	}
+}
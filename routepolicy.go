@@ -0,0 +1,82 @@
+package securecookie
+
+import (
+	"context"
+	"net/http"
+)
+
+// CookiePolicy declares how a route group reads a secure cookie.
+type CookiePolicy struct {
+	Name  string
+	Codec *SecureCookie
+	// MaxAge restricts the age of the value, in seconds, for this group. 0
+	// keeps the MaxAge of the codec.
+	MaxAge int
+	// Purpose, if set, requires the value to be encoded for it, see
+	// WithPurpose.
+	Purpose string
+	// New returns a pointer to a new value to decode the cookie into, like
+	// CookieSpec.New. Default is a pointer to a map[string]interface{}.
+	New func() interface{}
+}
+
+// PolicyMiddleware returns a middleware, e.g. for chi route groups, that
+// declares the given cookies for the routes it wraps. Cookies are only
+// decoded when a handler first reads them with FromContext, and the result is
+// cached for the rest of the request. When groups are nested, the policy of
+// the innermost group wins for a given name. The purpose and max age of a
+// policy are applied to its codec as configured when the cookie is decoded,
+// so later changes to the codec, e.g. SetKeys, take effect.
+func PolicyMiddleware(policies ...CookiePolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			results := make(map[string]*decodedCookie, len(policies))
+			if prev, ok := r.Context().Value(decodedCookiesKey).(map[string]*decodedCookie); ok {
+				for name, d := range prev {
+					results[name] = d
+				}
+			}
+			for i := range policies {
+				p := &policies[i]
+				results[p.Name] = &decodedCookie{load: p.loader(r)}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), decodedCookiesKey, results)))
+		})
+	}
+}
+
+// codec returns the codec of p with its purpose and max age applied.
+func (p *CookiePolicy) codec() *SecureCookie {
+	var c *SecureCookie
+	if p.Purpose != "" {
+		c = p.Codec.WithPurpose(p.Purpose)
+	} else {
+		cc := *p.Codec
+		c = &cc
+	}
+	if p.MaxAge != 0 {
+		c.MaxAge(p.MaxAge)
+	}
+	return c
+}
+
+// loader returns a function decoding the cookie of p sent with r.
+func (p *CookiePolicy) loader(r *http.Request) func(d *decodedCookie) {
+	return func(d *decodedCookie) {
+		if p.New != nil {
+			d.value = p.New()
+		} else {
+			d.value = &map[string]interface{}{}
+		}
+		var c *http.Cookie
+		if c, d.err = prefixedCookie(r, p.Name); d.err != nil {
+			return
+		}
+		d.name = c.Name
+		var codec *SecureCookie
+		if codec, d.err = p.codec().forRequest(r); d.err != nil {
+			return
+		}
+		d.err = codec.Decode(p.Name, c.Value, d.value)
+	}
+}
@@ -0,0 +1,111 @@
+package securecookie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPolicyMiddleware(t *testing.T) {
+	now := time.Now()
+	s := New([]byte("12345"), nil).SetClock(ClockFunc(func() time.Time { return now }))
+	admin, _ := s.WithPurpose("admin").Encode("session", testSession{User: "alice"})
+	plain, _ := s.Encode("session", testSession{User: "bob"})
+	news := 0
+	newSession := func() interface{} { news++; return &testSession{} }
+
+	outer := PolicyMiddleware(CookiePolicy{Name: "session", Codec: s, New: newSession})
+	inner := PolicyMiddleware(CookiePolicy{Name: "session", Codec: s, MaxAge: 60, Purpose: "admin", New: newSession})
+	read := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 2; i++ {
+			v, err := FromContext(r.Context(), "session")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if i == 1 {
+				_, _ = w.Write([]byte(v.(*testSession).User))
+			}
+		}
+	})
+	skip := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(h http.Handler, value string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: value})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := serve(outer(read), plain); w.Body.String() != "bob" {
+		t.Fatalf("Expected bob, got %q", w.Body.String())
+	}
+	if w := serve(outer(inner(read)), admin); w.Body.String() != "alice" {
+		t.Fatalf("Expected alice, got %q", w.Body.String())
+	}
+	if w := serve(outer(inner(read)), plain); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected a value without the purpose to fail, got %d", w.Code)
+	}
+	if news != 3 {
+		t.Fatalf("Expected 3 decodes, got %d", news)
+	}
+	serve(outer(skip), plain)
+	if news != 3 {
+		t.Fatalf("Expected no decode for unused cookies, got %d", news-3)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if w := serve(outer(inner(read)), admin); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected the group max age to apply, got %d", w.Code)
+	}
+	if w := serve(outer(read), plain); w.Body.String() != "bob" {
+		t.Fatalf("Expected bob, got %q", w.Body.String())
+	}
+}
+
+func TestPolicyMiddlewareSeveralPolicies(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	a, _ := s.WithPurpose("a").Encode("a", map[string]interface{}{"v": "A"})
+	b, _ := s.Encode("b", map[string]interface{}{"v": "B"})
+	mw := PolicyMiddleware(
+		CookiePolicy{Name: "a", Codec: s, Purpose: "a"},
+		CookiePolicy{Name: "b", Codec: s},
+	)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "a", Value: a})
+	r.AddCookie(&http.Cookie{Name: "b", Value: b})
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, want := range map[string]string{"a": "A", "b": "B"} {
+			v, err := FromContext(r.Context(), name)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if got := (*v.(*map[string]interface{}))["v"]; got != want {
+				t.Errorf("%s: expected %s, got %v", name, want, got)
+			}
+		}
+	})).ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestPolicyMiddlewareLateConfig(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	mw := PolicyMiddleware(CookiePolicy{Name: "sid", Codec: s, Purpose: "a"})
+	s.SetKeys([]byte("67890"), nil)
+	encoded, _ := s.WithPurpose("a").Encode("sid", map[string]interface{}{"v": "A"})
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "__Host-sid", Value: encoded})
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := FromContext(r.Context(), "sid"); err != nil {
+			t.Fatalf("Expected the prefixed cookie to decode with the new keys, got %v", err)
+		}
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	s.RequireTLS(true, nil)
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := FromContext(r.Context(), "sid"); err != errInsecureRequest {
+			t.Fatalf("Expected errInsecureRequest, got %v", err)
+		}
+	})).ServeHTTP(httptest.NewRecorder(), r)
+}
@@ -0,0 +1,123 @@
+package securecookie
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// samplePrefixLength is the number of bytes of a value kept in a sample.
+const samplePrefixLength = 8
+
+// FailureSample describes a value that failed to decode.
+type FailureSample struct {
+	Time time.Time
+	// Name is the cookie name passed to Decode.
+	Name string
+	// Prefix is the start of the encoded value as received, up to 8 bytes:
+	// enough to tell formats apart, not to replay the value.
+	Prefix string
+	// Length is the length of the encoded value.
+	Length int
+	// Hash is a truncated, hex-encoded SHA-256 of the encoded value, to tell
+	// whether samples are of the same value.
+	Hash string
+	// KeyID is the fingerprint of the hash key of the codec that failed; see
+	// SecureCookie.KeyID.
+	KeyID string
+	Err   error
+}
+
+// FailureSampler keeps the most recent decode failures in a fixed size ring
+// buffer, so a spike in decode errors can be analyzed after the fact. Attach
+// it to codecs with SecureCookie.SampleFailures.
+//
+// A FailureSampler is safe for concurrent use.
+type FailureSampler struct {
+	mu      sync.Mutex
+	samples []FailureSample
+	next    int
+	full    bool
+	total   uint64
+}
+
+// NewFailureSampler returns a FailureSampler keeping up to size samples.
+func NewFailureSampler(size int) *FailureSampler {
+	if size < 1 {
+		size = 1
+	}
+	return &FailureSampler{samples: make([]FailureSample, size)}
+}
+
+// Record adds a sample, overwriting the oldest one if the buffer is full.
+func (f *FailureSampler) Record(sample FailureSample) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.samples[f.next] = sample
+	f.next = (f.next + 1) % len(f.samples)
+	f.full = f.full || f.next == 0
+	f.total++
+}
+
+// Samples returns the retained samples, oldest first.
+func (f *FailureSampler) Samples() []FailureSample {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.full {
+		return append([]FailureSample(nil), f.samples[:f.next]...)
+	}
+	out := make([]FailureSample, 0, len(f.samples))
+	out = append(out, f.samples[f.next:]...)
+	return append(out, f.samples[:f.next]...)
+}
+
+// Total returns the number of failures recorded, including those no longer
+// retained.
+func (f *FailureSampler) Total() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.total
+}
+
+// Reset discards all samples.
+func (f *FailureSampler) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.samples {
+		f.samples[i] = FailureSample{}
+	}
+	f.next, f.full, f.total = 0, false, 0
+}
+
+// SampleFailures sets a sampler recording every value that fails to decode.
+// Samples never hold the whole value, which may still be valid for another
+// codec, e.g. with DecodeMulti.
+//
+// Default is nil (no sampling).
+func (s *SecureCookie) SampleFailures(f *FailureSampler) *SecureCookie {
+	s.sampler = f
+	return s
+}
+
+// sampleFailure records a decode failure, if a sampler is set.
+func (s *SecureCookie) sampleFailure(name, value string, err error) {
+	if s.sampler == nil {
+		return
+	}
+	prefix := value
+	if len(prefix) > samplePrefixLength {
+		// Copy the prefix, so the sample does not keep the value in memory.
+		prefix = string([]byte(prefix[:samplePrefixLength]))
+	}
+	sum := sha256.Sum256([]byte(value))
+	s.sampler.Record(FailureSample{
+		Time:   time.Unix(s.timestamp(), 0).UTC(),
+		Name:   name,
+		Prefix: prefix,
+		Length: len(value),
+		Hash:   hex.EncodeToString(sum[:8]),
+		KeyID:  s.KeyID(),
+		Err:    err,
+	})
+}
@@ -0,0 +1,51 @@
+package securecookie
+
+import "testing"
+
+func TestFailureSampler(t *testing.T) {
+	f := NewFailureSampler(2)
+	s := New([]byte("12345"), nil).SampleFailures(f)
+	var dst string
+	for _, v := range []string{"a", "b", "c"} {
+		if err := s.Decode("sid", v, &dst); err == nil {
+			t.Fatal("Expected decoding to fail")
+		}
+	}
+	encoded, _ := s.Encode("sid", "value")
+	if err := s.Decode("sid", encoded, &dst); err != nil {
+		t.Fatal(err)
+	}
+
+	samples := f.Samples()
+	if len(samples) != 2 || samples[0].Prefix != "b" || samples[1].Prefix != "c" {
+		t.Fatalf("Expected the two most recent failures, got %+v", samples)
+	}
+	if samples[0].Name != "sid" || samples[0].Err == nil || samples[0].Time.IsZero() {
+		t.Fatalf("Expected a complete sample, got %+v", samples[0])
+	}
+	if samples[0].Length != 1 || samples[0].Hash == "" || samples[0].Hash == samples[1].Hash {
+		t.Fatalf("Expected the length and hash of the value, got %+v", samples[0])
+	}
+	if f.Total() != 3 {
+		t.Fatalf("Expected 3 failures in total, got %d", f.Total())
+	}
+	f.Reset()
+	if len(f.Samples()) != 0 {
+		t.Fatal("Expected no samples after Reset")
+	}
+}
+
+func TestFailureSamplerValidValue(t *testing.T) {
+	f := NewFailureSampler(1)
+	old := New([]byte("12345"), nil).SampleFailures(f)
+	s := New([]byte("67890"), nil)
+	encoded, _ := s.Encode("sid", "value")
+	var dst string
+	if err := DecodeMulti("sid", encoded, &dst, old, s); err != nil {
+		t.Fatal(err)
+	}
+	samples := f.Samples()
+	if len(samples) != 1 || samples[0].Prefix != encoded[:samplePrefixLength] || samples[0].Length != len(encoded) {
+		t.Fatalf("Expected only a prefix of the value to be sampled, got %+v", samples)
+	}
+}
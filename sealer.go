@@ -0,0 +1,24 @@
+package securecookie
+
+// KeySealer seals key material to a root of trust, such as the machine TPM
+// (see TPMSealer, available with the "tpm" build tag), so that the sealed
+// form is useless when copied off the host.
+type KeySealer interface {
+	Seal(key []byte) ([]byte, error)
+	Unseal(sealed []byte) ([]byte, error)
+}
+
+// NewSealed returns a new SecureCookie like New, with a block key unsealed by
+// sealer. Use KeySealer.Seal once, at provisioning time, to produce
+// sealedBlockKey, and store only the sealed form in configuration.
+func NewSealed(hashKey, sealedBlockKey []byte, sealer KeySealer) (*SecureCookie, error) {
+	blockKey, err := sealer.Unseal(sealedBlockKey)
+	if err != nil {
+		return nil, err
+	}
+	s := New(hashKey, blockKey)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s, nil
+}
@@ -0,0 +1,40 @@
+package securecookie
+
+import (
+	"crypto/aes"
+	"testing"
+)
+
+// aesSealer seals keys with a local AES key, standing in for a TPM.
+type aesSealer struct{ key []byte }
+
+func (a aesSealer) Seal(key []byte) ([]byte, error) {
+	block, _ := aes.NewCipher(a.key)
+	return encrypt(block, append([]byte(nil), key...))
+}
+
+func (a aesSealer) Unseal(sealed []byte) ([]byte, error) {
+	block, _ := aes.NewCipher(a.key)
+	return decrypt(block, append([]byte(nil), sealed...))
+}
+
+func TestNewSealed(t *testing.T) {
+	sealer := aesSealer{key: GenerateRandomKey(32)}
+	blockKey := GenerateRandomKey(32)
+	sealed, err := sealer.Seal(blockKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSealed([]byte("12345"), sealed, sealer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := s.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err = New([]byte("12345"), blockKey).Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected the unsealed block key to be used, got %q, %v", dst, err)
+	}
+}
@@ -0,0 +1,500 @@
+package securecookie
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errSecretRollback  = Error{msg: "secret version is older than the one already loaded"}
+	errSecretMalformed = Error{msg: "secret does not contain valid keys"}
+	errTokenNotSet     = Error{msg: "secret manager token function is not set"}
+)
+
+// Secret is a version of a secret fetched from a secret manager.
+type Secret struct {
+	Value []byte
+	// Version is the version identifier assigned by the secret manager.
+	Version string
+	// Sequence orders versions: a newer version has a greater sequence. It is
+	// used for rollback protection.
+	Sequence int64
+}
+
+// SecretSource fetches key material from a secret manager.
+type SecretSource interface {
+	FetchSecret(ctx context.Context) (*Secret, error)
+}
+
+// keyPair is the JSON form of a hash and block key pair stored in a secret.
+// []byte values are base64-encoded.
+type keyPair struct {
+	HashKey  []byte `json:"hash_key"`
+	BlockKey []byte `json:"block_key,omitempty"`
+}
+
+const (
+	pemHashKey  = "SECURECOOKIE HASH KEY"
+	pemBlockKey = "SECURECOOKIE BLOCK KEY"
+)
+
+// parseKeyPair parses a key pair in JSON form or as PEM blocks of type
+// "SECURECOOKIE HASH KEY" and "SECURECOOKIE BLOCK KEY".
+func parseKeyPair(data []byte) (*keyPair, error) {
+	pair := &keyPair{}
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("-----BEGIN")) {
+		for rest := trimmed; ; {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			}
+			switch block.Type {
+			case pemHashKey:
+				pair.HashKey = block.Bytes
+			case pemBlockKey:
+				pair.BlockKey = block.Bytes
+			}
+		}
+	} else if err := json.Unmarshal(data, pair); err != nil {
+		return nil, errSecretMalformed
+	}
+	if len(pair.HashKey) == 0 {
+		return nil, errSecretMalformed
+	}
+	return pair, nil
+}
+
+// SecretKeyLoader loads keys from a SecretSource at startup and on a refresh
+// interval. It implements Codec using the keys loaded so far.
+//
+// The secret holds either a key pair, as a JSON object with base64-encoded
+// "hash_key" and optional "block_key" members or as PEM blocks of type
+// "SECURECOOKIE HASH KEY" and "SECURECOOKIE BLOCK KEY", or, if a passphrase
+// is set, a whole keyring sealed with Keyring.MarshalEncrypted. When a new key
+// pair version is loaded it becomes the active key and previous versions are
+// retired, so values they encoded still decode, up to the number set with
+// Grace; a sealed keyring replaces the keys of the previous one.
+//
+// Versions older than the one already loaded are rejected, so a compromised
+// or misconfigured secret manager cannot roll keys back.
+//
+// A SecretKeyLoader is safe for concurrent use.
+type SecretKeyLoader struct {
+	source     SecretSource
+	passphrase []byte
+
+	mu       sync.RWMutex
+	keyring  *Keyring
+	loaded   *Secret
+	grace    int
+	versions []string
+}
+
+// NewSecretKeyLoader returns a SecretKeyLoader reading keys from source. If
+// passphrase is not nil, the secret must hold a sealed keyring opened with
+// it. Call Load or Watch to load the keys.
+func NewSecretKeyLoader(source SecretSource, passphrase []byte) *SecretKeyLoader {
+	return &SecretKeyLoader{
+		source:     source,
+		passphrase: passphrase,
+		keyring:    NewKeyring(),
+		grace:      1,
+	}
+}
+
+// Configure sets a function applied to every SecureCookie built from the
+// loaded keys. See Keyring.Configure.
+func (l *SecretKeyLoader) Configure(f func(*SecureCookie)) *SecretKeyLoader {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.keyring.Configure(f)
+	return l
+}
+
+// Grace sets how many previous key pair versions still decode after a new
+// one is loaded. Older versions are removed from the keyring, so keys rotated
+// out of the secret stop being accepted. It does not apply to sealed
+// keyrings, which carry their own keys.
+//
+// Default is 1.
+func (l *SecretKeyLoader) Grace(n int) *SecretKeyLoader {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	l.grace = n
+	return l
+}
+
+// Keyring returns the keyring holding the loaded keys. It is updated in place
+// by Load and must be treated as read-only.
+func (l *SecretKeyLoader) Keyring() *Keyring {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.keyring
+}
+
+// Version returns the version of the most recently loaded secret, or an
+// empty string if none was loaded.
+func (l *SecretKeyLoader) Version() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.loaded == nil {
+		return ""
+	}
+	return l.loaded.Version
+}
+
+// Load fetches the secret and loads its keys if it is newer than the one
+// already loaded.
+func (l *SecretKeyLoader) Load(ctx context.Context) error {
+	secret, err := l.source.FetchSecret(ctx)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.loaded != nil {
+		if secret.Sequence < l.loaded.Sequence {
+			return fmt.Errorf("%w: %s", errSecretRollback, secret.Version)
+		}
+		if secret.Sequence == l.loaded.Sequence {
+			return nil
+		}
+	}
+	if l.passphrase != nil {
+		k, err := LoadKeyring(secret.Value, l.passphrase)
+		if err != nil {
+			return err
+		}
+		l.keyring.replace(k)
+	} else {
+		pair, err := parseKeyPair(secret.Value)
+		if err != nil {
+			return err
+		}
+		// A file may revert to content loaded before, which is still known.
+		err = l.keyring.Add(secret.Version, pair.HashKey, pair.BlockKey)
+		if err != nil && err != errKeyIDDuplicate {
+			return err
+		}
+		if err := l.keyring.Promote(secret.Version); err != nil {
+			return err
+		}
+		l.retire(secret.Version)
+	}
+	l.loaded = secret
+	return nil
+}
+
+// retire records version as the latest loaded and removes the versions
+// beyond the grace set from the keyring.
+func (l *SecretKeyLoader) retire(version string) {
+	versions := l.versions[:0]
+	for _, v := range l.versions {
+		if v != version {
+			versions = append(versions, v)
+		}
+	}
+	versions = append(versions, version)
+	for len(versions) > l.grace+1 {
+		_ = l.keyring.Remove(versions[0])
+		versions = versions[1:]
+	}
+	l.versions = versions
+}
+
+// Watch calls Load every interval until ctx is done. Errors are passed to
+// onError, which may be nil; the previously loaded keys stay in use.
+func (l *SecretKeyLoader) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Load(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Encode encodes a cookie value using the active loaded key.
+func (l *SecretKeyLoader) Encode(name string, value interface{}) (string, error) {
+	return l.Keyring().Encode(name, value)
+}
+
+// Decode decodes a cookie value trying every loaded key.
+func (l *SecretKeyLoader) Decode(name, value string, dst interface{}) error {
+	return l.Keyring().Decode(name, value, dst)
+}
+
+// getJSON performs an authenticated GET request and decodes the JSON
+// response into out.
+func getJSON(ctx context.Context, client *http.Client, url, token string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("securecookie: GET %s: status %d", url, res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// GCP ------------------------------------------------------------------------
+
+// GCPSecretManager is a SecretSource reading a secret from Google Cloud Secret
+// Manager through its REST API.
+type GCPSecretManager struct {
+	Project string
+	Secret  string
+	// Version pins a version number. Default is "latest".
+	Version string
+	// Token returns an OAuth2 access token, e.g. from
+	// golang.org/x/oauth2/google.DefaultTokenSource. It is required.
+	Token func(ctx context.Context) (string, error)
+	// Client is the HTTP client used. Default is http.DefaultClient.
+	Client *http.Client
+	// Endpoint overrides the API endpoint, e.g. for testing.
+	Endpoint string
+}
+
+// FetchSecret implements SecretSource. The version number is used as the
+// sequence.
+func (g *GCPSecretManager) FetchSecret(ctx context.Context) (*Secret, error) {
+	if g.Token == nil {
+		return nil, errTokenNotSet
+	}
+	token, err := g.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, version := g.Endpoint, g.Version
+	if endpoint == "" {
+		endpoint = "https://secretmanager.googleapis.com"
+	}
+	if version == "" {
+		version = "latest"
+	}
+	u := fmt.Sprintf("%s/v1/projects/%s/secrets/%s/versions/%s:access",
+		strings.TrimSuffix(endpoint, "/"), url.PathEscape(g.Project), url.PathEscape(g.Secret), url.PathEscape(version))
+	var resp struct {
+		Name    string `json:"name"`
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err = getJSON(ctx, g.Client, u, token, &resp); err != nil {
+		return nil, err
+	}
+	value, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return nil, errSecretMalformed
+	}
+	version = resp.Name[strings.LastIndex(resp.Name, "/")+1:]
+	seq, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return nil, errSecretMalformed
+	}
+	return &Secret{Value: value, Version: version, Sequence: seq}, nil
+}
+
+// Azure ----------------------------------------------------------------------
+
+// AzureKeyVault is a SecretSource reading a secret from Azure Key Vault
+// through its REST API. The secret value is used as is.
+type AzureKeyVault struct {
+	// VaultURL is the vault URL, e.g. "https://myvault.vault.azure.net".
+	VaultURL string
+	Name     string
+	// Version pins a version. Default is the current version.
+	Version string
+	// Token returns an access token for the https://vault.azure.net scope. It
+	// is required.
+	Token func(ctx context.Context) (string, error)
+	// Client is the HTTP client used. Default is http.DefaultClient.
+	Client *http.Client
+}
+
+// FetchSecret implements SecretSource. The creation time of the version is
+// used as the sequence.
+func (a *AzureKeyVault) FetchSecret(ctx context.Context) (*Secret, error) {
+	if a.Token == nil {
+		return nil, errTokenNotSet
+	}
+	token, err := a.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/secrets/%s", strings.TrimSuffix(a.VaultURL, "/"), url.PathEscape(a.Name))
+	if a.Version != "" {
+		u += "/" + url.PathEscape(a.Version)
+	}
+	u += "?api-version=7.4"
+	var resp struct {
+		Value      string `json:"value"`
+		ID         string `json:"id"`
+		Attributes struct {
+			Created int64 `json:"created"`
+		} `json:"attributes"`
+	}
+	if err = getJSON(ctx, a.Client, u, token, &resp); err != nil {
+		return nil, err
+	}
+	return &Secret{
+		Value:    []byte(resp.Value),
+		Version:  resp.ID[strings.LastIndex(resp.ID, "/")+1:],
+		Sequence: resp.Attributes.Created,
+	}, nil
+}
+
+// Files ----------------------------------------------------------------------
+
+// FileSecret is a SecretSource reading a local file, such as a Kubernetes
+// secret volume. Every change of the file content is a new version.
+type FileSecret struct {
+	Path string
+
+	mu   sync.Mutex
+	sum  [sha256.Size]byte
+	seq  int64
+	seen bool
+}
+
+// NewFileKeyLoader returns a SecretKeyLoader reading keys from the file at
+// path. See SecretKeyLoader for the file formats. Call Watch to swap keys
+// without restarting when the file changes.
+func NewFileKeyLoader(path string, passphrase []byte) *SecretKeyLoader {
+	return NewSecretKeyLoader(&FileSecret{Path: path}, passphrase)
+}
+
+// FetchSecret implements SecretSource. The version is a hash of the content.
+func (f *FileSecret) FetchSecret(ctx context.Context) (*Secret, error) {
+	f.mu.Lock()
+	path := f.Path
+	f.mu.Unlock()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.seen || sum != f.sum {
+		f.sum, f.seen = sum, true
+		f.seq++
+	}
+	return &Secret{
+		Value:    data,
+		Version:  hex.EncodeToString(sum[:8]),
+		Sequence: f.seq,
+	}, nil
+}
+
+var (
+	errIdentityNotSet = Error{msg: "decryption identity is not set"}
+	errDecryptNotSet  = Error{msg: "decryption function is not set"}
+)
+
+// EncryptedFileSecret is a SecretSource reading a file encrypted with a tool
+// such as age or SOPS, so plaintext keys never sit on disk. The file is
+// decrypted in memory every time it changes, using an identity read from an
+// environment variable or a file.
+//
+// Decryption is delegated to the tool's library. With filippo.io/age:
+//
+//	src := &securecookie.EncryptedFileSecret{
+//		Path:        "/etc/app/cookie-keys.age",
+//		IdentityEnv: "AGE_IDENTITY",
+//		Decrypt: func(ciphertext, identity []byte) ([]byte, error) {
+//			ids, err := age.ParseIdentities(bytes.NewReader(identity))
+//			if err != nil {
+//				return nil, err
+//			}
+//			r, err := age.Decrypt(bytes.NewReader(ciphertext), ids...)
+//			if err != nil {
+//				return nil, err
+//			}
+//			return io.ReadAll(r)
+//		},
+//	}
+//	loader := securecookie.NewSecretKeyLoader(src, nil)
+//
+// SOPS reads its identity itself, so IdentityEnv and IdentityFile may be left
+// empty and Decrypt can call decrypt.Data, ignoring the identity argument.
+type EncryptedFileSecret struct {
+	Path string
+	// Decrypt decrypts the file content using identity. It is required.
+	Decrypt func(ciphertext, identity []byte) ([]byte, error)
+	// IdentityEnv is the environment variable holding the identity. It takes
+	// precedence over IdentityFile.
+	IdentityEnv string
+	// IdentityFile is the path of a file holding the identity.
+	IdentityFile string
+
+	file FileSecret
+}
+
+// FetchSecret implements SecretSource. The version is a hash of the
+// encrypted content.
+func (e *EncryptedFileSecret) FetchSecret(ctx context.Context) (*Secret, error) {
+	if e.Decrypt == nil {
+		return nil, errDecryptNotSet
+	}
+	e.file.mu.Lock()
+	e.file.Path = e.Path
+	e.file.mu.Unlock()
+	secret, err := e.file.FetchSecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := e.identity()
+	if err != nil {
+		return nil, err
+	}
+	if secret.Value, err = e.Decrypt(secret.Value, identity); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+func (e *EncryptedFileSecret) identity() ([]byte, error) {
+	if e.IdentityEnv != "" {
+		if v := os.Getenv(e.IdentityEnv); v != "" {
+			return []byte(v), nil
+		}
+	}
+	if e.IdentityFile != "" {
+		return os.ReadFile(e.IdentityFile)
+	}
+	if e.IdentityEnv != "" {
+		return nil, fmt.Errorf("%w: %s", errIdentityNotSet, e.IdentityEnv)
+	}
+	return nil, nil
+}
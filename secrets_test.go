@@ -0,0 +1,238 @@
+package securecookie
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSecretKeyLoaderGCP(t *testing.T) {
+	version := 7
+	pairs := map[int][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if pairs[version] == nil {
+			pairs[version], _ = json.Marshal(keyPair{HashKey: GenerateRandomKey(32)})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"name":    fmt.Sprintf("projects/p/secrets/cookies/versions/%d", version),
+			"payload": map[string]string{"data": base64.StdEncoding.EncodeToString(pairs[version])},
+		})
+	}))
+	defer srv.Close()
+
+	l := NewSecretKeyLoader(&GCPSecretManager{
+		Project:  "p",
+		Secret:   "cookies",
+		Token:    func(context.Context) (string, error) { return "token", nil },
+		Endpoint: srv.URL,
+	}, nil)
+	ctx := context.Background()
+	if err := l.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := l.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version = 8
+	if err = l.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if l.Version() != "8" {
+		t.Fatalf("Expected version 8, got %s", l.Version())
+	}
+	var dst string
+	if err = l.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected previous version to decode, got %q, %v", dst, err)
+	}
+
+	version = 7
+	if err = l.Load(ctx); !errors.Is(err, errSecretRollback) {
+		t.Fatalf("Expected errSecretRollback, got %v", err)
+	}
+}
+
+func TestFileKeyLoader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.pem")
+	write := func() {
+		var buf bytes.Buffer
+		_ = pem.Encode(&buf, &pem.Block{Type: pemHashKey, Bytes: GenerateRandomKey(32)})
+		_ = pem.Encode(&buf, &pem.Block{Type: pemBlockKey, Bytes: GenerateRandomKey(32)})
+		if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write()
+	l := NewFileKeyLoader(path, nil)
+	ctx := context.Background()
+	if err := l.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := l.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := l.Version()
+
+	// Loading unchanged content is a no-op.
+	if err = l.Load(ctx); err != nil || l.Version() != first {
+		t.Fatalf("Expected version %s, got %s, %v", first, l.Version(), err)
+	}
+	write()
+	if err = l.Load(ctx); err != nil || l.Version() == first {
+		t.Fatalf("Expected a new version, got %s, %v", l.Version(), err)
+	}
+	var dst string
+	if err = l.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected previous keys to decode, got %q, %v", dst, err)
+	}
+}
+
+func TestEncryptedFileSecret(t *testing.T) {
+	identity := []byte("0123456789abcdef0123456789abcdef")
+	block, _ := aes.NewCipher(identity)
+	plaintext, _ := json.Marshal(keyPair{HashKey: GenerateRandomKey(32)})
+	ciphertext, _ := encrypt(block, plaintext)
+	path := filepath.Join(t.TempDir(), "keys.enc")
+	if err := os.WriteFile(path, ciphertext, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	src := &EncryptedFileSecret{
+		Path:        path,
+		IdentityEnv: "TEST_COOKIE_IDENTITY",
+		Decrypt: func(ciphertext, identity []byte) ([]byte, error) {
+			block, err := aes.NewCipher(identity)
+			if err != nil {
+				return nil, err
+			}
+			return decrypt(block, ciphertext)
+		},
+	}
+	l := NewSecretKeyLoader(src, nil)
+	if err := l.Load(context.Background()); !errors.Is(err, errIdentityNotSet) {
+		t.Fatalf("Expected errIdentityNotSet, got %v", err)
+	}
+	t.Setenv("TEST_COOKIE_IDENTITY", string(identity))
+	if err := l.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Encode("sid", "value"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSecretSourceMissingFunc(t *testing.T) {
+	for _, src := range []SecretSource{&GCPSecretManager{}, &AzureKeyVault{}} {
+		if _, err := src.FetchSecret(context.Background()); err != errTokenNotSet {
+			t.Errorf("%T: expected errTokenNotSet, got %v", src, err)
+		}
+	}
+	if _, err := (&EncryptedFileSecret{}).FetchSecret(context.Background()); err != errDecryptNotSet {
+		t.Errorf("Expected errDecryptNotSet, got %v", err)
+	}
+}
+
+func TestEncryptedFileSecretConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	src := &EncryptedFileSecret{
+		Path:    path,
+		Decrypt: func(ciphertext, _ []byte) ([]byte, error) { return ciphertext, nil },
+	}
+	done := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func() {
+			_, err := src.FetchSecret(context.Background())
+			done <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// secretFunc is a SecretSource returning f().
+type secretFunc func() *Secret
+
+func (f secretFunc) FetchSecret(ctx context.Context) (*Secret, error) {
+	return f(), nil
+}
+
+func TestSecretKeyLoaderGrace(t *testing.T) {
+	var seq int64
+	l := NewSecretKeyLoader(secretFunc(func() *Secret {
+		value, _ := json.Marshal(keyPair{HashKey: GenerateRandomKey(32)})
+		return &Secret{Value: value, Version: fmt.Sprint(seq), Sequence: seq}
+	}), nil)
+	ctx := context.Background()
+	var encoded []string
+	for seq = 1; seq <= 3; seq++ {
+		if err := l.Load(ctx); err != nil {
+			t.Fatal(err)
+		}
+		value, _ := l.Encode("sid", "value")
+		encoded = append(encoded, value)
+	}
+	if keys := l.Keyring().Keys(); len(keys) != 2 {
+		t.Fatalf("Expected the active key and one grace key, got %v", keys)
+	}
+	var dst string
+	if err := l.Decode("sid", encoded[0], &dst); err == nil {
+		t.Fatal("Expected a version rotated out of the grace set to be rejected")
+	}
+	if err := l.Decode("sid", encoded[1], &dst); err != nil {
+		t.Fatalf("Expected the previous version to decode, got %v", err)
+	}
+
+	l.Grace(0)
+	if err := l.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if keys := l.Keyring().Keys(); len(keys) != 1 {
+		t.Fatalf("Expected only the active key, got %v", keys)
+	}
+}
+
+func TestSecretKeyLoaderSealedInPlace(t *testing.T) {
+	passphrase := []byte("passphrase")
+	var seq int64
+	l := NewSecretKeyLoader(secretFunc(func() *Secret {
+		k := NewKeyring()
+		_ = k.Add(fmt.Sprint(seq), GenerateRandomKey(32), nil)
+		sealed, _ := k.MarshalEncrypted(passphrase)
+		return &Secret{Value: sealed, Version: fmt.Sprint(seq), Sequence: seq}
+	}), passphrase)
+	ctx := context.Background()
+	seq = 1
+	if err := l.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	k := l.Keyring()
+	seq = 2
+	if err := l.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if keys := k.Keys(); len(keys) != 1 || keys[0].ID != "2" {
+		t.Fatalf("Expected the keyring to be updated in place, got %v", keys)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
@@ -19,7 +18,9 @@ import (
 	"hash"
 	"io"
 	"strings"
+	"sync/atomic"
 	"time"
+	"unsafe"
 )
 
 type Error struct {
@@ -50,6 +51,8 @@ var (
 	errTimestampTooNew       = Error{msg: "cookie timestamp is too new"}
 	errTimestampExpired      = Error{msg: "cookie timestamp is too old"}
 	errDecryptionFailed      = Error{msg: "the value could not be decrypted"}
+	errBase64DecodeFailed    = Error{msg: "base64 decode failed"}
+	errDeserializeFailed     = Error{msg: "the value could not be deserialized"}
 	errValueNotByte          = Error{msg: "value not a []byte."}
 	errValueNotBytePtr       = Error{msg: "value not a pointer to []byte."}
 
@@ -76,20 +79,26 @@ type Codec interface {
 // GenerateRandomKey(). The key length must correspond to the key size
 // of the encryption algorithm. For AES, used by default, valid lengths are
 // 16, 24, or 32 bytes to select AES-128, AES-192, or AES-256.
-// The default encoder used for cookie serialization is encoding/gob.
+// The serializer is DefaultSerializer, JSONEncoder unless changed.
+//
+// The keys are copied, so the caller may wipe its own copies; see also Close.
 //
 // Note that keys created using GenerateRandomKey() are not automatically
 // persisted. New keys will be created when the application is restarted, and
 // previously issued cookies will not be able to be decoded.
 func New(hashKey, blockKey []byte) *SecureCookie {
 	cookie := &SecureCookie{
-		hashKey:   hashKey,
+		hashKey:   cloneKey(hashKey),
+		closed:    new(atomic.Bool),
 		hashFunc:  sha256.New,
-		blockKey:  blockKey,
+		blockKey:  cloneKey(blockKey),
 		maxLength: 4096,
 		maxAge:    86400 * 30,
-		sz:        JSONEncoder{},
+		sz:        DefaultSerializer,
 		hmacSize:  sha256.Size,
+
+		maxDecompressedSize: defaultMaxDecompressedSize,
+		maxExpansionRatio:   defaultMaxExpansionRatio,
 	}
 	if len(hashKey) == 0 {
 		panic(errHashKeyNotSet)
@@ -103,19 +112,63 @@ func New(hashKey, blockKey []byte) *SecureCookie {
 // SecureCookie encodes and decodes authenticated and optionally encrypted
 // cookie values.
 type SecureCookie struct {
-	hashKey   []byte
-	hashFunc  func() hash.Hash
-	blockKey  []byte
-	block     cipher.Block
-	maxLength int
-	maxAge    int64
-	minAge    int64
-	err       error
-	sz        Serializer
-	hmacSize  int
+	hashKey           []byte
+	closed            *atomic.Bool
+	hashFunc          func() hash.Hash
+	blockKey          []byte
+	block             cipher.Block
+	blockFunc         func([]byte) (cipher.Block, error)
+	maxLength         int
+	maxAge            int64
+	minAge            int64
+	err               error
+	sz                Serializer
+	hmacSize          int
+	maxPayloadKeys    int
+	maxPayloadDepth   int
+	trace             bool
+	sampler           *FailureSampler
+	prefix            string
+	shadow            Serializer
+	shadowReport      func(ShadowReport)
+	flags             uint16
+	fips              bool
+	compressor        Compressor
+	encoding          TextEncoding
+	strictEncoding    bool
+	millis            bool
+	leeway            time.Duration
+	grace             time.Duration
+	absoluteTimeout   time.Duration
+	tokenIDs          bool
+	revocation        RevocationChecker
+	revocationTimeout time.Duration
+	consume           ConsumeStore
+	consumeTimeout    time.Duration
+	bound             []byte
+	audience          string
+	clientCert        bool
+	requireTLS        bool
+	proxyTrust        *ProxyTrust
+	mac               hash.Hash
+	version           byte
+	legacyDecode      bool
+	legacyEncode      bool
+	overflow          OverflowPolicy
+	storage           Storage
+	storageTimeout    time.Duration
+	// Decompression limits, see MaxDecompressedSize and MaxExpansionRatio.
+	maxDecompressedSize int
+	maxExpansionRatio   int
+	// The clock, see SetClock.
+	clock Clock
 	// For testing purposes, the function that returns the current timestamp.
-	// If not set, it will use time.Now().UTC().Unix().
+	// If not set, it will use the clock.
 	timeFunc func() int64
+	// The error of err caused by the keys, which SetKeys clears, and whether
+	// ValidateKeys is strict.
+	keyErr     error
+	strictKeys bool
 }
 
 // MaxLength restricts the maximum length, in bytes, for the cookie value.
@@ -147,6 +200,10 @@ func (s *SecureCookie) MinAge(value int) *SecureCookie {
 // Default is crypto/sha256.New.
 func (s *SecureCookie) HashFunc(f func() hash.Hash) *SecureCookie {
 	s.hashFunc = f
+	s.hmacSize = f().Size()
+	if s.fips && !fipsHash(f) {
+		s.err = errFIPSHash
+	}
 	return s
 }
 
@@ -155,19 +212,22 @@ func (s *SecureCookie) HashFunc(f func() hash.Hash) *SecureCookie {
 // Default is crypto/aes.New.
 func (s *SecureCookie) BlockFunc(f func([]byte) (cipher.Block, error)) *SecureCookie {
 	if s.blockKey == nil {
-		s.err = errBlockKeyNotSet
+		s.setKeyErr(errBlockKeyNotSet)
 	} else if block, err := f(s.blockKey); err == nil {
 		s.block = block
+		s.blockFunc = f
+		if s.fips && !fipsBlock(block) {
+			s.err = errFIPSCipher
+		}
 	} else {
-		s.err = err
+		s.setKeyErr(err)
 	}
 	return s
 }
 
 // Encoding sets the encoding/serialization method for cookies.
 //
-// Default is encoding/gob.  To encode special structures using encoding/gob,
-// they must be registered first using gob.Register().
+// Default is DefaultSerializer.
 func (s *SecureCookie) SetSerializer(sz Serializer) *SecureCookie {
 	s.sz = sz
 
@@ -185,50 +245,174 @@ func (s *SecureCookie) SetSerializer(sz Serializer) *SecureCookie {
 //
 // It is the client's responsibility to ensure that value, when encoded using
 // the current serialization/encryption settings on s and then base64-encoded,
-// is shorter than the maximum permissible length.
+// is shorter than the maximum permissible length; see EstimatedLength.
 func (s *SecureCookie) Encode(name string, value interface{}) (string, error) {
+	return s.EncodeWith(name, value)
+}
+
+// EncodeWith encodes a cookie value like Encode, with options for this value
+// only, such as NotBefore.
+func (s *SecureCookie) EncodeWith(name string, value interface{}, opts ...EncodeOption) (string, error) {
+	var o *encodeOptions
+	if len(opts) > 0 {
+		o = &encodeOptions{}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+	out, err := s.encodeValue(name, value, o)
+	if err != nil {
+		return "", err
+	}
+	// 5. Check length.
+	if s.maxLength != 0 && len(out) > s.maxLength {
+		return "", s.lengthError(errEncodedValueTooLong, name, len(out))
+	}
+	// Done.
+	return string(out), nil
+}
+
+// EstimatedLength returns the length of the value Encode would return for
+// the given name and value, even if it exceeds MaxLength, so callers can trim
+// the value beforehand. It performs a full encoding, and the result is exact
+// unless the serializer output varies between calls.
+func (s *SecureCookie) EstimatedLength(name string, value interface{}) (int, error) {
+	out, err := s.encodeValue(name, value, nil)
+	if err != nil {
+		return 0, err
+	}
+	return len(out), nil
+}
+
+// encodeValue encodes a cookie value, without checking its length. o may be
+// nil.
+func (s *SecureCookie) encodeValue(name string, value interface{}, o *encodeOptions) ([]byte, error) {
+	if s.isClosed() {
+		return nil, errCodecClosed
+	}
 	if s.err != nil {
-		return "", s.err
+		return nil, s.err
 	}
 	if s.hashKey == nil {
-		s.err = errHashKeyNotSet
-		return "", s.err
+		s.setKeyErr(errHashKeyNotSet)
+		return nil, s.err
 	}
+	if err := s.checkPayload(value); err != nil {
+		return nil, err
+	}
+	if s.flags&flagNoTimestamp != 0 && (s.minAge != 0 || s.maxAge != 0) {
+		return nil, errTimestampRequired
+	}
+	tr := s.startTrace("securecookie.Encode")
+	defer tr.end()
 	// 1. Serialize.
+	endRegion := tr.region("serialize")
 	data, err := s.sz.Serialize(value)
+	endRegion()
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if s.legacyEncode {
+		if o != nil {
+			return nil, errLegacyOption
+		}
+		out, err := s.encodeLegacy(name, data)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(s.prefix), out...), nil
+	}
+	flags := s.flags
+	if o != nil && o.notBefore != 0 {
+		flags |= flagNotBefore
+	}
+	if s.absoluteTimeout != 0 && (o == nil || o.expiry == 0) {
+		if o == nil {
+			o = &encodeOptions{}
+		}
+		o.expiry = s.timestamp() + int64(s.absoluteTimeout/time.Second)
+	}
+	if o != nil && o.expiry != 0 {
+		flags |= flagExpiry
+	}
+	if s.tokenIDs {
+		flags |= flagTokenID
+	}
+	if s.compressor != nil {
+		endRegion = tr.region("compress")
+		compressed, err := compress(s.compressor, data)
+		endRegion()
+		if err != nil {
+			return nil, err
+		}
+		if len(compressed) < len(data) {
+			data = compressed
+			flags |= flagCompressed
+		}
 	}
 	// 2. Encrypt (optional).
 	if s.block != nil {
-		if data, err = encrypt(s.block, data); err != nil {
-			return "", err
+		endRegion = tr.region("encrypt")
+		data, err = encrypt(s.block, data)
+		endRegion()
+		if err != nil {
+			return nil, err
 		}
 	}
-	buf := new(bytes.Buffer)
 	name = s.sanitizeName(name)
-	if err = binary.Write(buf, binary.LittleEndian, uint16(len(name))); err != nil {
-		return "", err
+	if len(name) > maxNameSize {
+		return nil, errNameTooLong
+	}
+	if s.overflow == OverflowSpill && s.maxLength != 0 && s.encodedLen(name, flags, len(data)) > s.maxLength {
+		if s.storage == nil {
+			return nil, errSpillNotConfigured
+		}
+		endRegion = tr.region("spill")
+		data, err = s.spill(data)
+		endRegion()
+		if err != nil {
+			return nil, err
+		}
+		flags |= flagSpilled
+	}
+	buf := new(bytes.Buffer)
+	if err = binary.Write(buf, binary.LittleEndian, uint16(len(name))|flags); err != nil {
+		return nil, err
 	}
-	now := s.timestamp()
 	buf.WriteString(name)
-	if err = binary.Write(buf, binary.LittleEndian, uint64(now)); err != nil {
-		return "", err
+	if flags&flagNoTimestamp == 0 {
+		if err = binary.Write(buf, binary.LittleEndian, s.encodeTimestamp()); err != nil {
+			return nil, err
+		}
+	}
+	if flags&flagNotBefore != 0 {
+		if err = binary.Write(buf, binary.LittleEndian, uint64(o.notBefore)); err != nil {
+			return nil, err
+		}
+	}
+	if flags&flagExpiry != 0 {
+		if err = binary.Write(buf, binary.LittleEndian, uint64(o.expiry)); err != nil {
+			return nil, err
+		}
+	}
+	if flags&flagTokenID != 0 {
+		id := GenerateRandomKey(tokenIDSize)
+		if id == nil {
+			return nil, errGeneratingIV
+		}
+		buf.Write(id)
 	}
 	buf.Write(data)
 	payload := buf.Bytes()
-	mac := createMac(hmac.New(s.hashFunc, s.hashKey), payload)
+	endRegion = tr.region("mac")
+	out := s.seal(payload)
+	endRegion()
 	// 4. Encode to base64.
-	out := make([]byte, len(payload)+len(mac))
-	copy(out[:len(mac)], mac)
-	copy(out[len(mac):], payload)
-	out = encode(out)
-	// 5. Check length.
-	if s.maxLength != 0 && len(out) > s.maxLength {
-		return "", fmt.Errorf("%w: %d", errEncodedValueTooLong, len(out))
+	out = encode(s.textEncoding(), out)
+	if s.prefix != "" {
+		out = append([]byte(s.prefix), out...)
 	}
-	// Done.
-	return string(out), nil
+	return out, nil
 }
 
 // Decode decodes a cookie value.
@@ -240,55 +424,182 @@ func (s *SecureCookie) Encode(name string, value interface{}) (string, error) {
 // it was stored. The value argument is the encoded cookie value. The dst
 // argument is where the cookie will be decoded. It must be a pointer.
 func (s *SecureCookie) Decode(name, value string, dst interface{}) error {
+	err := s.decode(name, value, dst, s.maxLength, nil)
+	if err != nil {
+		s.sampleFailure(name, value, err)
+	}
+	return err
+}
+
+// DecodeBytes decodes a cookie value held in a byte slice, e.g. by fasthttp,
+// like Decode but without copying it. value is not retained after the call,
+// unless a FailureSampler is set, in which case it is copied.
+func (s *SecureCookie) DecodeBytes(name string, value []byte, dst interface{}) error {
+	if s.sampler != nil || len(value) == 0 {
+		return s.Decode(name, string(value), dst)
+	}
+	return s.Decode(name, unsafe.String(&value[0], len(value)), dst)
+}
+
+// DecodeWithNames decodes a cookie value encoded under any of the given
+// names, e.g. the current name followed by the names the cookie had before
+// being renamed, as the name is authenticated with the value. Names are
+// tried in order, and the error for the first name is returned if none
+// matches.
+func (s *SecureCookie) DecodeWithNames(names []string, value string, dst interface{}) error {
+	if len(names) == 0 {
+		return errNameIsUnexpected
+	}
+	var first error
+	for _, name := range names {
+		err := s.decode(name, value, dst, s.maxLength, nil)
+		if err == nil {
+			return nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	s.sampleFailure(names[0], value, first)
+	return first
+}
+
+// decode decodes a cookie value of at most maxLength bytes, or of any length
+// if maxLength is 0. If info is not nil, it is filled with the fields of the
+// value.
+func (s *SecureCookie) decode(name, value string, dst interface{}, maxLength int, info *valueInfo) error {
+	if s.isClosed() {
+		return errCodecClosed
+	}
 	if s.err != nil {
 		return s.err
 	}
 	if s.hashKey == nil {
-		s.err = errHashKeyNotSet
+		s.setKeyErr(errHashKeyNotSet)
 		return s.err
 	}
+	rawName := name
 	name = s.sanitizeName(name)
 	// 1. Check length.
-	if s.maxLength != 0 && len(value) > s.maxLength {
-		return fmt.Errorf("%w: %d", errValueToDecodeTooLong, len(value))
+	if maxLength != 0 && len(value) > maxLength {
+		return s.lengthError(errValueToDecodeTooLong, name, len(value))
 	}
+	if !strings.HasPrefix(value, s.prefix) {
+		return errValuePrefixUnknown
+	}
+	value = value[len(s.prefix):]
+	if s.legacyDecode {
+		if b, parts, ok := s.legacyParts(value); ok {
+			return s.decodeLegacy(rawName, b, parts, dst, info)
+		}
+	}
+	tr := s.startTrace("securecookie.Decode")
+	defer tr.end()
 	// 2. Decode from base64.
-	b, err := decode([]byte(value))
+	b, err := decode(s.textEncoding(), []byte(value))
 	if err != nil {
 		return err
 	}
-	if len(b) <= s.hmacSize {
-		return errValueToDecodeTooSmall
+	if s.strictEncoding && !bytes.Equal(encode(s.textEncoding(), b), []byte(value)) {
+		return errEncodingNotCanonical
 	}
-	mac, payload := b[:s.hmacSize], b[s.hmacSize:]
-	h := hmac.New(s.hashFunc, s.hashKey)
-	if err = verifyMac(h, payload, mac); err != nil {
+	endRegion := tr.region("mac")
+	payload, version, err := s.open(b)
+	endRegion()
+	if err != nil {
 		return err
 	}
-	nameLen := binary.LittleEndian.Uint16(payload[:2])
+	header := binary.LittleEndian.Uint16(payload[:2])
+	nameLen := int(header &^ flagsMask)
+	tsLen := timestampLen(header)
+	if len(payload) < 2+nameLen+fieldsLen(header) {
+		return errValueToDecodeTooSmall
+	}
 	n := string(payload[2 : 2+nameLen])
-	ts := int64(binary.LittleEndian.Uint64(payload[2+nameLen:]))
-	data := payload[2+nameLen+8:]
+	data := payload[2+nameLen+fieldsLen(header):]
 	if n != name {
 		return fmt.Errorf("%w: %s", errNameIsUnexpected, name)
 	}
 	// 4. Verify date ranges.
-	now := s.timestamp()
-	if s.minAge != 0 && s.minAge > now-ts {
-		return errTimestampTooNew
+	if tsLen == 0 {
+		if s.minAge != 0 || s.maxAge != 0 {
+			return errTimestampMissing
+		}
+	} else if err = s.checkAge(binary.LittleEndian.Uint64(payload[2+nameLen:])); err != nil {
+		return err
+	}
+	if info == nil {
+		info = &valueInfo{}
 	}
-	if s.maxAge != 0 && s.maxAge < now-ts {
-		return errTimestampExpired
+	info.version = version
+	if tsLen != 0 {
+		info.timestamp = binary.LittleEndian.Uint64(payload[2+nameLen:])
+	}
+	fields := payload[2+nameLen+tsLen:]
+	if header&flagNotBefore != 0 {
+		info.notBefore = int64(binary.LittleEndian.Uint64(fields))
+		if err = s.checkNotBefore(uint64(info.notBefore)); err != nil {
+			return err
+		}
+		fields = fields[8:]
+	}
+	if header&flagExpiry != 0 {
+		info.expiry = int64(binary.LittleEndian.Uint64(fields))
+		if err = s.checkExpiry(uint64(info.expiry)); err != nil {
+			return err
+		}
+		fields = fields[8:]
+	}
+	if header&flagTokenID != 0 {
+		info.tokenID = base64.RawURLEncoding.EncodeToString(fields[:tokenIDSize])
+	}
+	if s.revocation != nil {
+		if err = s.checkRevoked(info); err != nil {
+			return err
+		}
+	}
+	if header&flagSpilled != 0 {
+		endRegion = tr.region("unspill")
+		data, err = s.unspill(data)
+		endRegion()
+		if err != nil {
+			return err
+		}
 	}
 	// 5. Decrypt (optional).
 	if s.block != nil {
-		if data, err = decrypt(s.block, data); err != nil {
+		endRegion = tr.region("decrypt")
+		data, err = decrypt(s.block, data)
+		endRegion()
+		if err != nil {
+			return err
+		}
+	}
+	if header&flagCompressed != 0 {
+		endRegion = tr.region("decompress")
+		data, err = s.decompress(data)
+		endRegion()
+		if err != nil {
 			return err
 		}
 	}
 	// 6. Deserialize.
-	if err = s.sz.Deserialize(data, dst); err != nil {
-		return Error{msg: err.Error()}
+	endRegion = tr.region("deserialize")
+	start := time.Now()
+	err = s.sz.Deserialize(data, dst)
+	elapsed := time.Since(start)
+	endRegion()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errDeserializeFailed, err)
+	}
+	if s.shadow != nil && s.shadowReport != nil {
+		s.shadowDecode(name, data, dst, elapsed)
+	}
+	if err = s.checkPayload(dst); err != nil {
+		return err
+	}
+	if s.consume != nil {
+		return s.consumeToken(info)
 	}
 	return nil
 }
@@ -296,10 +607,10 @@ func (s *SecureCookie) Decode(name, value string, dst interface{}) error {
 // timestamp returns the current timestamp, in seconds.
 //
 // For testing purposes, the function that generates the timestamp can be
-// overridden. If not set, it will return time.Now().UTC().Unix().
+// overridden. If not set, it will return the time of the clock.
 func (s *SecureCookie) timestamp() int64 {
 	if s.timeFunc == nil {
-		return time.Now().UTC().Unix()
+		return s.now().Unix()
 	}
 	return s.timeFunc()
 }
@@ -368,19 +679,19 @@ func decrypt(block cipher.Block, value []byte) ([]byte, error) {
 
 // Encoding -------------------------------------------------------------------
 
-// encode encodes a value using base64.
-func encode(value []byte) []byte {
-	encoded := make([]byte, base64.URLEncoding.EncodedLen(len(value)))
-	base64.URLEncoding.Encode(encoded, value)
+// encode encodes a value using base64, or another text encoding.
+func encode(enc TextEncoding, value []byte) []byte {
+	encoded := make([]byte, enc.EncodedLen(len(value)))
+	enc.Encode(encoded, value)
 	return encoded
 }
 
-// decode decodes a cookie using base64.
-func decode(value []byte) ([]byte, error) {
-	decoded := make([]byte, base64.URLEncoding.DecodedLen(len(value)))
-	b, err := base64.URLEncoding.Decode(decoded, value)
+// decode decodes a cookie using base64, or another text encoding.
+func decode(enc TextEncoding, value []byte) ([]byte, error) {
+	decoded := make([]byte, enc.DecodedLen(len(value)))
+	b, err := enc.Decode(decoded, value)
 	if err != nil {
-		return nil, Error{msg: "base64 decode failed"}
+		return nil, errBase64DecodeFailed
 	}
 	return decoded[:b], nil
 }
@@ -5,13 +5,19 @@
 package securecookie
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	fuzz "github.com/google/gofuzz"
 )
@@ -27,7 +33,6 @@ var testCookies = []interface{}{
 var testStrings = []string{"foo", "bar", "baz"}
 
 func TestSecureCookie(t *testing.T) {
-	// TODO test too old / too new timestamps
 	s1 := New(GenerateRandomKey(64), GenerateRandomKey(32))
 	s2 := New([]byte("54321"), []byte("6543210987654321"))
 	value := map[string]interface{}{
@@ -148,6 +153,106 @@ func TestJSONSerialization(t *testing.T) {
 	}
 }
 
+func TestJSONUseNumber(t *testing.T) {
+	s := New([]byte("12345"), nil).SetSerializer(JSONEncoder{UseNumber: true})
+	encoded, err := s.Encode("sid", map[string]interface{}{"id": int64(1) << 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst map[string]interface{}
+	if err := s.Decode("sid", encoded, &dst); err != nil {
+		t.Fatal(err)
+	}
+	n, ok := dst["id"].(json.Number)
+	if !ok {
+		t.Fatalf("Expected a json.Number, got %T", dst["id"])
+	}
+	if i, err := n.Int64(); err != nil || i != 1<<60 {
+		t.Fatalf("Expected %d, got %v, %v", int64(1)<<60, i, err)
+	}
+}
+
+func TestJSONStrict(t *testing.T) {
+	sz := JSONEncoder{DisallowUnknownFields: true, MaxDepth: 2, MaxSize: 64}
+	var dst FooBar
+	if err := sz.Deserialize([]byte(`{"Foo": 1, "Baz": 2}`), &dst); err == nil {
+		t.Fatal("Expected unknown field to be rejected")
+	}
+	var m map[string]interface{}
+	if err := sz.Deserialize([]byte(`{"a": {"b": "[[{\\"}}`), &m); err != nil {
+		t.Fatalf("Expected brackets in strings to be ignored, got %v", err)
+	}
+	if err := sz.Deserialize([]byte(`{"a": {"b": [1]}}`), &m); !errors.Is(err, errJSONTooDeep) {
+		t.Fatalf("Expected errJSONTooDeep, got %v", err)
+	}
+	if err := sz.Deserialize(bytes.Repeat([]byte(" "), 65), &m); !errors.Is(err, errJSONTooLarge) {
+		t.Fatalf("Expected errJSONTooLarge, got %v", err)
+	}
+}
+
+func TestJSONCanonical(t *testing.T) {
+	type Session struct {
+		User  string            `json:"user"`
+		Admin bool              `json:"admin"`
+		Attrs map[string]string `json:"attrs"`
+	}
+	sz := JSONEncoder{Canonical: true}
+	b, err := sz.Serialize(Session{User: "<alice>", Attrs: map[string]string{"b": "2", "a": "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"admin":false,"attrs":{"a":"1","b":"2"},"user":"<alice>"}`
+	if string(b) != want {
+		t.Fatalf("Expected %s, got %s", want, b)
+	}
+}
+
+func TestMarshalerSerialization(t *testing.T) {
+	s := New([]byte("12345"), nil).SetSerializer(MarshalerEncoder{})
+	for _, tt := range []struct {
+		src, dst interface{}
+	}{
+		{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), &time.Time{}},
+		{net.ParseIP("192.0.2.1"), &net.IP{}},
+		{FooBar{42, "bar"}, &FooBar{}},
+		{ptrMarshaler{7}, &ptrMarshaler{}},
+	} {
+		encoded, err := s.Encode("sid", tt.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Decode("sid", encoded, tt.dst); err != nil {
+			t.Fatal(err)
+		}
+		if got := reflect.ValueOf(tt.dst).Elem().Interface(); fmt.Sprint(got) != fmt.Sprint(tt.src) {
+			t.Fatalf("Expected %v, got %v", tt.src, got)
+		}
+	}
+}
+
+// ptrMarshaler implements encoding.BinaryMarshaler on its pointer only.
+type ptrMarshaler struct{ N byte }
+
+func (m *ptrMarshaler) MarshalBinary() ([]byte, error) {
+	return []byte{'p', m.N}, nil
+}
+
+func (m *ptrMarshaler) UnmarshalBinary(b []byte) error {
+	if len(b) != 2 || b[0] != 'p' {
+		return fmt.Errorf("unexpected encoding %q", b)
+	}
+	m.N = b[1]
+	return nil
+}
+
+func TestDefaultSerializer(t *testing.T) {
+	defer func(sz Serializer) { DefaultSerializer = sz }(DefaultSerializer)
+	DefaultSerializer = NopEncoder{}
+	if _, err := New([]byte("12345"), nil).Encode("sid", "value"); err != errValueNotByte {
+		t.Fatalf("Expected NopEncoder to be used, got %v", err)
+	}
+}
+
 func TestNopSerialization(t *testing.T) {
 	cookieData := "fooobar123"
 	sz := NopEncoder{}
@@ -177,8 +282,8 @@ func TestNopSerialization(t *testing.T) {
 
 func TestEncoding(t *testing.T) {
 	for _, value := range testStrings {
-		encoded := encode([]byte(value))
-		decoded, err := decode(encoded)
+		encoded := encode(base64.URLEncoding, []byte(value))
+		decoded, err := decode(base64.URLEncoding, encoded)
 		if err != nil {
 			t.Error(err)
 		} else if string(decoded) != value {
@@ -187,6 +292,72 @@ func TestEncoding(t *testing.T) {
 	}
 }
 
+func TestWireFormat(t *testing.T) {
+	s := New([]byte("12345"), nil).SetSerializer(NopEncoder{})
+	s.timeFunc = func() int64 { return 1700000000 }
+	encoded, err := s.Encode("sid", []byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != sha256.Size+2+len("sid")+8+len("value") {
+		t.Fatalf("Expected a single base64 pass over binary fields, got %d bytes", len(b))
+	}
+	payload := b[sha256.Size:]
+	want := []byte{3, 0, 's', 'i', 'd', 0, 0xf1, 0x53, 0x65, 0, 0, 0, 0, 'v', 'a', 'l', 'u', 'e'}
+	if !bytes.Equal(payload, want) {
+		t.Fatalf("Expected payload %v, got %v", want, payload)
+	}
+}
+
+func TestFraming(t *testing.T) {
+	// Fields are length-prefixed or fixed-size, so delimiters in names and
+	// values and empty values need no escaping.
+	s := New([]byte("12345"), nil).SetSerializer(NopEncoder{})
+	for _, tt := range []struct{ name, value string }{
+		{"a|b", "c|d|"},
+		{"sid", ""},
+		{"|", "|||"},
+	} {
+		encoded, err := s.Encode(tt.name, []byte(tt.value))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dst []byte
+		if err := s.Decode(tt.name, encoded, &dst); err != nil || string(dst) != tt.value {
+			t.Fatalf("%q: expected %q, got %q, %v", tt.name, tt.value, dst, err)
+		}
+		if err := s.Decode(tt.name+"|", encoded, &dst); err == nil {
+			t.Fatalf("%q: expected failure decoding with another name", tt.name)
+		}
+		// Truncated values must fail, without panicking.
+		b, _ := base64.URLEncoding.DecodeString(encoded)
+		for i := 0; i < len(b); i++ {
+			if err := s.Decode(tt.name, base64.URLEncoding.EncodeToString(b[:i]), &dst); err == nil {
+				t.Fatalf("%q: expected failure decoding %d bytes", tt.name, i)
+			}
+		}
+	}
+}
+
+func TestEstimatedLength(t *testing.T) {
+	s := New([]byte("12345"), []byte("1234567890123456")).MaxLength(100)
+	n, err := s.EstimatedLength("sid", "short")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := s.Encode("sid", "short")
+	if n != len(encoded) {
+		t.Fatalf("Expected %d, got %d", len(encoded), n)
+	}
+	if n, err = s.EstimatedLength("sid", strings.Repeat("x", 200)); err != nil || n <= 100 {
+		t.Fatalf("Expected a length over MaxLength, got %d, %v", n, err)
+	}
+}
+
 func TestMultiNoCodecs(t *testing.T) {
 	_, err := EncodeMulti("foo", "bar")
 	if err != errNoCodecs {
@@ -253,3 +424,73 @@ func FuzzEncodeDecode(f *testing.F) {
 		}
 	})
 }
+
+func TestDecodeWithNames(t *testing.T) {
+	s := New([]byte("12345"), nil)
+	encoded, err := s.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := s.DecodeWithNames([]string{"session", "sid"}, encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected %q, got %q, %v", "value", dst, err)
+	}
+	err = s.DecodeWithNames([]string{"session", "other"}, encoded, &dst)
+	if !errors.Is(err, errNameIsUnexpected) || !strings.Contains(err.Error(), "session") {
+		t.Fatalf("Expected errNameIsUnexpected for the first name, got %v", err)
+	}
+	if err := s.DecodeWithNames(nil, encoded, &dst); err != errNameIsUnexpected {
+		t.Fatalf("Expected errNameIsUnexpected, got %v", err)
+	}
+}
+
+func TestDecodeBytes(t *testing.T) {
+	s := New([]byte("12345"), []byte("1234567890123456"))
+	encoded, err := s.Encode("sid", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := s.DecodeBytes("sid", []byte(encoded), &dst); err != nil || dst != "alice" {
+		t.Fatalf("Expected %q, got %q, %v", "alice", dst, err)
+	}
+	if err := s.DecodeBytes("sid", nil, &dst); err == nil {
+		t.Fatal("Expected an error for an empty value")
+	}
+}
+
+func TestDecodeBytesBuffer(t *testing.T) {
+	sampler := NewFailureSampler(1)
+	s := New([]byte("12345"), []byte("1234567890123456"))
+	encoded, err := s.Encode("sid", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clobber := func(b []byte) {
+		for i := range b {
+			b[i] = 'x'
+		}
+	}
+
+	var dst string
+	buf := []byte(encoded)
+	if err := s.DecodeBytes("sid", buf, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != encoded {
+		t.Fatal("Expected the value not to be modified")
+	}
+	clobber(buf)
+	if dst != "alice" {
+		t.Fatalf("Expected the decoded value not to share the buffer, got %q", dst)
+	}
+
+	buf = []byte(encoded[:len(encoded)-2])
+	if err := s.SampleFailures(sampler).DecodeBytes("sid", buf, &dst); err == nil {
+		t.Fatal("Expected an error for a truncated value")
+	}
+	clobber(buf)
+	if samples := sampler.Samples(); len(samples) != 1 || samples[0].Prefix != encoded[:samplePrefixLength] {
+		t.Fatalf("Expected the sampled value to be copied, got %v", samples)
+	}
+}
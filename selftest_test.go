@@ -0,0 +1,49 @@
+package securecookie
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"testing"
+	"time"
+)
+
+// consumeFunc is a ConsumeStore calling f.
+type consumeFunc func()
+
+func (f consumeFunc) Consume(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	f()
+	return true, nil
+}
+
+func TestSelfTest(t *testing.T) {
+	for _, s := range []*SecureCookie{
+		New([]byte("12345"), nil),
+		New([]byte("12345"), []byte("1234567890123456")).MinAge(60),
+		New([]byte("12345"), nil).SetSerializer(NopEncoder{}),
+		New([]byte("12345"), nil).SetSerializer(MarshalerEncoder{}),
+		New([]byte("12345"), nil).SingleUse(consumeFunc(func() { t.Error("Expected the store not to be used") }), 0),
+	} {
+		if err := s.SelfTest(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A hash function that is not deterministic fails the round trip.
+	n := 0
+	broken := func() hash.Hash {
+		n++
+		h := sha256.New()
+		h.Write([]byte{byte(n)})
+		return h
+	}
+	if err := New([]byte("12345"), nil).HashFunc(broken).SelfTest(); !errors.Is(err, errSelfTest) {
+		t.Fatalf("Expected errSelfTest, got %v", err)
+	}
+	s := New([]byte("12345"), nil)
+	s.Close()
+	if err := s.SelfTest(); !errors.Is(err, errSelfTest) {
+		t.Fatalf("Expected errSelfTest for a closed codec, got %v", err)
+	}
+}
@@ -2,7 +2,15 @@ package securecookie
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+var (
+	errJSONTooLarge = Error{msg: "json payload is too large"}
+	errJSONTooDeep  = Error{msg: "json payload is nested too deeply"}
 )
 
 // Serializer provides an interface for providing custom serializers for cookie
@@ -12,13 +20,40 @@ type Serializer interface {
 	Deserialize(src []byte, dst interface{}) error
 }
 
+// DefaultSerializer is the serializer of codecs created by New and the
+// constructors built on it. Set it at init time, before creating codecs, as
+// it is not safe to change concurrently.
+var DefaultSerializer Serializer = JSONEncoder{}
+
 // JSONEncoder encodes cookie values using encoding/json. Users who wish to
 // encode complex types need to satisfy the json.Marshaller and
 // json.Unmarshaller interfaces.
-type JSONEncoder struct{}
+type JSONEncoder struct {
+	// UseNumber decodes numbers held in interface{} values, e.g. in a
+	// map[string]interface{}, as json.Number instead of float64, so integers
+	// survive a round trip intact; use Number.Int64 to read them.
+	UseNumber bool
+	// DisallowUnknownFields rejects objects with keys that do not match a
+	// field of the destination struct.
+	DisallowUnknownFields bool
+	// MaxDepth restricts the nesting depth of objects and arrays, checked
+	// before decoding. A flat object has a depth of 1. 0 means no limit.
+	MaxDepth int
+	// MaxSize restricts the size of the serialized value, in bytes, checked
+	// before decoding. 0 means no limit.
+	MaxSize int
+	// Canonical makes serialization deterministic: object keys are sorted at
+	// every level, struct fields included, HTML characters are not escaped
+	// and no whitespace is emitted, so equal values always serialize to the
+	// same bytes.
+	Canonical bool
+}
 
 // Serialize encodes a value using encoding/json.
 func (e JSONEncoder) Serialize(src interface{}) ([]byte, error) {
+	if e.Canonical {
+		return canonicalJSON(src)
+	}
 	buf := new(bytes.Buffer)
 	enc := json.NewEncoder(buf)
 	if err := enc.Encode(src); err != nil {
@@ -27,15 +62,74 @@ func (e JSONEncoder) Serialize(src interface{}) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// canonicalJSON encodes src, then decodes it into generic maps and encodes
+// it again, as encoding/json only sorts the keys of maps.
+func canonicalJSON(src interface{}) ([]byte, error) {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return nil, Error{msg: err.Error()}
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, Error{msg: err.Error()}
+	}
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, Error{msg: err.Error()}
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
 // Deserialize decodes a value using encoding/json.
 func (e JSONEncoder) Deserialize(src []byte, dst interface{}) error {
+	if e.MaxSize > 0 && len(src) > e.MaxSize {
+		return fmt.Errorf("%w: %d", errJSONTooLarge, len(src))
+	}
+	if e.MaxDepth > 0 && jsonDepth(src) > e.MaxDepth {
+		return errJSONTooDeep
+	}
 	dec := json.NewDecoder(bytes.NewReader(src))
+	if e.UseNumber {
+		dec.UseNumber()
+	}
+	if e.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
 	if err := dec.Decode(dst); err != nil {
 		return Error{msg: err.Error()}
 	}
 	return nil
 }
 
+// jsonDepth returns the maximum nesting depth of objects and arrays in src,
+// skipping brackets inside strings. src does not need to be valid.
+func jsonDepth(src []byte) int {
+	depth, max := 0, 0
+	inString, escaped := false, false
+	for _, c := range src {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			escaped = c == '\\'
+			inString = c != '"'
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			if depth++; depth > max {
+				max = depth
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return max
+}
+
 // NopEncoder does not encode cookie values, and instead simply accepts a []byte
 // (as an interface{}) and returns a []byte. This is particularly useful when
 // you're encoding an object upstream and do not wish to re-encode it.
@@ -58,3 +152,81 @@ func (e NopEncoder) Deserialize(src []byte, dst interface{}) error {
 	}
 	return errValueNotBytePtr
 }
+
+// MarshalerEncoder encodes values implementing encoding.BinaryMarshaler, or
+// else encoding.TextMarshaler, with their own methods, and decodes into
+// values implementing the matching unmarshaler. This avoids a generic
+// encoding for types that already define a compact one. Other values use
+// Fallback.
+type MarshalerEncoder struct {
+	// Fallback serializes values that do not implement the interfaces.
+	// Default is JSONEncoder.
+	Fallback Serializer
+}
+
+// Serialize encodes a value with MarshalBinary, MarshalText or Fallback.
+// Values whose methods have pointer receivers may be passed by value, to
+// match Deserialize into a pointer.
+func (e MarshalerEncoder) Serialize(src interface{}) ([]byte, error) {
+	var (
+		b   []byte
+		err error
+	)
+	switch m := addressable(src).(type) {
+	case encoding.BinaryMarshaler:
+		b, err = m.MarshalBinary()
+	case encoding.TextMarshaler:
+		b, err = m.MarshalText()
+	default:
+		return e.fallback().Serialize(src)
+	}
+	if err != nil {
+		return nil, Error{msg: err.Error()}
+	}
+	return b, nil
+}
+
+// Deserialize decodes a value with UnmarshalBinary, UnmarshalText or
+// Fallback.
+func (e MarshalerEncoder) Deserialize(src []byte, dst interface{}) error {
+	var err error
+	switch u := dst.(type) {
+	case encoding.BinaryUnmarshaler:
+		err = u.UnmarshalBinary(src)
+	case encoding.TextUnmarshaler:
+		err = u.UnmarshalText(src)
+	default:
+		return e.fallback().Deserialize(src, dst)
+	}
+	if err != nil {
+		return Error{msg: err.Error()}
+	}
+	return nil
+}
+
+// addressable returns src, or a pointer to a copy of src if only its
+// pointer implements a marshaler.
+func addressable(src interface{}) interface{} {
+	switch src.(type) {
+	case encoding.BinaryMarshaler, encoding.TextMarshaler:
+		return src
+	}
+	v := reflect.ValueOf(src)
+	if !v.IsValid() || v.Kind() == reflect.Ptr {
+		return src
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	switch p.Interface().(type) {
+	case encoding.BinaryMarshaler, encoding.TextMarshaler:
+		return p.Interface()
+	}
+	return src
+}
+
+func (e MarshalerEncoder) fallback() Serializer {
+	if e.Fallback == nil {
+		return JSONEncoder{}
+	}
+	return e.Fallback
+}
@@ -0,0 +1,8 @@
+// Package sessions provides a gorilla/sessions Store keeping sessions in
+// cookies encoded by securecookie codecs, such as a Keyring, optionally
+// spread over chunked cookies.
+//
+// This package requires github.com/gorilla/sessions, which is not a
+// dependency of the default build. Add it with
+// "go get github.com/gorilla/sessions" and build with "-tags sessions".
+package sessions
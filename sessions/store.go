@@ -0,0 +1,118 @@
+//go:build sessions
+
+package sessions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	gorillasessions "github.com/gorilla/sessions"
+
+	"github.com/monime-lab/gorilla-securecookie"
+)
+
+// CookieStore is a gorilla/sessions Store keeping the values of sessions in
+// cookies encoded with Codec. Sessions have no ID.
+//
+// Values are encoded as a map[string]interface{}, so that they do not depend
+// on gob: keys must be strings, and values are decoded as the serializer of
+// the codec returns them, e.g. numbers as float64 with JSON.
+type CookieStore struct {
+	Codec securecookie.Codec
+	// Options are the default attributes of the cookies of new sessions.
+	Options *gorillasessions.Options
+	// Chunked spreads values over several cookies, see
+	// securecookie.WriteChunked, for sessions over 4096 bytes.
+	Chunked bool
+}
+
+// NewCookieStore returns a CookieStore encoding sessions with codec, whose
+// cookies have path "/" and last 30 days, like gorilla/sessions.
+func NewCookieStore(codec securecookie.Codec) *CookieStore {
+	return &CookieStore{
+		Codec:   codec,
+		Options: &gorillasessions.Options{Path: "/", MaxAge: 86400 * 30},
+	}
+}
+
+// Get returns the named session from the registry of r, creating it with New
+// on first use.
+func (s *CookieStore) Get(r *http.Request, name string) (*gorillasessions.Session, error) {
+	return gorillasessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns the named session decoded from the cookies of r. If r has none,
+// the session is new and the error nil. If they fail to decode, the session
+// is new and the error is returned.
+func (s *CookieStore) New(r *http.Request, name string) (*gorillasessions.Session, error) {
+	session := gorillasessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+	values := map[string]interface{}{}
+	var err error
+	if s.Chunked {
+		err = securecookie.ReadChunked(r, s.Codec, name, &values)
+	} else {
+		_, err = securecookie.DecodeFirstValid(r, s.Codec, name, &values)
+	}
+	if errors.Is(err, http.ErrNoCookie) {
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+	for k, v := range values {
+		session.Values[k] = v
+	}
+	session.IsNew = false
+	return session, nil
+}
+
+// Save encodes the values of session and sets its cookies on w, or expires
+// them if its MaxAge is negative.
+func (s *CookieStore) Save(r *http.Request, w http.ResponseWriter, session *gorillasessions.Session) error {
+	c := gorillasessions.NewCookie(session.Name(), "", session.Options)
+	if session.Options.MaxAge < 0 {
+		s.expire(w, r, c)
+		return nil
+	}
+	values := make(map[string]interface{}, len(session.Values))
+	for k, v := range session.Values {
+		key, ok := k.(string)
+		if !ok {
+			return fmt.Errorf("securecookie: session key %v is not a string", k)
+		}
+		values[key] = v
+	}
+	if s.Chunked {
+		return securecookie.WriteChunked(w, r, s.Codec, c, values, 0)
+	}
+	codec, err := securecookie.RequestCodec(s.Codec, r)
+	if err != nil {
+		return err
+	}
+	if c.Value, err = codec.Encode(c.Name, values); err != nil {
+		return err
+	}
+	return securecookie.WriteCookie(w, r, c)
+}
+
+// expire expires c, or the chunks of c sent with r.
+func (s *CookieStore) expire(w http.ResponseWriter, r *http.Request, c *http.Cookie) {
+	c.MaxAge = -1
+	if !s.Chunked {
+		http.SetCookie(w, c)
+		return
+	}
+	name := c.Name
+	for i := 0; ; i++ {
+		c.Name = name + "." + strconv.Itoa(i)
+		if _, err := r.Cookie(c.Name); err != nil {
+			return
+		}
+		http.SetCookie(w, c)
+	}
+}
@@ -0,0 +1,90 @@
+//go:build sessions
+
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/monime-lab/gorilla-securecookie"
+)
+
+// roundTrip returns a request carrying the cookies set on w.
+func roundTrip(w *httptest.ResponseRecorder) *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+func TestCookieStore(t *testing.T) {
+	for _, chunked := range []bool{false, true} {
+		codec := securecookie.New([]byte("12345"), []byte("1234567890123456"))
+		if chunked {
+			codec.MaxLength(0)
+		}
+		store := NewCookieStore(codec)
+		store.Chunked = chunked
+
+		r := httptest.NewRequest("GET", "/", nil)
+		session, err := store.Get(r, "session")
+		if err != nil || !session.IsNew {
+			t.Fatalf("chunked %v: expected a new session, got %v", chunked, err)
+		}
+		if again, _ := store.Get(r, "session"); again != session {
+			t.Fatalf("chunked %v: expected Get to return the registered session", chunked)
+		}
+		session.Values["user"] = "alice"
+		if chunked {
+			session.Values["data"] = strings.Repeat("x", 6000)
+		}
+		w := httptest.NewRecorder()
+		if err := store.Save(r, w, session); err != nil {
+			t.Fatal(err)
+		}
+
+		r = roundTrip(w)
+		session, err = store.New(r, "session")
+		if err != nil || session.IsNew {
+			t.Fatalf("chunked %v: expected the saved session, got %v", chunked, err)
+		}
+		if session.Values["user"] != "alice" {
+			t.Fatalf("chunked %v: expected alice, got %v", chunked, session.Values["user"])
+		}
+		if session.Options.Path != "/" || session.Options.MaxAge != 86400*30 {
+			t.Fatalf("chunked %v: expected the default options, got %+v", chunked, session.Options)
+		}
+
+		session.Options.MaxAge = -1
+		w = httptest.NewRecorder()
+		if err := store.Save(r, w, session); err != nil {
+			t.Fatal(err)
+		}
+		cookies := w.Result().Cookies()
+		if len(cookies) == 0 {
+			t.Fatalf("chunked %v: expected the cookies to be expired", chunked)
+		}
+		for _, c := range cookies {
+			if c.MaxAge >= 0 {
+				t.Fatalf("chunked %v: expected %s to be expired, got MaxAge %d", chunked, c.Name, c.MaxAge)
+			}
+		}
+	}
+}
+
+func TestCookieStoreErrors(t *testing.T) {
+	store := NewCookieStore(securecookie.New([]byte("12345"), nil))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: "invalid"})
+	session, err := store.New(r, "session")
+	if err == nil || !session.IsNew {
+		t.Fatalf("Expected a new session and an error for an invalid cookie, got %v", err)
+	}
+	session.Values[1] = "one"
+	if err := store.Save(r, httptest.NewRecorder(), session); err == nil {
+		t.Fatal("Expected an error for a key that is not a string")
+	}
+}
@@ -0,0 +1,62 @@
+package securecookie
+
+import (
+	"reflect"
+	"time"
+)
+
+// ShadowReport compares the current serializer of a SecureCookie with a
+// candidate on one decoded value. See SecureCookie.ShadowSerializer.
+type ShadowReport struct {
+	// Name is the cookie name passed to Decode.
+	Name string
+	// CurrentSize is the size of the value serialized by the current
+	// serializer, and CandidateSize by the candidate.
+	CurrentSize   int
+	CandidateSize int
+	// CurrentDuration is the time the current serializer took to deserialize
+	// the value, and CandidateDuration the time the candidate took to
+	// serialize and deserialize it.
+	CurrentDuration   time.Duration
+	CandidateDuration time.Duration
+	// Mismatch is true if the value round-tripped through the candidate
+	// differs from the value decoded by the current serializer.
+	Mismatch bool
+	// Err is the error returned by the candidate, if any.
+	Err error
+}
+
+// ShadowSerializer enables a shadow mode to gather production evidence before
+// switching serializers, e.g. from JSON to a more compact format. After every
+// successful Decode, the decoded value is serialized and deserialized again
+// with candidate, and report is called with the size and time differences and
+// whether the candidate reproduced the value. Encoded values are unaffected.
+//
+// Shadowing roughly doubles the cost of Decode. Pass a nil candidate to
+// disable it.
+func (s *SecureCookie) ShadowSerializer(candidate Serializer, report func(ShadowReport)) *SecureCookie {
+	s.shadow = candidate
+	s.shadowReport = report
+	return s
+}
+
+// shadowDecode compares the candidate serializer on a decoded value.
+func (s *SecureCookie) shadowDecode(name string, data []byte, dst interface{}, current time.Duration) {
+	r := ShadowReport{
+		Name:            name,
+		CurrentSize:     len(data),
+		CurrentDuration: current,
+	}
+	start := time.Now()
+	serialized, err := s.shadow.Serialize(reflect.ValueOf(dst).Elem().Interface())
+	if err == nil {
+		r.CandidateSize = len(serialized)
+		fresh := reflect.New(reflect.TypeOf(dst).Elem())
+		if err = s.shadow.Deserialize(serialized, fresh.Interface()); err == nil {
+			r.Mismatch = !reflect.DeepEqual(fresh.Interface(), dst)
+		}
+	}
+	r.CandidateDuration = time.Since(start)
+	r.Err = err
+	s.shadowReport(r)
+}
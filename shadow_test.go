@@ -0,0 +1,37 @@
+package securecookie
+
+import "testing"
+
+// lossySerializer drops every value, so it never reproduces anything.
+type lossySerializer struct{ JSONEncoder }
+
+func (lossySerializer) Serialize(src interface{}) ([]byte, error) {
+	return []byte("{}"), nil
+}
+
+func TestShadowSerializer(t *testing.T) {
+	var reports []ShadowReport
+	report := func(r ShadowReport) { reports = append(reports, r) }
+	s := New([]byte("12345"), nil)
+	encoded, _ := s.Encode("sid", map[string]string{"foo": "bar"})
+
+	for _, candidate := range []Serializer{JSONEncoder{}, lossySerializer{}} {
+		s.ShadowSerializer(candidate, report)
+		dst := map[string]string{}
+		if err := s.Decode("sid", encoded, &dst); err != nil {
+			t.Fatal(err)
+		}
+		if dst["foo"] != "bar" {
+			t.Fatalf("Expected shadowing not to affect the result, got %v", dst)
+		}
+	}
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 reports, got %d", len(reports))
+	}
+	if reports[0].Mismatch || reports[0].Err != nil || reports[0].CandidateSize == 0 {
+		t.Fatalf("Expected JSON to match itself, got %+v", reports[0])
+	}
+	if !reports[1].Mismatch {
+		t.Fatalf("Expected a mismatch, got %+v", reports[1])
+	}
+}
@@ -0,0 +1,141 @@
+package securecookie
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+var (
+	errShamirParams       = Error{msg: "shares must satisfy 2 <= threshold <= shares <= 255"}
+	errShamirSecretEmpty  = Error{msg: "secret to split is empty"}
+	errShamirSharesFew    = Error{msg: "at least two shares are required"}
+	errShamirSharesLength = Error{msg: "shares must all have the same length"}
+	errShamirSharesDup    = Error{msg: "shares must be distinct"}
+)
+
+// SplitSecret splits secret into n shares using Shamir's secret sharing, so
+// that any threshold of them reconstruct it and fewer reveal nothing about
+// it. Use it to split the passphrase of a sealed keyring (see
+// Keyring.MarshalEncrypted) among operators, so no single one holds it.
+//
+// Each share is one byte longer than the secret: the last byte identifies
+// the share.
+func SplitSecret(secret []byte, n, threshold int) ([][]byte, error) {
+	if threshold < 2 || threshold > n || n > 255 {
+		return nil, errShamirParams
+	}
+	if len(secret) == 0 {
+		return nil, errShamirSecretEmpty
+	}
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = byte(i + 1)
+	}
+	coeffs := make([]byte, threshold)
+	for i, b := range secret {
+		// A random polynomial of degree threshold-1 whose value at 0 is b.
+		coeffs[0] = b
+		if _, err := io.ReadFull(rand.Reader, coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			share[i] = gfEval(coeffs, share[len(secret)])
+		}
+	}
+	return shares, nil
+}
+
+// CombineShares reconstructs a secret split by SplitSecret. It needs at least
+// threshold shares; with fewer, it returns a wrong secret rather than an
+// error, which makes opening what the secret protects fail.
+func CombineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errShamirSharesFew
+	}
+	size := len(shares[0])
+	if size < 2 {
+		return nil, errShamirSharesLength
+	}
+	xs := make([]byte, len(shares))
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, errShamirSharesLength
+		}
+		xs[i] = share[size-1]
+		for j := 0; j < i; j++ {
+			if xs[j] == xs[i] {
+				return nil, errShamirSharesDup
+			}
+		}
+	}
+	secret := make([]byte, size-1)
+	for i := range secret {
+		// Lagrange interpolation at x = 0.
+		var value byte
+		for j, share := range shares {
+			basis := byte(1)
+			for m := range shares {
+				if m != j {
+					basis = gfMul(basis, gfDiv(xs[m], xs[m]^xs[j]))
+				}
+			}
+			value ^= gfMul(share[i], basis)
+		}
+		secret[i] = value
+	}
+	return secret, nil
+}
+
+// LoadKeyringFromShares restores a sealed keyring whose passphrase was split
+// with SplitSecret.
+func LoadKeyringFromShares(sealed []byte, shares [][]byte) (*Keyring, error) {
+	passphrase, err := CombineShares(shares)
+	if err != nil {
+		return nil, err
+	}
+	return LoadKeyring(sealed, passphrase)
+}
+
+// GF(2^8) arithmetic -----------------------------------------------------------
+
+// gfExp and gfLog are exponent and logarithm tables of GF(2^8) with the AES
+// polynomial x^8 + x^4 + x^3 + x + 1 and generator 3.
+var gfExp, gfLog = func() (exp [510]byte, log [256]byte) {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i], exp[i+255] = x, x
+		log[x] = byte(i)
+		// Multiply by the generator 3: x*2 ^ x.
+		x2 := x << 1
+		if x&0x80 != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	return exp, log
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// gfDiv returns a / b. b must not be 0.
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// gfEval evaluates the polynomial with the given coefficients at x.
+func gfEval(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ coeffs[i]
+	}
+	return y
+}
@@ -0,0 +1,55 @@
+package securecookie
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestShamir(t *testing.T) {
+	secret := GenerateRandomKey(32)
+	shares, err := SplitSecret(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 2, 3, 4}} {
+		var picked [][]byte
+		for _, i := range subset {
+			picked = append(picked, shares[i])
+		}
+		combined, err := CombineShares(picked)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(combined, secret) {
+			t.Fatalf("%v: expected the secret to be reconstructed", subset)
+		}
+	}
+	combined, _ := CombineShares(shares[:2])
+	if bytes.Equal(combined, secret) {
+		t.Fatal("Expected fewer shares than the threshold not to reconstruct the secret")
+	}
+	if _, err = CombineShares([][]byte{shares[0], shares[0]}); err != errShamirSharesDup {
+		t.Fatalf("Expected errShamirSharesDup, got %v", err)
+	}
+	if _, err = SplitSecret(secret, 2, 3); err != errShamirParams {
+		t.Fatalf("Expected errShamirParams, got %v", err)
+	}
+}
+
+func TestLoadKeyringFromShares(t *testing.T) {
+	k := NewKeyring()
+	_ = k.Add("k1", GenerateRandomKey(32), nil)
+	passphrase := GenerateRandomKey(32)
+	sealed, err := k.MarshalEncrypted(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares, _ := SplitSecret(passphrase, 3, 2)
+	loaded, err := LoadKeyringFromShares(sealed, shares[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Keys()) != 1 {
+		t.Fatalf("Expected 1 key, got %d", len(loaded.Keys()))
+	}
+}
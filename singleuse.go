@@ -0,0 +1,87 @@
+package securecookie
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var (
+	errTokenConsumed  = Error{msg: "value was already used"}
+	errTokenIDMissing = Error{msg: "value has no token ID"}
+	errConsumeFailed  = Error{msg: "value could not be marked as used"}
+)
+
+// ConsumeStore records the token IDs of single-use values, see SingleUse.
+type ConsumeStore interface {
+	// Consume atomically marks id as used for ttl, and reports whether it
+	// was unused. ttl is 0 for values that do not expire.
+	Consume(ctx context.Context, id string, ttl time.Duration) (bool, error)
+}
+
+// SingleUse makes values single-use, e.g. for magic links, download grants
+// or form submissions: the first successful Decode of a value marks its
+// token ID as used in st, and later ones fail. It enables TokenIDs, and
+// values without a token ID fail to decode. Calls use a background context,
+// with the given timeout if it is not 0.
+func (s *SecureCookie) SingleUse(st ConsumeStore, timeout time.Duration) *SecureCookie {
+	s.consume = st
+	s.consumeTimeout = timeout
+	if st != nil {
+		s.tokenIDs = true
+	}
+	return s
+}
+
+// consumeToken marks the token ID of a decoded value as used.
+func (s *SecureCookie) consumeToken(info *valueInfo) error {
+	if info.tokenID == "" {
+		return errTokenIDMissing
+	}
+	ctx, cancel := backgroundContext(s.consumeTimeout)
+	defer cancel()
+	unused, err := s.consume.Consume(ctx, info.tokenID, s.timeLeft(info))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errConsumeFailed, err)
+	}
+	if !unused {
+		return errTokenConsumed
+	}
+	return nil
+}
+
+// timeLeft returns the time until a decoded value expires, through MaxAge
+// or ExpiresAt, plus the leeway, or 0 if it does not.
+func (s *SecureCookie) timeLeft(info *valueInfo) time.Duration {
+	var end time.Time
+	if info.expiry != 0 {
+		end = time.Unix(info.expiry, 0)
+	}
+	if info.timestamp != 0 && s.maxAge != 0 {
+		if t := issuedAt(info.timestamp).Add(time.Duration(s.maxAge) * time.Second); end.IsZero() || t.Before(end) {
+			end = t
+		}
+	}
+	if end.IsZero() {
+		return 0
+	}
+	if d := end.Sub(time.UnixMilli(s.timestampMillis())) + s.leeway; d > 0 {
+		return d
+	}
+	return time.Second
+}
+
+// Consume implements ConsumeStore.
+func (m *MemoryRevocations) Consume(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active(id) {
+		return false, nil
+	}
+	var expiry time.Time
+	if ttl != 0 {
+		expiry = m.now().Add(ttl)
+	}
+	m.ids[id] = expiry
+	return true, nil
+}
@@ -0,0 +1,46 @@
+package securecookie
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSingleUse(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m := NewMemoryRevocations()
+	m.now = func() time.Time { return now }
+	s := New([]byte("12345"), []byte("1234567890123456")).MaxAge(600).SingleUse(m, 0)
+	s.timeFunc = func() int64 { return now.Unix() }
+	encoded, err := s.Encode("link", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Failed decodes do not consume the value.
+	var n int
+	if err := s.Decode("link", encoded, &n); err == nil {
+		t.Fatal("Expected a deserialization error")
+	}
+	var dst string
+	if err := s.Decode("link", encoded, &dst); err != nil || dst != "alice" {
+		t.Fatalf("Expected %q, got %q, %v", "alice", dst, err)
+	}
+	if err := s.Decode("link", encoded, &dst); err != errTokenConsumed {
+		t.Fatalf("Expected errTokenConsumed, got %v", err)
+	}
+
+	// Used IDs are kept until the value expires.
+	for _, expiry := range m.ids {
+		if !expiry.Equal(now.Add(600 * time.Second)) {
+			t.Fatalf("Expected the ID to be kept for the MaxAge, got %v", expiry)
+		}
+	}
+
+	other, err := s.TokenIDs(false).Encode("link", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Decode("link", other, &dst); err != errTokenIDMissing {
+		t.Fatalf("Expected errTokenIDMissing, got %v", err)
+	}
+}
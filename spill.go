@@ -0,0 +1,107 @@
+package securecookie
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// flagSpilled marks values whose data was written to a Storage. The data is
+// then a reference: the storage key followed by the SHA-256 of the stored
+// bytes.
+const flagSpilled uint16 = 1 << 13
+
+// spillKeySize is the size of the random part of storage keys.
+const spillKeySize = 16
+
+var (
+	errSpillNotConfigured = Error{msg: "no storage is configured for spilled values"}
+	errSpillReference     = Error{msg: "spilled value reference is malformed"}
+	errSpillFailed        = Error{msg: "spilled value could not be stored or fetched"}
+	errSpillMismatch      = Error{msg: "spilled value does not match its reference"}
+)
+
+// Storage holds values too long for a cookie, e.g. in Redis or a database.
+// Keys are random and values are already encrypted, if a block key is set.
+type Storage interface {
+	// Put stores value under key. ttl is 0 if the value does not expire.
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Get returns the value stored under key. The caller may modify the
+	// returned slice.
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// SpillTo sets a Storage for values longer than MaxLength and the overflow
+// policy to OverflowSpill. Instead of failing, Encode writes their
+// serialized and encrypted data to st, and the cookie only carries a
+// reference to it, authenticated like any other value. Decode fetches and
+// verifies the data transparently. Stored values expire after MaxAge.
+//
+// Storage calls use a background context, with the given timeout if it is
+// not 0.
+func (s *SecureCookie) SpillTo(st Storage, timeout time.Duration) *SecureCookie {
+	s.storage = st
+	s.storageTimeout = timeout
+	s.overflow = OverflowSpill
+	return s
+}
+
+// backgroundContext returns a background context with the given timeout, or
+// none if it is 0.
+func backgroundContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// encodedLen returns the length of a value encoded with the given name, flags
+// and data.
+func (s *SecureCookie) encodedLen(name string, flags uint16, data int) int {
+	return len(s.prefix) + s.textEncoding().EncodedLen(s.versionLen()+s.hmacSize+2+len(name)+fieldsLen(flags)+data)
+}
+
+// spill writes data to the storage and returns a reference to it.
+func (s *SecureCookie) spill(data []byte) ([]byte, error) {
+	key := GenerateRandomKey(spillKeySize)
+	if key == nil {
+		return nil, errGeneratingIV
+	}
+	ctx, cancel := backgroundContext(s.storageTimeout)
+	defer cancel()
+	ttl := time.Duration(s.maxAge) * time.Second
+	if err := s.storage.Put(ctx, spillKey(key), data, ttl); err != nil {
+		return nil, fmt.Errorf("%w: %v", errSpillFailed, err)
+	}
+	sum := sha256.Sum256(data)
+	return append(key, sum[:]...), nil
+}
+
+// unspill fetches the data referenced by ref and checks its digest.
+func (s *SecureCookie) unspill(ref []byte) ([]byte, error) {
+	if s.storage == nil {
+		return nil, errSpillNotConfigured
+	}
+	if len(ref) != spillKeySize+sha256.Size {
+		return nil, errSpillReference
+	}
+	ctx, cancel := backgroundContext(s.storageTimeout)
+	defer cancel()
+	data, err := s.storage.Get(ctx, spillKey(ref[:spillKeySize]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errSpillFailed, err)
+	}
+	sum := sha256.Sum256(data)
+	if subtle.ConstantTimeCompare(sum[:], ref[spillKeySize:]) != 1 {
+		return nil, errSpillMismatch
+	}
+	return data, nil
+}
+
+// spillKey returns the storage key for the random bytes of a reference.
+func spillKey(b []byte) string {
+	return "securecookie:" + base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,77 @@
+package securecookie
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryStorage struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func (m *memoryStorage) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values == nil {
+		m.values = make(map[string][]byte)
+	}
+	m.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (m *memoryStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return append([]byte(nil), v...), nil
+}
+
+func TestSpillTo(t *testing.T) {
+	st := &memoryStorage{}
+	s := New([]byte("12345"), []byte("1234567890123456")).MaxLength(200).SpillTo(st, time.Second)
+	short, err := s.Encode("cart", "small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.values) != 0 {
+		t.Fatal("Expected a short value not to be spilled")
+	}
+	value := strings.Repeat("item,", 100)
+	encoded, err := s.Encode("cart", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encoded) > 200 || len(st.values) != 1 {
+		t.Fatalf("Expected a spilled value, got %d bytes and %d stored", len(encoded), len(st.values))
+	}
+	for _, v := range []string{short, encoded} {
+		var dst string
+		if err := s.Decode("cart", v, &dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var dst string
+	if err := s.Decode("cart", encoded, &dst); err != nil || dst != value {
+		t.Fatalf("Expected the spilled value, got %v", err)
+	}
+
+	for k, v := range st.values {
+		v[len(v)-1] ^= 1
+		st.values[k] = v
+	}
+	if err := s.Decode("cart", encoded, &dst); !errors.Is(err, errSpillMismatch) {
+		t.Fatalf("Expected errSpillMismatch, got %v", err)
+	}
+	plain := New([]byte("12345"), []byte("1234567890123456")).MaxLength(200)
+	if err := plain.Decode("cart", encoded, &dst); !errors.Is(err, errSpillNotConfigured) {
+		t.Fatalf("Expected errSpillNotConfigured, got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package securecookie
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	errKeyIDInvalid     = Error{msg: "key id must not contain '.'"}
+	errSplitKeyIDFormat = Error{msg: "value has no key ids"}
+)
+
+// SplitKeyCodec holds hash keys and block keys as two independent sets, so
+// the signing key and the encryption key can be rotated on their own
+// schedules. Encoded values start with the IDs of both keys, as
+// "hashID.blockID.", so decoding selects the keys directly instead of trying
+// every combination. The IDs are also bound to the MAC.
+//
+// The first hash key and the first block key added are used for encoding;
+// use UseHashKey and UseBlockKey to rotate them. Without block keys, values
+// are only authenticated and the block ID is empty.
+//
+// A SplitKeyCodec is safe for concurrent use.
+type SplitKeyCodec struct {
+	mu        sync.RWMutex
+	hashKeys  map[string][]byte
+	blockKeys map[string][]byte
+	hashID    string
+	blockID   string
+	codecs    map[[2]string]*SecureCookie
+	configure func(*SecureCookie)
+}
+
+// NewSplitKeyCodec returns a SplitKeyCodec without keys.
+func NewSplitKeyCodec() *SplitKeyCodec {
+	return &SplitKeyCodec{
+		hashKeys:  make(map[string][]byte),
+		blockKeys: make(map[string][]byte),
+		codecs:    make(map[[2]string]*SecureCookie),
+	}
+}
+
+// Configure sets a function applied to the SecureCookie built for every pair
+// of keys. Use it to change the default options, e.g. MaxAge or the
+// serializer.
+func (c *SplitKeyCodec) Configure(f func(*SecureCookie)) *SplitKeyCodec {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.configure = f
+	c.codecs = make(map[[2]string]*SecureCookie)
+	return c
+}
+
+// AddHashKey adds a hash key. The first one added is used for encoding.
+func (c *SplitKeyCodec) AddHashKey(id string, key []byte) error {
+	if len(key) == 0 {
+		return errHashKeyNotSet
+	}
+	return c.add(c.hashKeys, &c.hashID, id, key)
+}
+
+// AddBlockKey adds a block key. The first one added is used for encoding.
+func (c *SplitKeyCodec) AddBlockKey(id string, key []byte) error {
+	if len(key) == 0 {
+		return errBlockKeyNotSet
+	}
+	return c.add(c.blockKeys, &c.blockID, id, key)
+}
+
+// UseHashKey makes the given hash key the one used for encoding.
+func (c *SplitKeyCodec) UseHashKey(id string) error {
+	return c.use(c.hashKeys, &c.hashID, id)
+}
+
+// UseBlockKey makes the given block key the one used for encoding.
+func (c *SplitKeyCodec) UseBlockKey(id string) error {
+	return c.use(c.blockKeys, &c.blockID, id)
+}
+
+// RemoveHashKey removes a hash key that is not used for encoding.
+func (c *SplitKeyCodec) RemoveHashKey(id string) error {
+	return c.remove(c.hashKeys, c.hashID, id, 0)
+}
+
+// RemoveBlockKey removes a block key that is not used for encoding.
+func (c *SplitKeyCodec) RemoveBlockKey(id string) error {
+	return c.remove(c.blockKeys, c.blockID, id, 1)
+}
+
+// Encode encodes a cookie value with the current hash and block keys.
+func (c *SplitKeyCodec) Encode(name string, value interface{}) (string, error) {
+	c.mu.Lock()
+	hashID, blockID := c.hashID, c.blockID
+	s, err := c.codec(hashID, blockID)
+	c.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	encoded, err := s.Encode(splitKeyName(name, hashID, blockID), value)
+	if err != nil {
+		return "", err
+	}
+	return hashID + "." + blockID + "." + encoded, nil
+}
+
+// Decode decodes a cookie value with the keys named by its IDs.
+func (c *SplitKeyCodec) Decode(name, value string, dst interface{}) error {
+	parts := strings.SplitN(value, ".", 3)
+	if len(parts) != 3 {
+		return errSplitKeyIDFormat
+	}
+	c.mu.Lock()
+	s, err := c.codec(parts[0], parts[1])
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.Decode(splitKeyName(name, parts[0], parts[1]), parts[2], dst)
+}
+
+// splitKeyName binds the key IDs to the name authenticated by the MAC.
+func splitKeyName(name, hashID, blockID string) string {
+	return name + "#" + hashID + "." + blockID
+}
+
+// codec returns the codec for the given key IDs, building it if needed.
+// c.mu must be held.
+func (c *SplitKeyCodec) codec(hashID, blockID string) (*SecureCookie, error) {
+	pair := [2]string{hashID, blockID}
+	if s, ok := c.codecs[pair]; ok {
+		return s, nil
+	}
+	hashKey, ok := c.hashKeys[hashID]
+	if !ok {
+		return nil, fmt.Errorf("%w: hash key %q", errKeyIDUnknown, hashID)
+	}
+	var blockKey []byte
+	if blockID != "" {
+		if blockKey, ok = c.blockKeys[blockID]; !ok {
+			return nil, fmt.Errorf("%w: block key %q", errKeyIDUnknown, blockID)
+		}
+	}
+	s := New(hashKey, blockKey)
+	if c.configure != nil {
+		c.configure(s)
+	}
+	c.codecs[pair] = s
+	return s, nil
+}
+
+func (c *SplitKeyCodec) add(keys map[string][]byte, current *string, id string, key []byte) error {
+	if id == "" {
+		return errKeyIDEmpty
+	}
+	if strings.Contains(id, ".") {
+		return errKeyIDInvalid
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := keys[id]; ok {
+		return errKeyIDDuplicate
+	}
+	keys[id] = cloneKey(key)
+	if *current == "" {
+		*current = id
+	}
+	return nil
+}
+
+func (c *SplitKeyCodec) use(keys map[string][]byte, current *string, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := keys[id]; !ok {
+		return errKeyIDUnknown
+	}
+	*current = id
+	return nil
+}
+
+func (c *SplitKeyCodec) remove(keys map[string][]byte, current, id string, index int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := keys[id]; !ok {
+		return errKeyIDUnknown
+	}
+	if id == current {
+		return errKeyActiveRemoval
+	}
+	wipe(keys[id])
+	delete(keys, id)
+	// Codecs built with the key are dropped but not closed, as concurrent
+	// calls may still be using them.
+	for pair := range c.codecs {
+		if pair[index] == id {
+			delete(c.codecs, pair)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package securecookie
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSplitKeyCodec(t *testing.T) {
+	c := NewSplitKeyCodec()
+	if err := c.AddHashKey("h1", []byte("hash-1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlockKey("b1", []byte("1234567890123456")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddHashKey("h.2", []byte("hash-2")); !errors.Is(err, errKeyIDInvalid) {
+		t.Fatalf("Expected errKeyIDInvalid, got %v", err)
+	}
+	old, err := c.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(old, "h1.b1.") {
+		t.Fatalf("Expected the key ids as prefix, got %q", old)
+	}
+
+	// Rotate the block key only.
+	_ = c.AddBlockKey("b2", []byte("6543210987654321"))
+	_ = c.UseBlockKey("b2")
+	rotated, _ := c.Encode("sid", "value")
+	if !strings.HasPrefix(rotated, "h1.b2.") {
+		t.Fatalf("Expected the new block key id, got %q", rotated)
+	}
+	for _, v := range []string{old, rotated} {
+		var dst string
+		if err := c.Decode("sid", v, &dst); err != nil || dst != "value" {
+			t.Fatalf("Expected value, got %q, %v", dst, err)
+		}
+	}
+
+	// Swapping the ids is detected by the MAC.
+	var dst string
+	if err := c.Decode("sid", "h1.b2."+strings.TrimPrefix(old, "h1.b1."), &dst); err == nil {
+		t.Fatal("Expected decoding with swapped ids to fail")
+	}
+	if err := c.RemoveBlockKey("b2"); !errors.Is(err, errKeyActiveRemoval) {
+		t.Fatalf("Expected errKeyActiveRemoval, got %v", err)
+	}
+	c.mu.Lock()
+	inUse, _ := c.codec("h1", "b1")
+	c.mu.Unlock()
+	if err := c.RemoveBlockKey("b1"); err != nil {
+		t.Fatal(err)
+	}
+	// A codec fetched before the removal keeps working.
+	if err := inUse.Decode(splitKeyName("sid", "h1", "b1"), strings.TrimPrefix(old, "h1.b1."), &dst); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Decode("sid", old, &dst); !errors.Is(err, errKeyIDUnknown) {
+		t.Fatalf("Expected errKeyIDUnknown, got %v", err)
+	}
+}
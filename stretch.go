@@ -0,0 +1,46 @@
+package securecookie
+
+import "crypto/sha256"
+
+// Flags stored in the high bits of the name length field of the wire format.
+// Cookie names are far shorter than 2^9 bytes, and values encoded before a
+// flag existed have it unset.
+const (
+	flagKeysStretched uint16 = 1 << 15
+
+	flagsMask   uint16 = 0xfe00
+	maxNameSize        = int(^flagsMask)
+)
+
+var (
+	errNameTooLong          = Error{msg: "cookie name is too long"}
+	errKeyStretchedMismatch = Error{msg: "value was encoded with different key stretching"}
+)
+
+// NewStretched returns a new SecureCookie like New, but with keys expanded
+// through HKDF-SHA256 to 32 bytes each. Any secret is accepted, so a block
+// key of the wrong length for AES no longer fails at Encode time; it is
+// still recommended to use secrets with at least 32 bytes of entropy, as
+// stretching does not add any.
+//
+// Values record that stretched keys were used: decoding them with a codec
+// that does not stretch keys, or the other way around, fails with a
+// distinct error rather than a MAC failure.
+func NewStretched(hashKey, blockKey []byte) *SecureCookie {
+	if len(hashKey) == 0 {
+		panic(errHashKeyNotSet)
+	}
+	s := New(stretchKeys(hashKey, blockKey))
+	s.flags |= flagKeysStretched
+	return s
+}
+
+// stretchKeys expands the given keys through HKDF-SHA256. A nil block key
+// stays nil.
+func stretchKeys(hashKey, blockKey []byte) ([]byte, []byte) {
+	hashKey = hkdf(sha256.New, hashKey, nil, []byte("securecookie stretched hash key"), 32)
+	if blockKey != nil {
+		blockKey = hkdf(sha256.New, blockKey, nil, []byte("securecookie stretched block key"), 32)
+	}
+	return hashKey, blockKey
+}
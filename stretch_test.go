@@ -0,0 +1,25 @@
+package securecookie
+
+import "testing"
+
+func TestNewStretched(t *testing.T) {
+	// A 10 byte block key is not a valid AES key, but is stretched to one.
+	s := NewStretched([]byte("short"), []byte("0123456789"))
+	encoded, err := s.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err = s.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected to decode, got %q, %v", dst, err)
+	}
+
+	plain := New([]byte("short"), nil)
+	if err = plain.Decode("sid", encoded, &dst); err != errKeyStretchedMismatch {
+		t.Fatalf("Expected errKeyStretchedMismatch, got %v", err)
+	}
+	encoded, _ = plain.Encode("sid", "value")
+	if err = NewStretched([]byte("short"), nil).Decode("sid", encoded, &dst); err != errKeyStretchedMismatch {
+		t.Fatalf("Expected errKeyStretchedMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package securecookie
+
+import "fmt"
+
+var (
+	errBlockKeySize        = Error{msg: "block key must be 16, 24 or 32 bytes"}
+	errSerializerNotSet    = Error{msg: "serializer is not set"}
+	errAgeNegative         = Error{msg: "max age and min age must not be negative"}
+	errMinAgeExceedsMaxAge = Error{msg: "min age is greater than max age"}
+	errMaxLengthNegative   = Error{msg: "max length must not be negative"}
+)
+
+// Option configures a SecureCookie, e.g.
+//
+//	func(s *SecureCookie) { s.MaxAge(3600) }
+type Option func(*SecureCookie)
+
+// NewStrict is like New, but validates the configuration up front instead of
+// panicking or deferring errors to Encode and Decode. The options are applied
+// in order, then NewStrict checks:
+//
+//   - the keys, as ValidateKeys does in strict mode;
+//   - that the block key, if any, is a valid AES key size;
+//   - that a serializer is set;
+//   - that max age and min age are not negative and min age does not exceed
+//     max age, and that max length is not negative;
+//   - any error recorded by the options, e.g. by BlockFunc.
+func NewStrict(hashKey, blockKey []byte, opts ...Option) (*SecureCookie, error) {
+	if len(hashKey) == 0 {
+		return nil, errHashKeyNotSet
+	}
+	if blockKey != nil {
+		switch len(blockKey) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("%w: got %d", errBlockKeySize, len(blockKey))
+		}
+	}
+	if err := checkKeys(hashKey, blockKey); err != nil {
+		return nil, err
+	}
+	s := New(hashKey, blockKey)
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.sz == nil {
+		return nil, errSerializerNotSet
+	}
+	if s.maxAge < 0 || s.minAge < 0 {
+		return nil, errAgeNegative
+	}
+	if s.maxAge != 0 && s.minAge > s.maxAge {
+		return nil, fmt.Errorf("%w: %d > %d", errMinAgeExceedsMaxAge, s.minAge, s.maxAge)
+	}
+	if s.maxLength < 0 {
+		return nil, errMaxLengthNegative
+	}
+	return s, nil
+}
@@ -0,0 +1,44 @@
+package securecookie
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewStrict(t *testing.T) {
+	hashKey := GenerateRandomKey(32)
+	blockKey := GenerateRandomKey(16)
+
+	s, err := NewStrict(hashKey, blockKey, func(s *SecureCookie) { s.MaxAge(3600) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := s.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	if err := s.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected value, got %q, %v", dst, err)
+	}
+
+	for _, tt := range []struct {
+		hashKey, blockKey []byte
+		opts              []Option
+		want              error
+	}{
+		{nil, nil, nil, errHashKeyNotSet},
+		{[]byte("12345"), nil, nil, errKeyTooShort},
+		{hashKey, GenerateRandomKey(20), nil, errBlockKeySize},
+		{hashKey, hashKey, nil, errKeysRelated},
+		{hashKey, nil, []Option{func(s *SecureCookie) { s.SetSerializer(nil) }}, errSerializerNotSet},
+		{hashKey, nil, []Option{func(s *SecureCookie) { s.MaxAge(-1) }}, errAgeNegative},
+		{hashKey, nil, []Option{func(s *SecureCookie) { s.MaxAge(60).MinAge(120) }}, errMinAgeExceedsMaxAge},
+		{hashKey, nil, []Option{func(s *SecureCookie) { s.MaxLength(-1) }}, errMaxLengthNegative},
+		{hashKey, nil, []Option{func(s *SecureCookie) { s.BlockFunc(nil) }}, errBlockKeyNotSet},
+	} {
+		if _, err := NewStrict(tt.hashKey, tt.blockKey, tt.opts...); !errors.Is(err, tt.want) {
+			t.Errorf("Expected %v, got %v", tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package securecookie
+
+import (
+	"reflect"
+	"time"
+)
+
+// SessionTimeouts sets two lifetimes for sliding sessions: values expire
+// idle after their last reissue, and absolute after they were first issued,
+// however often they are reissued. idle sets MaxAge; absolute is embedded
+// in values, see ExpiresAt, and kept by Reissue. Values issued without an
+// absolute expiry get one when reissued, so enabling it logs nobody out.
+func (s *SecureCookie) SessionTimeouts(idle, absolute time.Duration) *SecureCookie {
+	s.maxAge = int64(idle / time.Second)
+	s.absoluteTimeout = absolute
+	return s
+}
+
+// Reissue decodes value into dst and encodes it again with the current
+// time, keeping its not-before time and absolute expiry, e.g. to slide the
+// idle timeout of a session on each request.
+func (s *SecureCookie) Reissue(name, value string, dst interface{}) (string, error) {
+	info, err := s.DecodeWithInfo(name, value, dst)
+	if err != nil {
+		return "", err
+	}
+	return s.reissue(name, dst, info)
+}
+
+// reissue encodes the value pointed to by dst, keeping the not-before time
+// and absolute expiry of info.
+func (s *SecureCookie) reissue(name string, dst interface{}, info *DecodeInfo) (string, error) {
+	var opts []EncodeOption
+	if !info.NotBefore.IsZero() {
+		opts = append(opts, NotBefore(info.NotBefore))
+	}
+	if !info.Expiry.IsZero() {
+		opts = append(opts, ExpiresAt(info.Expiry))
+	}
+	return s.EncodeWith(name, reflect.ValueOf(dst).Elem().Interface(), opts...)
+}
+
+// DecodeWithMaxAge decodes a cookie value like Decode, with the given MaxAge
+// in seconds for this call only, so that one codec serves cookies with
+// different lifetimes. To set the lifetime of a value when encoding it, see
+// ExpiresAt.
+func (s *SecureCookie) DecodeWithMaxAge(name, value string, dst interface{}, maxAge int) error {
+	return s.DecodeWithAges(name, value, dst, int(s.minAge), maxAge)
+}
+
+// DecodeWithAges decodes a cookie value like Decode, with the given MinAge
+// and MaxAge in seconds for this call only.
+func (s *SecureCookie) DecodeWithAges(name, value string, dst interface{}, minAge, maxAge int) error {
+	c := *s
+	c.minAge, c.maxAge = int64(minAge), int64(maxAge)
+	err := c.decode(name, value, dst, s.maxLength, nil)
+	if err != nil {
+		s.sampleFailure(name, value, err)
+	}
+	return err
+}
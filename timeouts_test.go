@@ -0,0 +1,73 @@
+package securecookie
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionTimeouts(t *testing.T) {
+	issued := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := issued
+	s := New([]byte("12345"), nil).SessionTimeouts(time.Hour, 3*time.Hour).
+		SetClock(ClockFunc(func() time.Time { return now }))
+	encoded, err := s.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	// Reissuing every 50 minutes keeps the session alive, up to 3 hours.
+	for i := 1; i <= 3; i++ {
+		now = now.Add(50 * time.Minute)
+		if encoded, err = s.Reissue("sid", encoded, &dst); err != nil {
+			t.Fatalf("Reissue %d: %v", i, err)
+		}
+	}
+	now = now.Add(30 * time.Minute)
+	if err := s.Decode("sid", encoded, &dst); !errors.Is(err, errValueExpired) {
+		t.Fatalf("Expected errValueExpired, got %v", err)
+	}
+
+	// Idle sessions expire after an hour.
+	now = issued
+	encoded, _ = s.Encode("sid", "value")
+	now = now.Add(61 * time.Minute)
+	if _, err := s.Reissue("sid", encoded, &dst); err != errTimestampExpired {
+		t.Fatalf("Expected errTimestampExpired, got %v", err)
+	}
+
+	// Values without an absolute expiry get one when reissued.
+	now = issued
+	old, _ := New([]byte("12345"), nil).SetClock(ClockFunc(func() time.Time { return now })).Encode("sid", "value")
+	if encoded, err = s.Reissue("sid", old, &dst); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(3 * time.Hour)
+	if err := s.MaxAge(0).Decode("sid", encoded, &dst); !errors.Is(err, errValueExpired) {
+		t.Fatalf("Expected errValueExpired, got %v", err)
+	}
+}
+
+func TestDecodeWithAges(t *testing.T) {
+	issued := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := issued
+	s := New([]byte("12345"), nil).MaxAge(3600).SetClock(ClockFunc(func() time.Time { return now }))
+	encoded, err := s.Encode("remember-me", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst string
+	now = issued.Add(2 * time.Hour)
+	if err := s.Decode("remember-me", encoded, &dst); err != errTimestampExpired {
+		t.Fatalf("Expected errTimestampExpired, got %v", err)
+	}
+	if err := s.DecodeWithMaxAge("remember-me", encoded, &dst, 86400); err != nil || dst != "alice" {
+		t.Fatalf("Expected %q, got %q, %v", "alice", dst, err)
+	}
+	if err := s.DecodeWithAges("remember-me", encoded, &dst, 3*3600, 0); err != errTimestampTooNew {
+		t.Fatalf("Expected errTimestampTooNew, got %v", err)
+	}
+	if s.maxAge != 3600 || s.minAge != 0 {
+		t.Fatal("Expected the codec settings to be unchanged")
+	}
+}
@@ -0,0 +1,103 @@
+package securecookie
+
+import "time"
+
+// flagNoTimestamp marks values encoded without a timestamp.
+const flagNoTimestamp uint16 = 1 << 12
+
+// timestampMillis is set in timestamps in milliseconds. Timestamps in seconds
+// are positive int64 values, so they never have it set.
+const timestampMillis uint64 = 1 << 63
+
+var (
+	errTimestampRequired  = Error{msg: "timestamp is omitted but max age or min age is set"}
+	errTimestampMissing   = Error{msg: "value has no timestamp"}
+	errTimestampPrecision = Error{msg: "timestamp precision must be a second or a millisecond"}
+)
+
+// OmitTimestamp leaves the timestamp out of encoded values, for stateless
+// tokens that never expire: it saves 8 bytes and does not reveal when the
+// value was issued. MaxAge and MinAge must then be 0, or Encode fails, and
+// values without a timestamp fail to decode with a codec that sets them.
+//
+// Default is false.
+func (s *SecureCookie) OmitTimestamp(omit bool) *SecureCookie {
+	if omit {
+		s.flags |= flagNoTimestamp
+	} else {
+		s.flags &^= flagNoTimestamp
+	}
+	return s
+}
+
+// timestampLen returns the length of the timestamp of values with the given
+// header.
+func timestampLen(header uint16) int {
+	if header&flagNoTimestamp != 0 {
+		return 0
+	}
+	return 8
+}
+
+// TimestampPrecision sets the precision of the timestamp of encoded values,
+// time.Second or time.Millisecond, e.g. for anti-replay checks that need
+// sub-second issue times. Values in both precisions are decoded whatever
+// the setting, and MaxAge and MinAge keep counting seconds.
+//
+// Default is time.Second.
+func (s *SecureCookie) TimestampPrecision(d time.Duration) *SecureCookie {
+	switch d {
+	case time.Second:
+		s.millis = false
+	case time.Millisecond:
+		s.millis = true
+	default:
+		s.err = errTimestampPrecision
+	}
+	return s
+}
+
+// encodeTimestamp returns the current timestamp as stored in encoded values.
+func (s *SecureCookie) encodeTimestamp() uint64 {
+	if !s.millis {
+		return uint64(s.timestamp())
+	}
+	return uint64(s.timestampMillis()) | timestampMillis
+}
+
+// timestampMillis returns the current time in milliseconds.
+func (s *SecureCookie) timestampMillis() int64 {
+	if s.timeFunc == nil {
+		return s.now().UnixMilli()
+	}
+	return s.timeFunc() * 1000
+}
+
+// checkAge checks a timestamp read from an encoded value against MinAge and
+// MaxAge.
+func (s *SecureCookie) checkAge(ts uint64) error {
+	age, minAge, maxAge := s.timestamp()-int64(ts), s.minAge, s.maxAge
+	leeway, grace := int64(s.leeway/time.Second), int64(s.grace/time.Second)
+	if ts&timestampMillis != 0 {
+		age = s.timestampMillis() - int64(ts&^timestampMillis)
+		minAge, maxAge = minAge*1000, maxAge*1000
+		leeway, grace = int64(s.leeway/time.Millisecond), int64(s.grace/time.Millisecond)
+	}
+	if minAge != 0 && minAge > age+leeway {
+		return errTimestampTooNew
+	}
+	if maxAge != 0 && maxAge < age-leeway-grace {
+		return errTimestampExpired
+	}
+	return nil
+}
+
+// Leeway sets the clock skew tolerated between the hosts encoding and
+// decoding values: values are accepted up to d earlier than MinAge allows
+// and d later than MaxAge allows, and d before their not-before time.
+//
+// Default is 0.
+func (s *SecureCookie) Leeway(d time.Duration) *SecureCookie {
+	s.leeway = d
+	return s
+}
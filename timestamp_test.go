@@ -0,0 +1,80 @@
+package securecookie
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestOmitTimestamp(t *testing.T) {
+	s := New([]byte("12345"), nil).OmitTimestamp(true)
+	if _, err := s.Encode("sid", "value"); err != errTimestampRequired {
+		t.Fatalf("Expected errTimestampRequired, got %v", err)
+	}
+	s.MaxAge(0)
+	encoded, err := s.Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withTimestamp, _ := New([]byte("12345"), nil).MaxAge(0).Encode("sid", "value")
+	if len(encoded) >= len(withTimestamp) {
+		t.Fatalf("Expected a shorter value, got %d and %d bytes", len(encoded), len(withTimestamp))
+	}
+
+	var dst string
+	if err := s.Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected %q, got %q, %v", "value", dst, err)
+	}
+	if err := New([]byte("12345"), nil).MaxAge(0).Decode("sid", encoded, &dst); err != nil {
+		t.Fatalf("Expected any codec without max age to decode, got %v", err)
+	}
+	if err := New([]byte("12345"), nil).Decode("sid", encoded, &dst); err != errTimestampMissing {
+		t.Fatalf("Expected errTimestampMissing, got %v", err)
+	}
+	if err := s.Decode("sid", withTimestamp, &dst); err != nil {
+		t.Fatalf("Expected values with a timestamp to decode, got %v", err)
+	}
+}
+
+func TestTimestampPrecision(t *testing.T) {
+	now := int64(1700000000)
+	s := New([]byte("12345"), nil).SetSerializer(NopEncoder{}).TimestampPrecision(time.Millisecond)
+	s.timeFunc = func() int64 { return now }
+	encoded, err := s.Encode("sid", []byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := base64.URLEncoding.DecodeString(encoded)
+	ts := binary.LittleEndian.Uint64(b[sha256.Size+2+len("sid"):])
+	if ts != uint64(now*1000)|timestampMillis {
+		t.Fatalf("Expected a timestamp in milliseconds, got %x", ts)
+	}
+
+	seconds := New([]byte("12345"), nil).SetSerializer(NopEncoder{}).MaxAge(60).MinAge(10)
+	seconds.timeFunc = func() int64 { return now }
+	inSeconds, _ := seconds.Encode("sid", []byte("value"))
+	s.MaxAge(60).MinAge(10)
+	var dst []byte
+	for _, v := range []string{encoded, inSeconds} {
+		for _, codec := range []*SecureCookie{s, seconds} {
+			now = 1700000000
+			if err := codec.Decode("sid", v, &dst); err != errTimestampTooNew {
+				t.Fatalf("Expected errTimestampTooNew, got %v", err)
+			}
+			now += 30
+			if err := codec.Decode("sid", v, &dst); err != nil {
+				t.Fatal(err)
+			}
+			now += 60
+			if err := codec.Decode("sid", v, &dst); err != errTimestampExpired {
+				t.Fatalf("Expected errTimestampExpired, got %v", err)
+			}
+		}
+	}
+
+	if err := New([]byte("12345"), nil).TimestampPrecision(time.Minute).err; err != errTimestampPrecision {
+		t.Fatalf("Expected errTimestampPrecision, got %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+package securecookie
+
+var (
+	errInsecureRequest = Error{msg: "request was not received over TLS"}
+	errInsecureCookie  = Error{msg: "cookie must be Secure"}
+)
+
+// RequireTLS makes the HTTP helpers of this package refuse to decode or set
+// cookies on requests not received over TLS, as told by p, see
+// ProxyTrust.IsTLS, and makes NewCookie and SetCookie refuse cookies that are
+// not Secure. It keeps confidential cookies off plaintext connections in
+// misconfigured environments. Encode and Decode are not affected, nor are
+// NewCookie, NewChunkedCookies and SetCookie given a nil request, for which
+// only the Secure attribute is checked.
+//
+// Default is false.
+func (s *SecureCookie) RequireTLS(enabled bool, p *ProxyTrust) *SecureCookie {
+	s.requireTLS = enabled
+	s.proxyTrust = p
+	return s
+}
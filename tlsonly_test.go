@@ -0,0 +1,61 @@
+package securecookie
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireTLS(t *testing.T) {
+	s := New([]byte("12345"), nil).RequireTLS(true, TrustProxies("10.0.0.0/8"))
+	encoded, err := s.Encode("sid", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(remote string, tlsState *tls.ConnectionState, proto string) *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remote
+		r.TLS = tlsState
+		if proto != "" {
+			r.Header.Set("X-Forwarded-Proto", proto)
+		}
+		r.AddCookie(&http.Cookie{Name: "sid", Value: encoded})
+		return r
+	}
+	var dst string
+	if err := s.GetCookie(request("192.0.2.1:1234", nil, ""), "sid", &dst); err != errInsecureRequest {
+		t.Fatalf("Expected errInsecureRequest, got %v", err)
+	}
+	if err := s.GetCookie(request("192.0.2.1:1234", nil, "https"), "sid", &dst); err != errInsecureRequest {
+		t.Fatalf("Expected untrusted proxy headers to be ignored, got %v", err)
+	}
+	for _, r := range []*http.Request{
+		request("192.0.2.1:1234", &tls.ConnectionState{}, ""),
+		request("10.0.0.1:1234", nil, "https"),
+	} {
+		if err := s.GetCookie(r, "sid", &dst); err != nil || dst != "alice" {
+			t.Fatalf("Expected %q, got %q, %v", "alice", dst, err)
+		}
+	}
+
+	if _, err := s.NewCookie(nil, "sid", "alice", &CookieOptions{}); !errors.Is(err, errInsecureCookie) {
+		t.Fatalf("Expected errInsecureCookie, got %v", err)
+	}
+	if _, err := s.NewCookie(nil, "sid", "alice", nil); err != nil {
+		t.Fatalf("Expected the default attributes to be Secure, got %v", err)
+	}
+
+	// Cookies are not set in response to plaintext requests.
+	plain := request("192.0.2.1:1234", nil, "")
+	if err := s.SetCookie(httptest.NewRecorder(), plain, "sid", "alice", nil); err != errInsecureRequest {
+		t.Fatalf("Expected errInsecureRequest, got %v", err)
+	}
+	if _, err := s.NewChunkedCookies(plain, "sid", "alice", nil, 0); err != errInsecureRequest {
+		t.Fatalf("Expected errInsecureRequest, got %v", err)
+	}
+	if err := s.SetCookie(httptest.NewRecorder(), request("10.0.0.1:1234", nil, "https"), "sid", "alice", nil); err != nil {
+		t.Fatal(err)
+	}
+}
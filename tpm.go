@@ -0,0 +1,120 @@
+//go:build tpm
+
+package securecookie
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// This file requires github.com/google/go-tpm, which is not a dependency of
+// the default build. Add it with "go get github.com/google/go-tpm" and build
+// with "-tags tpm".
+
+var errTPMSealedMalformed = Error{msg: "tpm sealed key is malformed"}
+
+// srkTemplate is the template of the storage root key under which keys are
+// sealed. It is deterministic, so the same primary key is recreated on every
+// boot of the same TPM.
+var srkTemplate = tpm2.Public{
+	Type:       tpm2.AlgRSA,
+	NameAlg:    tpm2.AlgSHA256,
+	Attributes: tpm2.FlagStorageDefault,
+	RSAParameters: &tpm2.RSAParams{
+		Symmetric: &tpm2.SymScheme{
+			Alg:     tpm2.AlgAES,
+			KeyBits: 128,
+			Mode:    tpm2.AlgCFB,
+		},
+		KeyBits: 2048,
+	},
+}
+
+// TPMSealer is a KeySealer sealing keys to the TPM of the machine, under a
+// storage root key in the owner hierarchy. Keys it sealed can only be
+// unsealed by the same TPM.
+//
+// A TPMSealer is safe for concurrent use.
+type TPMSealer struct {
+	// Path is the TPM device. Default is "/dev/tpmrm0".
+	Path string
+
+	mu sync.Mutex
+}
+
+// NewTPMSealer returns a TPMSealer using the TPM device at path.
+func NewTPMSealer(path string) *TPMSealer {
+	return &TPMSealer{Path: path}
+}
+
+// Seal implements KeySealer. The result holds the public and private parts
+// of the sealed object, each prefixed with its length.
+func (t *TPMSealer) Seal(key []byte) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rw, err := tpm2.OpenTPM(t.path())
+	if err != nil {
+		return nil, err
+	}
+	defer rw.Close()
+	srk, _, err := tpm2.CreatePrimary(rw, tpm2.HandleOwner, tpm2.PCRSelection{}, "", "", srkTemplate)
+	if err != nil {
+		return nil, err
+	}
+	defer tpm2.FlushContext(rw, srk)
+	private, public, err := tpm2.Seal(rw, srk, "", "", nil, key)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, b := range [][]byte{public, private} {
+		_ = binary.Write(&buf, binary.BigEndian, uint16(len(b)))
+		buf.Write(b)
+	}
+	return buf.Bytes(), nil
+}
+
+// Unseal implements KeySealer.
+func (t *TPMSealer) Unseal(sealed []byte) ([]byte, error) {
+	var parts [2][]byte
+	rest := sealed
+	for i := range parts {
+		if len(rest) < 2 {
+			return nil, errTPMSealedMalformed
+		}
+		n := int(binary.BigEndian.Uint16(rest))
+		if len(rest) < 2+n {
+			return nil, errTPMSealedMalformed
+		}
+		parts[i], rest = rest[2:2+n], rest[2+n:]
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rw, err := tpm2.OpenTPM(t.path())
+	if err != nil {
+		return nil, err
+	}
+	defer rw.Close()
+	srk, _, err := tpm2.CreatePrimary(rw, tpm2.HandleOwner, tpm2.PCRSelection{}, "", "", srkTemplate)
+	if err != nil {
+		return nil, err
+	}
+	defer tpm2.FlushContext(rw, srk)
+	var handle tpmutil.Handle
+	if handle, _, err = tpm2.Load(rw, srk, "", parts[0], parts[1]); err != nil {
+		return nil, err
+	}
+	defer tpm2.FlushContext(rw, handle)
+	return tpm2.Unseal(rw, handle, "")
+}
+
+func (t *TPMSealer) path() string {
+	if t.Path == "" {
+		return "/dev/tpmrm0"
+	}
+	return t.Path
+}
@@ -0,0 +1,27 @@
+package securecookie
+
+import (
+	"bytes"
+	"runtime/trace"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	s := New([]byte("12345"), []byte("1234567890123456")).Trace(true)
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Skipf("tracing unavailable: %v", err)
+	}
+	encoded, err := s.Encode("sid", "value")
+	if err == nil {
+		var dst string
+		err = s.Decode("sid", encoded, &dst)
+	}
+	trace.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() == 0 {
+		t.Fatal("Expected trace output")
+	}
+}
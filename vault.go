@@ -0,0 +1,169 @@
+package securecookie
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var errVaultNoToken = Error{msg: "vault token is not set"}
+
+// VaultTransit is a KMSClient backed by the transit secrets engine of
+// HashiCorp Vault, so the key encrypting data keys never leaves Vault.
+// Combine it with KMSCodec, which caches the derived data keys locally:
+//
+//	v := &securecookie.VaultTransit{
+//		Address: "https://vault.example.com:8200",
+//		Key:     "cookies",
+//		Auth:    loginWithKubernetesAuth,
+//	}
+//	codec := securecookie.NewVaultCodec(v)
+//
+// A VaultTransit is safe for concurrent use.
+type VaultTransit struct {
+	// Address is the Vault server address.
+	Address string
+	// Mount is the mount path of the transit engine. Default is "transit".
+	Mount string
+	// Key is the name of the transit key.
+	Key string
+	// Token is the initial Vault token. It may be empty if Auth is set.
+	Token string
+	// Auth obtains a new token. It is called when no token is set and when
+	// Vault rejects the current one, e.g. because it expired. It may be nil.
+	Auth func(ctx context.Context) (string, error)
+	// Client is the HTTP client used. Default is http.DefaultClient.
+	Client *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewVaultCodec returns a KMSCodec using v to generate and decrypt data keys
+// under v.Key.
+func NewVaultCodec(v *VaultTransit) *KMSCodec {
+	return NewKMSCodec(v, v.Key)
+}
+
+// GenerateDataKey implements KMSClient using the transit datakey endpoint.
+// keyID is the name of the transit key.
+func (v *VaultTransit) GenerateDataKey(ctx context.Context, keyID string) ([]byte, []byte, error) {
+	var resp struct {
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	}
+	body := map[string]interface{}{"bits": 256}
+	if err := v.call(ctx, "datakey/plaintext/"+keyID, body, &resp); err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Plaintext)
+	if err != nil {
+		return nil, nil, Error{msg: "vault returned a malformed data key"}
+	}
+	return plaintext, []byte(resp.Ciphertext), nil
+}
+
+// Decrypt implements KMSClient using the transit decrypt endpoint for v.Key.
+func (v *VaultTransit) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext string `json:"plaintext"`
+	}
+	body := map[string]interface{}{"ciphertext": string(ciphertext)}
+	if err := v.call(ctx, "decrypt/"+v.Key, body, &resp); err != nil {
+		return nil, err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Plaintext)
+	if err != nil {
+		return nil, Error{msg: "vault returned a malformed data key"}
+	}
+	return plaintext, nil
+}
+
+// call posts body to the given transit endpoint and decodes the data field of
+// the response into out, authenticating again once if the token is rejected.
+// Requests Vault refuses, e.g. invalid ciphertext or a missing permission,
+// fail with ErrKMSKeyRejected.
+func (v *VaultTransit) call(ctx context.Context, endpoint string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	token, err := v.currentToken(ctx, "")
+	if err != nil {
+		return err
+	}
+	status, err := v.post(ctx, endpoint, token, payload, out)
+	if err != nil && status == http.StatusForbidden && v.Auth != nil {
+		if token, err = v.currentToken(ctx, token); err != nil {
+			return err
+		}
+		status, err = v.post(ctx, endpoint, token, payload, out)
+	}
+	if err != nil && (status == http.StatusBadRequest || status == http.StatusForbidden) {
+		return fmt.Errorf("%w: %v", ErrKMSKeyRejected, err)
+	}
+	return err
+}
+
+func (v *VaultTransit) post(ctx context.Context, endpoint, token string, payload []byte, out interface{}) (int, error) {
+	mount := v.Mount
+	if mount == "" {
+		mount = "transit"
+	}
+	url := strings.TrimSuffix(v.Address, "/") + "/v1/" + mount + "/" + endpoint
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	req.Header.Set("Content-Type", "application/json")
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&envelope); err != nil {
+		return res.StatusCode, fmt.Errorf("securecookie: vault %s: status %d", endpoint, res.StatusCode)
+	}
+	if res.StatusCode != http.StatusOK {
+		return res.StatusCode, fmt.Errorf("securecookie: vault %s: status %d: %s",
+			endpoint, res.StatusCode, strings.Join(envelope.Errors, "; "))
+	}
+	return res.StatusCode, json.Unmarshal(envelope.Data, out)
+}
+
+// currentToken returns the token to use. If rejected is not empty, it is the
+// token Vault just refused, and a new one is obtained unless another call
+// already did so.
+func (v *VaultTransit) currentToken(ctx context.Context, rejected string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.token == "" {
+		v.token = v.Token
+	}
+	if v.token != "" && v.token != rejected {
+		return v.token, nil
+	}
+	if v.Auth == nil {
+		return "", errVaultNoToken
+	}
+	token, err := v.Auth(ctx)
+	if err != nil {
+		return "", err
+	}
+	v.token = token
+	return token, nil
+}
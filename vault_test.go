@@ -0,0 +1,90 @@
+package securecookie
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeVault emulates the transit datakey and decrypt endpoints. Data keys are
+// "encrypted" by prefixing them, which is enough to exercise the client.
+func fakeVault(t *testing.T, validToken *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != *validToken {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+			return
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		data := map[string]string{}
+		switch r.URL.Path {
+		case "/v1/transit/datakey/plaintext/cookies":
+			plaintext := base64.StdEncoding.EncodeToString(GenerateRandomKey(32))
+			data["plaintext"] = plaintext
+			data["ciphertext"] = "vault:v1:" + plaintext
+		case "/v1/transit/decrypt/cookies":
+			if !strings.HasPrefix(body["ciphertext"].(string), "vault:v1:") {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string][]string{"errors": {"invalid ciphertext"}})
+				return
+			}
+			data["plaintext"] = strings.TrimPrefix(body["ciphertext"].(string), "vault:v1:")
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+}
+
+func TestVaultCodec(t *testing.T) {
+	token := "t1"
+	srv := fakeVault(t, &token)
+	defer srv.Close()
+	logins := 0
+	v := &VaultTransit{
+		Address: srv.URL,
+		Key:     "cookies",
+		Auth: func(ctx context.Context) (string, error) {
+			logins++
+			return token, nil
+		},
+	}
+	encoded, err := NewVaultCodec(v).Encode("sid", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The token expires; the client must authenticate again.
+	token = "t2"
+	var dst string
+	if err = NewVaultCodec(v).Decode("sid", encoded, &dst); err != nil || dst != "value" {
+		t.Fatalf("Expected to decode, got %q, %v", dst, err)
+	}
+	if logins != 2 {
+		t.Fatalf("Expected 2 logins, got %d", logins)
+	}
+}
+
+func TestVaultTransitRejected(t *testing.T) {
+	token := "t1"
+	srv := fakeVault(t, &token)
+	defer srv.Close()
+	v := &VaultTransit{Address: srv.URL, Key: "cookies", Token: token}
+	if _, err := v.Decrypt(context.Background(), []byte("forged")); !errors.Is(err, ErrKMSKeyRejected) {
+		t.Fatalf("Expected ErrKMSKeyRejected for invalid ciphertext, got %v", err)
+	}
+	v = &VaultTransit{Address: srv.URL, Key: "cookies", Token: "t0"}
+	if _, err := v.Decrypt(context.Background(), []byte("vault:v1:AAAA")); !errors.Is(err, ErrKMSKeyRejected) {
+		t.Fatalf("Expected ErrKMSKeyRejected for a denied request, got %v", err)
+	}
+	srv.Close()
+	if _, err := v.Decrypt(context.Background(), []byte("vault:v1:AAAA")); err == nil || errors.Is(err, ErrKMSKeyRejected) {
+		t.Fatalf("Expected a transport error not to be a rejection, got %v", err)
+	}
+}
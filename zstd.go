@@ -0,0 +1,59 @@
+//go:build zstd
+
+package securecookie
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// This file requires github.com/klauspost/compress, which is not a
+// dependency of the default build. Add it with
+// "go get github.com/klauspost/compress" and build with "-tags zstd".
+
+func init() {
+	RegisterCompressor(ZstdCompressor{})
+}
+
+// ZstdCompressor compresses with zstd, optionally with a dictionary trained
+// on typical values, which is most effective on values as small as cookies.
+// Decoding a value compressed with a dictionary requires a codec whose
+// compressor has the same dictionary.
+type ZstdCompressor struct {
+	// Level is the zstd compression level. Default is 3.
+	Level int
+	// Dict is a dictionary in the format produced by "zstd --train".
+	Dict []byte
+}
+
+// ID returns the ID of zstd.
+func (c ZstdCompressor) ID() byte {
+	return compressZstd
+}
+
+// NewWriter returns a zstd writer.
+func (c ZstdCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = 3
+	}
+	opts := []zstd.EOption{zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level))}
+	if c.Dict != nil {
+		opts = append(opts, zstd.WithEncoderDict(c.Dict))
+	}
+	return zstd.NewWriter(w, opts...)
+}
+
+// NewReader returns a zstd reader.
+func (c ZstdCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	var opts []zstd.DOption
+	if c.Dict != nil {
+		opts = append(opts, zstd.WithDecoderDicts(c.Dict))
+	}
+	d, err := zstd.NewReader(r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}